@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ghodss/yaml"
+	"github.com/n3wscott/knap/pkg/graph"
+)
+
+func describeCmd(o *options, args []string) error {
+	o.addOutput("text", "json", "yaml")
+	args, err := o.parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return errors.New("describe takes one KIND/NAME or KEY")
+	}
+	dc, ns, err := o.dynamic()
+	if err != nil {
+		return err
+	}
+	g := graph.LoadTopology(dc, ns)
+
+	n, err := find(g, args[0])
+	if err != nil {
+		return err
+	}
+	e, _ := g.Explain(n.Key)
+
+	switch o.Output {
+	case "json":
+		b, err := json.MarshalIndent(e, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	case "yaml":
+		b, err := yaml.Marshal(e)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(b))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Key:\t%s\n", n.Key)
+	fmt.Fprintf(w, "Kind:\t%s\n", n.Kind)
+	if n.APIVersion != "" {
+		fmt.Fprintf(w, "API Version:\t%s\n", n.APIVersion)
+	}
+	if n.Namespace != "" {
+		fmt.Fprintf(w, "Namespace:\t%s\n", n.Namespace)
+	}
+	if n.URI != "" {
+		fmt.Fprintf(w, "URI:\t%s\n", n.URI)
+	}
+	if n.Ready != "" {
+		fmt.Fprintf(w, "Ready:\t%s\n", n.Ready)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n%s:\n", name(g, n.Key))
+	for _, line := range explain(g, e) {
+		fmt.Printf("  %s\n", line)
+	}
+
+	fmt.Printf("\nEvent paths:\n")
+	for _, path := range e.Paths {
+		var names []string
+		for _, k := range path {
+			names = append(names, name(g, k))
+		}
+		fmt.Printf("  %s\n", strings.Join(names, " -> "))
+	}
+
+	conditions, _ := n.Status["conditions"].([]interface{})
+	if len(conditions) > 0 {
+		fmt.Printf("\nConditions:\n")
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(w, "  Type\tStatus\tReason\tMessage\n")
+		for _, c := range conditions {
+			cond, _ := c.(map[string]interface{})
+			fmt.Fprintf(w, "  %v\t%v\t%v\t%v\n", cond["type"], cond["status"], orNone(cond["reason"]), orNone(cond["message"]))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(e.Problems) > 0 {
+		fmt.Printf("\nProblems:\n")
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		for _, p := range e.Problems {
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", p.Severity, name(g, p.Key), p.Message)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// explain says in sentences what the edges of the explanation mean.
+func explain(g *graph.Graph, e *graph.Explanation) []string {
+	var lines []string
+	for _, edge := range e.In {
+		from := name(g, edge.From)
+		switch edge.Relation {
+		case "sink":
+			lines = append(lines, fmt.Sprintf("Receives the events %s sends.", from))
+		case "subscriber":
+			lines = append(lines, fmt.Sprintf("Receives the events %s delivers.", from))
+		case "reply":
+			lines = append(lines, fmt.Sprintf("Receives the replies to the events %s delivers.", from))
+		default:
+			lines = append(lines, fmt.Sprintf("Receives the events of %s.", from))
+		}
+	}
+	if len(e.In) == 0 {
+		lines = append(lines, "Receives no events from the topology.")
+	}
+
+	if e.Node.Kind == "Trigger" {
+		if e.Filter == "" {
+			lines = append(lines, "Passes every event.")
+		} else {
+			lines = append(lines, fmt.Sprintf("Passes the events with %s.", e.Filter))
+		}
+	}
+
+	for _, edge := range e.Out {
+		to := name(g, edge.To)
+		switch edge.Relation {
+		case "sink":
+			lines = append(lines, fmt.Sprintf("Sends its events to %s.", to))
+		case "trigger":
+			if te, ok := g.Explain(edge.To); ok && te.Filter != "" {
+				lines = append(lines, fmt.Sprintf("Delivers the events with %s to %s.", te.Filter, to))
+			} else {
+				lines = append(lines, fmt.Sprintf("Delivers every event to %s.", to))
+			}
+		case "subscriber":
+			if e.Node.Kind == "Trigger" {
+				lines = append(lines, fmt.Sprintf("Delivers the events it passes to %s.", to))
+			} else {
+				lines = append(lines, fmt.Sprintf("Delivers its events to %s.", to))
+			}
+		case "reply":
+		default:
+			lines = append(lines, fmt.Sprintf("Delivers its events to %s.", to))
+		}
+	}
+
+	for _, r := range e.Replies {
+		lines = append(lines, fmt.Sprintf("Replies go to %s.", name(g, r)))
+	}
+	if e.Node.Kind == "Subscription" && len(e.Replies) == 0 {
+		lines = append(lines, "Replies are dropped.")
+	}
+	return lines
+}
+
+// name is how a node is shown: KIND/NAME, its URI, or its kind when it may
+// not be shown.
+func name(g *graph.Graph, key string) string {
+	n, ok := g.Node(key)
+	switch {
+	case !ok:
+		return key
+	case n.URI != "":
+		return n.URI
+	case n.Name == "":
+		return n.Kind + " (hidden)"
+	}
+	return n.Kind + "/" + n.Name
+}
+
+// find resolves a node key, or KIND/NAME with the kind in any case.
+func find(g *graph.Graph, arg string) (*graph.Node, error) {
+	if n, ok := g.Node(arg); ok {
+		return n, nil
+	}
+	parts := strings.SplitN(arg, "/", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("%q is not a KIND/NAME or a key", arg)
+	}
+	var found []*graph.Node
+	for _, n := range g.Nodes() {
+		if strings.EqualFold(n.Kind, parts[0]) && n.Name == parts[1] {
+			found = append(found, n)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("%s not found", arg)
+	case 1:
+		return found[0], nil
+	}
+	var keys []string
+	for _, n := range found {
+		keys = append(keys, n.Key)
+	}
+	return nil, fmt.Errorf("%s is ambiguous, use one of the keys %s", arg, strings.Join(keys, ", "))
+}
+
+func orNone(v interface{}) interface{} {
+	if v == nil || v == "" {
+		return "<none>"
+	}
+	return v
+}
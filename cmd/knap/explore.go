@@ -0,0 +1,529 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ghodss/yaml"
+	"github.com/n3wscott/knap/pkg/graph"
+	"github.com/n3wscott/knap/pkg/knative"
+	"golang.org/x/crypto/ssh/terminal"
+	"k8s.io/client-go/dynamic"
+)
+
+// debounce collects a burst of changes into a single reload.
+const debounce = 500 * time.Millisecond
+
+const exploreHelp = "↑↓ move  → enter follow  ← back  PgUp PgDn scroll details  r reload  q quit"
+
+// exploreCmd runs a full-screen explorer of the topology, reloading it as the
+// cluster changes.
+func exploreCmd(o *options, args []string) error {
+	var file string
+	o.fs.StringVar(&file, "snapshot", "",
+		"Explore a `file` written by knap export --snapshot, or any YAML or JSON of objects, instead of the cluster.")
+	if _, err := o.parse(args); err != nil {
+		return err
+	}
+	if !terminal.IsTerminal(int(os.Stdin.Fd())) || !terminal.IsTerminal(int(os.Stdout.Fd())) {
+		return errors.New("explore needs a terminal, use graph -o text otherwise")
+	}
+
+	var dc dynamic.Interface
+	var self knative.Authorizer
+	var ns, source string
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		objs, err := knative.ReadSnapshot(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("reading %s: %v", file, err)
+		}
+		dc, source = knative.NewSnapshot(objs), file
+		if ns = o.Namespace; ns == "" {
+			for _, obj := range objs {
+				if ns = obj.GetNamespace(); ns != "" {
+					break
+				}
+			}
+		}
+		if ns == "" {
+			ns = "default"
+		}
+	} else {
+		var err error
+		if dc, ns, err = o.dynamic(); err != nil {
+			return err
+		}
+		source = "live"
+		// Like knap serve, skip what knap may not list instead of
+		// retrying it.
+		self = knative.SelfAuthorizer(dc, time.Minute)
+	}
+
+	// Failures are logged by the client, show them in the status line
+	// instead of over the screen.
+	status := &statusLine{}
+	log.SetOutput(status)
+	defer log.SetOutput(os.Stderr)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	var changes <-chan knative.Change
+	var sources *knative.SourceInformers
+	if file == "" {
+		c := knative.New(dc, knative.WithAuthorizer(self))
+		changes = c.Watch(ns, stop)
+		// Reloads list the sources from informers instead of the API server.
+		sources = c.SourceInformers(ns, 0, stop)
+	}
+	load := func() *graph.Graph {
+		return graph.LoadTopology(dc, ns, graph.WithAuthorizer(self), graph.WithSourceInformers(sources))
+	}
+
+	e := &explorer{ns: ns, source: source}
+	e.load(load())
+
+	state, err := terminal.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return err
+	}
+	defer terminal.Restore(int(os.Stdin.Fd()), state)
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer fmt.Print("\x1b[?25h\x1b[?1049l")
+
+	keys := readKeys(stop)
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+	var reload <-chan time.Time
+
+	width, height := 0, 0
+	draw := func() {
+		width, height, _ = terminal.GetSize(int(os.Stdout.Fd()))
+		e.status = status.String()
+		var b strings.Builder
+		b.WriteString("\x1b[H")
+		for i, line := range e.render(width, height) {
+			if i > 0 {
+				b.WriteString("\r\n")
+			}
+			b.WriteString(line + "\x1b[K")
+		}
+		b.WriteString("\x1b[J")
+		fmt.Print(b.String())
+	}
+	draw()
+
+	for {
+		select {
+		case k, ok := <-keys:
+			if !ok || !e.handle(k) {
+				return nil
+			}
+			if k == "r" {
+				e.load(load())
+			}
+		case <-changes:
+			if reload == nil {
+				reload = time.After(debounce)
+			}
+			continue
+		case <-reload:
+			reload = nil
+			e.load(load())
+		case <-tick.C:
+			if w, h, _ := terminal.GetSize(int(os.Stdout.Fd())); w == width && h == height {
+				continue
+			}
+		}
+		draw()
+	}
+}
+
+// readKeys sends the keys pressed, escape sequences as one key.
+func readKeys(stop <-chan struct{}) <-chan string {
+	keys := make(chan string)
+	go func() {
+		defer close(keys)
+		buf := make([]byte, 64)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				return
+			}
+			for _, k := range splitKeys(buf[:n]) {
+				select {
+				case keys <- k:
+				case <-stop:
+					return
+				}
+			}
+		}
+	}()
+	return keys
+}
+
+// splitKeys splits what was read from the terminal into keys.
+func splitKeys(b []byte) []string {
+	var keys []string
+	for len(b) > 0 {
+		n := 1
+		switch {
+		case len(b) > 2 && b[0] == 0x1b && (b[1] == '[' || b[1] == 'O'):
+			for n = 2; n < len(b) && !(b[n] >= 'A' && b[n] <= 'Z' || b[n] >= 'a' && b[n] <= 'z' || b[n] == '~'); n++ {
+			}
+			if n < len(b) {
+				n++
+			}
+		default:
+			_, n = utf8.DecodeRune(b)
+		}
+		keys = append(keys, string(b[:n]))
+		b = b[n:]
+	}
+	return keys
+}
+
+// statusLine keeps the last line logged.
+type statusLine struct {
+	mu   sync.Mutex
+	last string
+}
+
+func (s *statusLine) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if lines := strings.Split(strings.TrimSpace(string(p)), "\n"); len(lines) > 0 {
+		s.last = lines[len(lines)-1]
+	}
+	return len(p), nil
+}
+
+func (s *statusLine) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.last
+}
+
+// explorer is the state of the explorer, drawn by render.
+type explorer struct {
+	g       *graph.Graph
+	ns      string
+	source  string
+	updated time.Time
+	status  string
+
+	// views is the trail of nodes followed, starting with the list of
+	// brokers, channels and sources.
+	views []*view
+
+	// scroll is how far the details pane is scrolled.
+	scroll int
+}
+
+type view struct {
+	key    string // empty for the root list
+	title  string
+	items  []item
+	cursor int
+	top    int
+}
+
+// item is a node in a view, and how it is connected to the node of the view.
+type item struct {
+	key      string
+	relation string
+}
+
+// load replaces the topology, keeping the trail and the selected nodes.
+func (e *explorer) load(g *graph.Graph) {
+	e.g, e.updated = g, time.Now()
+	if len(e.views) == 0 {
+		e.views = []*view{{}}
+	}
+	for _, v := range e.views {
+		selected := ""
+		if v.cursor < len(v.items) {
+			selected = v.items[v.cursor].key
+		}
+		e.fill(v)
+		v.cursor = 0
+		for i, it := range v.items {
+			if it.key == selected {
+				v.cursor = i
+			}
+		}
+	}
+}
+
+// fill lists the items of the view from the topology.
+func (e *explorer) fill(v *view) {
+	v.items = nil
+	if v.key == "" {
+		v.title = "Brokers, channels and sources"
+		var nodes []*graph.Node
+		for _, n := range e.g.Nodes() {
+			if n.Kind == "Broker" || n.Kind == "Channel" || n.IsSource() {
+				nodes = append(nodes, n)
+			}
+		}
+		rank := func(n *graph.Node) int {
+			switch n.Kind {
+			case "Broker":
+				return 0
+			case "Channel":
+				return 1
+			}
+			return 2
+		}
+		sort.SliceStable(nodes, func(i, j int) bool { return rank(nodes[i]) < rank(nodes[j]) })
+		for _, n := range nodes {
+			v.items = append(v.items, item{key: n.Key})
+		}
+		return
+	}
+
+	v.title = name(e.g, v.key)
+	if _, ok := e.g.Node(v.key); !ok {
+		v.title += " (deleted)"
+	}
+	in, out := e.g.Edges(v.key)
+	for _, edge := range out {
+		v.items = append(v.items, item{key: edge.To, relation: "→ " + edge.Relation})
+	}
+	for _, edge := range in {
+		v.items = append(v.items, item{key: edge.From, relation: "← " + edge.Relation})
+	}
+}
+
+func (e *explorer) view() *view {
+	return e.views[len(e.views)-1]
+}
+
+// handle acts on a key, and returns false to quit.
+func (e *explorer) handle(k string) bool {
+	v := e.view()
+	switch k {
+	case "q", "\x03":
+		return false
+	case "\x1b[A", "\x1bOA", "k":
+		if v.cursor > 0 {
+			v.cursor--
+			e.scroll = 0
+		}
+	case "\x1b[B", "\x1bOB", "j":
+		if v.cursor < len(v.items)-1 {
+			v.cursor++
+			e.scroll = 0
+		}
+	case "\x1b[C", "\x1bOC", "l", "\r", "\n":
+		if v.cursor < len(v.items) {
+			next := &view{key: v.items[v.cursor].key}
+			e.fill(next)
+			e.views = append(e.views, next)
+			e.scroll = 0
+		}
+	case "\x1b[D", "\x1bOD", "h", "\x7f", "\x1b":
+		if len(e.views) > 1 {
+			e.views = e.views[:len(e.views)-1]
+			e.scroll = 0
+		}
+	case "\x1b[5~":
+		if e.scroll -= 10; e.scroll < 0 {
+			e.scroll = 0
+		}
+	case "\x1b[6~":
+		e.scroll += 10
+	}
+	return true
+}
+
+// render draws the screen: a header, the list of the current view on the
+// left, the details of the selected node on the right, and a status line.
+func (e *explorer) render(width, height int) []string {
+	if width < 40 || height < 5 {
+		return []string{cell("knap explore needs a larger terminal", width)}
+	}
+	v := e.view()
+	left := width * 2 / 5
+	right := width - left - 1
+	rows := height - 2
+
+	lines := []string{reverse(cell(fmt.Sprintf(" knap explore  namespace: %s  source: %s  updated: %s",
+		e.ns, e.source, e.updated.Format("15:04:05")), width))}
+
+	var trail []string
+	for _, v := range e.views {
+		trail = append(trail, v.title)
+	}
+	title := []rune(strings.Join(trail, " › "))
+	if len(title) > left {
+		// Keep the end of the trail, it names the current view.
+		title = append([]rune("…"), title[len(title)-left+1:]...)
+	}
+	list := []string{bold(cell(string(title), left))}
+
+	if v.cursor < v.top {
+		v.top = v.cursor
+	}
+	if v.cursor >= v.top+rows-1 {
+		v.top = v.cursor - rows + 2
+	}
+	if len(v.items) == 0 {
+		list = append(list, cell("  <none>", left))
+	}
+	for i := v.top; i < len(v.items) && len(list) < rows; i++ {
+		it := v.items[i]
+		text := name(e.g, it.key)
+		if it.relation != "" {
+			text = fmt.Sprintf("%-16s %s", it.relation, text)
+		}
+		n, _ := e.g.Node(it.key)
+		text = cell(" "+readyMark(n)+" "+text, left)
+		if i == v.cursor {
+			list = append(list, reverse(text))
+		} else {
+			list = append(list, paint(readyColor(n), text))
+		}
+	}
+
+	var details []string
+	if v.cursor < len(v.items) {
+		details = e.details(v.items[v.cursor].key)
+	}
+	if e.scroll > len(details)-1 {
+		e.scroll = len(details) - 1
+	}
+	if e.scroll < 0 {
+		e.scroll = 0
+	}
+	if e.scroll > 0 {
+		details = details[e.scroll:]
+	}
+
+	for i := 0; i < rows; i++ {
+		l, r := strings.Repeat(" ", left), ""
+		if i < len(list) {
+			l = list[i]
+		}
+		if i < len(details) {
+			r = cell(details[i], right)
+		}
+		lines = append(lines, l+"│"+r)
+	}
+
+	footer := exploreHelp
+	if e.status != "" {
+		footer = e.status
+	}
+	return append(lines, reverse(cell(" "+footer, width)))
+}
+
+// details are the lines of the details pane: the node, its conditions and
+// its YAML.
+func (e *explorer) details(key string) []string {
+	n, ok := e.g.Node(key)
+	if !ok {
+		return []string{"deleted"}
+	}
+	var b bytes.Buffer
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, " %s\n", name(e.g, key))
+	if n.APIVersion != "" {
+		fmt.Fprintf(w, " API Version:\t%s\n", n.APIVersion)
+	}
+	if n.Ready != "" {
+		fmt.Fprintf(w, " Ready:\t%s\n", n.Ready)
+	}
+	if conditions, _ := n.Status["conditions"].([]interface{}); len(conditions) > 0 {
+		fmt.Fprintf(w, "\n Conditions:\n   Type\tStatus\tReason\tMessage\n")
+		for _, c := range conditions {
+			cond, _ := c.(map[string]interface{})
+			fmt.Fprintf(w, "   %v\t%v\t%v\t%v\n", cond["type"], cond["status"], orNone(cond["reason"]), orNone(cond["message"]))
+		}
+	}
+	_ = w.Flush()
+
+	switch {
+	case n.Object != nil:
+		y, err := yaml.Marshal(n.Object)
+		if err != nil {
+			fmt.Fprintf(&b, "\n %v\n", err)
+			break
+		}
+		b.WriteString("\n")
+		for _, line := range strings.Split(strings.TrimRight(string(y), "\n"), "\n") {
+			b.WriteString(" " + line + "\n")
+		}
+	case n.URI != "":
+		b.WriteString("\n Nothing in the namespace serves this address.\n")
+	default:
+		b.WriteString("\n Not loaded, it is only referenced.\n")
+	}
+	return strings.Split(strings.TrimRight(b.String(), "\n"), "\n")
+}
+
+func readyMark(n *graph.Node) string {
+	if n == nil {
+		return " "
+	}
+	switch n.Ready {
+	case "True":
+		return "✔"
+	case "False":
+		return "✘"
+	case "Unknown":
+		return "…"
+	}
+	return "·"
+}
+
+func readyColor(n *graph.Node) string {
+	if n == nil {
+		return ""
+	}
+	switch n.Ready {
+	case "True":
+		return "\x1b[32m"
+	case "False":
+		return "\x1b[31m"
+	case "Unknown":
+		return "\x1b[33m"
+	}
+	return ""
+}
+
+// cell truncates or pads s to width runes. Tabs become spaces.
+func cell(s string, width int) string {
+	s = strings.Replace(s, "\t", "    ", -1)
+	if n := utf8.RuneCountInString(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	r := []rune(s)
+	if width > 1 {
+		return string(r[:width-1]) + "…"
+	}
+	return string(r[:width])
+}
+
+func paint(code, s string) string {
+	if code == "" {
+		return s
+	}
+	return code + s + "\x1b[0m"
+}
+
+func reverse(s string) string { return paint("\x1b[7m", s) }
+func bold(s string) string    { return paint("\x1b[1m", s) }
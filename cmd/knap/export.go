@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ghodss/yaml"
+	"github.com/n3wscott/knap/pkg/knative"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// exportCmd prints the topology as manifests that can be applied to another
+// namespace or cluster.
+func exportCmd(o *options, args []string) error {
+	var owned, snapshot bool
+	o.fs.BoolVar(&owned, "include-owned", false,
+		"Also export the objects controllers create, such as the channels of brokers.")
+	o.fs.BoolVar(&snapshot, "snapshot", false,
+		"Export everything as it is stored, status and source CRDs included, for knap explore --snapshot.")
+	o.addOutput("yaml", "json")
+	if _, err := o.parse(args); err != nil {
+		return err
+	}
+	dc, ns, err := o.dynamic()
+	if err != nil {
+		return err
+	}
+
+	c := knative.New(dc)
+	var items []interface{}
+	if snapshot {
+		for _, obj := range c.Snapshot(ns) {
+			items = append(items, obj.Object)
+		}
+	} else {
+		for _, obj := range c.Objects(ns) {
+			if !owned && metav1.GetControllerOf(&obj) != nil {
+				continue
+			}
+			items = append(items, manifest(obj).Object)
+		}
+	}
+
+	if o.Output == "json" {
+		b, err := json.MarshalIndent(map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "List",
+			"items":      items,
+		}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+	for i, item := range items {
+		b, err := yaml.Marshal(item)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Println("---")
+		}
+		fmt.Print(string(b))
+	}
+	return nil
+}
+
+// manifest strips what the cluster sets from obj.
+func manifest(obj unstructured.Unstructured) *unstructured.Unstructured {
+	m := obj.DeepCopy()
+	delete(m.Object, "status")
+	for _, f := range []string{"uid", "resourceVersion", "selfLink", "creationTimestamp", "generation"} {
+		unstructured.RemoveNestedField(m.Object, "metadata", f)
+	}
+	unstructured.RemoveNestedField(m.Object, "metadata", "annotations", "kubectl.kubernetes.io/last-applied-configuration")
+	if len(m.GetAnnotations()) == 0 {
+		unstructured.RemoveNestedField(m.Object, "metadata", "annotations")
+	}
+	return m
+}
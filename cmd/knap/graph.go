@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/n3wscott/knap/pkg/graph"
+	"github.com/n3wscott/knap/pkg/knative"
+	"github.com/n3wscott/knap/pkg/render"
+	"k8s.io/client-go/dynamic"
+)
+
+// graphExtensions are the formats of the files -o may name.
+var graphExtensions = map[string]string{
+	".dot":  "dot",
+	".gv":   "dot",
+	".json": "json",
+	".mmd":  "mermaid",
+	".txt":  "text",
+	".svg":  "svg",
+	".png":  "png",
+	".pdf":  "pdf",
+}
+
+func graphCmd(o *options, args []string) error {
+	var focus, group, color, renderer string
+	var internals, watch bool
+	var links, contexts []string
+	o.fs.StringVar(&focus, "focus", "triggers",
+		"What to draw: triggers, or subscriptions to also draw channels.")
+	o.fs.StringVar(&group, "group", "",
+		"Comma separated `groupings` to nest resources by, outermost first: cluster, namespace, owner, team, part-of or label=<key>.")
+	o.fs.BoolVar(&internals, "internals", false,
+		"Draw the channels and subscriptions brokers and triggers create.")
+	o.fs.StringArrayVar(&links, "link", nil,
+		"Link nodes of a kind to a URL, as Kind=template. May be repeated.")
+	o.fs.StringVar(&color, "color", "auto",
+		"Color text output by readiness: auto, always or never. Auto colors a terminal unless NO_COLOR is set.")
+	o.fs.StringVar(&o.File, "file", "",
+		"Write the graph to the `file` instead of stdout.")
+	o.fs.StringVar(&renderer, "renderer", "auto",
+		"How svg, png and pdf are drawn: graphviz, builtin, or auto to use graphviz when dot is installed.")
+	o.fs.BoolVarP(&watch, "watch", "w", false,
+		"Keep watching the namespace, rewriting the file or reprinting the graph when the topology changes.")
+	o.fs.StringSliceVar(&contexts, "contexts", nil,
+		"Comma separated kubeconfig `contexts` to draw in one graph, grouped by cluster. Sinks and subscribers addressed by an external URL of a broker or service in another cluster link to it.")
+	o.addOutput("dot", "json", "mermaid", "text", "svg", "png", "pdf")
+	o.acceptFiles(graphExtensions)
+	if _, err := o.parse(args); err != nil {
+		return err
+	}
+	file := o.File
+
+	image := o.Output == "svg" || o.Output == "png" || o.Output == "pdf"
+	if image {
+		if err := render.Check(renderer, o.Output); err != nil {
+			return err
+		}
+		if file == "" && isTerminal(os.Stdout) {
+			return fmt.Errorf("not writing %s to a terminal, name a file with -o graph.%s", o.Output, o.Output)
+		}
+	}
+	if watch && file == "" && image {
+		return fmt.Errorf("--watch rewrites %s to a file, name one with -o graph.%s", o.Output, o.Output)
+	}
+	colored, err := useColor(color)
+	if err != nil {
+		return err
+	}
+	if file != "" && color != "always" {
+		colored = false
+	}
+	groupings, err := graph.ParseGroupings(group)
+	if err != nil {
+		return err
+	}
+	if len(contexts) > 0 && !groupsClusters(group) {
+		groupings = append([]graph.Grouping{graph.GroupByCluster()}, groupings...)
+	}
+	l, err := graph.ParseLinks(links...)
+	if err != nil {
+		return err
+	}
+	opts := []graph.Option{graph.WithGrouping(groupings...), graph.WithLinks(l)}
+	if internals {
+		opts = append(opts, graph.ExpandInternals())
+	}
+	var load func(dc dynamic.Interface, ns string, opts ...graph.Option) *graph.Graph
+	var loadClusters func(clusters []graph.Cluster, opts ...graph.Option) *graph.Graph
+	switch focus {
+	case "triggers":
+		load, loadClusters = graph.LoadTriggers, graph.LoadClusterTriggers
+	case "subscriptions":
+		load, loadClusters = graph.LoadSubscriptions, graph.LoadClusterSubscriptions
+	default:
+		return fmt.Errorf("unknown focus %q, use triggers or subscriptions", focus)
+	}
+
+	var clusters []graph.Cluster
+	if len(contexts) > 0 {
+		if clusters, err = o.clusters(contexts); err != nil {
+			return err
+		}
+	} else {
+		dc, ns, err := o.dynamic()
+		if err != nil {
+			return err
+		}
+		clusters = []graph.Cluster{{Client: dc, Namespace: ns}}
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	if watch {
+		// Redraws list the sources from informers instead of the API server.
+		for i, cl := range clusters {
+			clusters[i].Sources = knative.New(cl.Client).SourceInformers(cl.Namespace, 0, stop)
+		}
+		if len(contexts) == 0 {
+			opts = append(opts, graph.WithSourceInformers(clusters[0].Sources))
+		}
+	}
+
+	draw := func() ([]byte, error) {
+		var g *graph.Graph
+		if len(contexts) > 0 {
+			g = loadClusters(clusters, opts...)
+		} else {
+			g = load(clusters[0].Client, clusters[0].Namespace, opts...)
+		}
+		switch o.Output {
+		case "json":
+			b, err := json.MarshalIndent(g.Document(), "", "  ")
+			return append(b, '\n'), err
+		case "mermaid":
+			return []byte(g.Mermaid()), nil
+		case "text":
+			return []byte(g.Terminal(colored)), nil
+		case "svg", "png", "pdf":
+			return render.Image(context.Background(), renderer, o.Output, []byte(g.String()))
+		}
+		return []byte(g.String()), nil
+	}
+
+	b, err := draw()
+	if err != nil {
+		return err
+	}
+	if !watch {
+		return output(file, b)
+	}
+	clear := file == "" && isTerminal(os.Stdout)
+	show := func(b []byte) error {
+		if clear {
+			fmt.Print("\x1b[H\x1b[2J")
+		}
+		if err := output(file, b); err != nil {
+			return err
+		}
+		if file != "" {
+			fmt.Fprintf(os.Stderr, "%s wrote %s\n", time.Now().Format("15:04:05"), file)
+		}
+		return nil
+	}
+	if err := show(b); err != nil {
+		return err
+	}
+
+	changes := make(chan knative.Change)
+	for _, cl := range clusters {
+		go func(in <-chan knative.Change) {
+			for {
+				select {
+				case c := <-in:
+					select {
+					case changes <- c:
+					case <-stop:
+						return
+					}
+				case <-stop:
+					return
+				}
+			}
+		}(knative.New(cl.Client).Watch(cl.Namespace, stop))
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	var redraw <-chan time.Time
+	for {
+		select {
+		case <-interrupt:
+			return nil
+		case <-changes:
+			if redraw == nil {
+				redraw = time.After(debounce)
+			}
+		case <-redraw:
+			redraw = nil
+			next, err := draw()
+			if err != nil {
+				// Keep the last good graph, the next change may fix it.
+				fmt.Fprintf(os.Stderr, "%s %v\n", time.Now().Format("15:04:05"), err)
+				continue
+			}
+			if bytes.Equal(next, b) {
+				continue
+			}
+			b = next
+			if err := show(b); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// groupsClusters reports whether the --group spec already groups by cluster.
+func groupsClusters(spec string) bool {
+	for _, s := range strings.Split(spec, ",") {
+		if strings.TrimSpace(s) == "cluster" {
+			return true
+		}
+	}
+	return false
+}
+
+// output writes b to stdout or, replacing it at once so viewers never see it
+// half written, to file.
+func output(file string, b []byte) error {
+	if file == "" {
+		_, err := os.Stdout.Write(b)
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(file), "."+filepath.Base(file))
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// useColor decides whether to write ANSI colors to stdout.
+func useColor(mode string) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+			return false, nil
+		}
+		return isTerminal(os.Stdout), nil
+	}
+	return false, fmt.Errorf("unknown color mode %q, use auto, always or never", mode)
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/n3wscott/knap/pkg/graph"
+)
+
+// lintCmd fails when any problem is an error, so it can gate a pipeline.
+func lintCmd(o *options, args []string) error {
+	o.addOutput("text", "json")
+	if _, err := o.parse(args); err != nil {
+		return err
+	}
+	dc, ns, err := o.dynamic()
+	if err != nil {
+		return err
+	}
+	problems := graph.LoadTopology(dc, ns).Lint()
+
+	if o.Output == "json" {
+		if problems == nil {
+			problems = []graph.Problem{}
+		}
+		b, err := json.MarshalIndent(problems, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	} else if len(problems) == 0 {
+		fmt.Printf("No problems found in namespace %s.\n", ns)
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "SEVERITY\tKEY\tMESSAGE")
+		for _, p := range problems {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", p.Severity, p.Key, p.Message)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range problems {
+		if p.Severity == graph.SeverityError {
+			return errFailed
+		}
+	}
+	return nil
+}
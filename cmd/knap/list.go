@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ghodss/yaml"
+	eventingv1alpha1 "github.com/knative/eventing/pkg/apis/eventing/v1alpha1"
+	"github.com/n3wscott/knap/pkg/graph"
+	"github.com/n3wscott/knap/pkg/knative"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// row is a resource as it is listed.
+type row struct {
+	Kind       string `json:"kind"`
+	APIVersion string `json:"apiVersion"`
+	Name       string `json:"name"`
+	Ready      string `json:"ready,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+
+	// Via is the broker of a trigger or the channel of a subscription.
+	Via        string `json:"via,omitempty"`
+	Filter     string `json:"filter,omitempty"`
+	Subscriber string `json:"subscriber,omitempty"`
+	Sink       string `json:"sink,omitempty"`
+	Owner      string `json:"owner,omitempty"`
+
+	Created time.Time `json:"created"`
+}
+
+var sortKeys = map[string]func(a, b *row) bool{
+	"kind":  func(a, b *row) bool { return a.Kind < b.Kind },
+	"name":  func(a, b *row) bool { return a.Name < b.Name },
+	"ready": func(a, b *row) bool { return a.Ready < b.Ready },
+	"age":   func(a, b *row) bool { return a.Created.After(b.Created) },
+}
+
+func listCmd(o *options, args []string) error {
+	var sortBy, selector string
+	o.fs.StringVar(&sortBy, "sort-by", "kind",
+		"Sort by kind, name, ready or age. Ties are sorted by kind and name.")
+	o.fs.StringVarP(&selector, "selector", "l", "",
+		"Label selector to filter on, such as app=display or 'tier in (web, events)'.")
+	o.addOutput("table", "wide", "json", "yaml")
+	if _, err := o.parse(args); err != nil {
+		return err
+	}
+	less, ok := sortKeys[sortBy]
+	if !ok {
+		return fmt.Errorf("unknown sort key %q, use kind, name, ready or age", sortBy)
+	}
+	if _, err := labels.Parse(selector); err != nil {
+		return err
+	}
+	dc, ns, err := o.dynamic()
+	if err != nil {
+		return err
+	}
+	c := knative.New(dc, knative.WithLabelSelector(selector))
+
+	var rows []*row
+	for _, t := range c.Brokers(ns) {
+		rows = append(rows, newRow(&t, t.TypeMeta, t.ObjectMeta))
+	}
+	for _, t := range c.Triggers(ns) {
+		r := newRow(&t, t.TypeMeta, t.ObjectMeta)
+		r.Via = "Broker/" + t.Spec.Broker
+		r.Filter = filter(t.Spec.Filter)
+		r.Subscriber = subscriber(t.Spec.Subscriber)
+		rows = append(rows, r)
+	}
+	for _, t := range c.Channels(ns) {
+		rows = append(rows, newRow(&t, t.TypeMeta, t.ObjectMeta))
+	}
+	for _, t := range c.Subscriptions(ns) {
+		r := newRow(&t, t.TypeMeta, t.ObjectMeta)
+		r.Via = ref(&t.Spec.Channel)
+		r.Subscriber = subscriber(t.Spec.Subscriber)
+		if t.Spec.Reply != nil {
+			r.Sink = ref(t.Spec.Reply.Channel)
+		}
+		rows = append(rows, r)
+	}
+	for _, t := range c.Sources(ns) {
+		r := newRow(&t, t.TypeMeta, t.ObjectMeta)
+		if t.Status.SinkURI != nil {
+			r.Sink = *t.Status.SinkURI
+		} else {
+			r.Sink = ref(t.Spec.Sink)
+		}
+		rows = append(rows, r)
+	}
+	for _, t := range c.KnServices(ns) {
+		rows = append(rows, newRow(&t, t.TypeMeta, t.ObjectMeta))
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		switch {
+		case less(a, b):
+			return true
+		case less(b, a):
+			return false
+		case a.Kind != b.Kind:
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+
+	switch o.Output {
+	case "json":
+		b, err := json.MarshalIndent(rowsOrEmpty(rows), "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	case "yaml":
+		b, err := yaml.Marshal(rowsOrEmpty(rows))
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(b))
+		return nil
+	}
+
+	if len(rows) == 0 {
+		fmt.Fprintf(os.Stderr, "No resources found in namespace %s.\n", ns)
+		return nil
+	}
+	wide := o.Output == "wide"
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 3, ' ', 0)
+	header := "NAME\tREADY\tBROKER/CHANNEL\tFILTER\tSUBSCRIBER\tSINK\tOWNER\tAGE"
+	if wide {
+		header += "\tREASON\tAPI VERSION"
+	}
+	fmt.Fprintln(w, header)
+	for _, r := range rows {
+		cols := []string{r.Kind + "/" + r.Name, r.Ready, r.Via, r.Filter, r.Subscriber, r.Sink, r.Owner, age(r.Created)}
+		if wide {
+			cols = append(cols, r.Reason, r.APIVersion)
+		}
+		for i, c := range cols {
+			if c == "" {
+				cols[i] = "<none>"
+			}
+		}
+		fmt.Fprintln(w, strings.Join(cols, "\t"))
+	}
+	return w.Flush()
+}
+
+func newRow(obj interface{}, t metav1.TypeMeta, meta metav1.ObjectMeta) *row {
+	r := &row{
+		Kind:       t.Kind,
+		APIVersion: t.APIVersion,
+		Name:       meta.Name,
+		Created:    meta.CreationTimestamp.Time,
+	}
+	if u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj); err == nil {
+		status, _ := u["status"].(map[string]interface{})
+		conditions, _ := status["conditions"].([]interface{})
+		for _, c := range conditions {
+			cond, _ := c.(map[string]interface{})
+			if cond["type"] == "Ready" {
+				r.Ready, _ = cond["status"].(string)
+				r.Reason, _ = cond["reason"].(string)
+			}
+		}
+	}
+	var owners []string
+	for _, o := range meta.OwnerReferences {
+		owners = append(owners, o.Kind+"/"+o.Name)
+	}
+	r.Owner = strings.Join(owners, ",")
+	return r
+}
+
+func filter(f *eventingv1alpha1.TriggerFilter) string {
+	if f == nil || f.SourceAndType == nil {
+		return ""
+	}
+	var parts []string
+	if t := f.SourceAndType.Type; t != eventingv1alpha1.TriggerAnyFilter && t != "Any" {
+		parts = append(parts, "type="+t)
+	}
+	if s := f.SourceAndType.Source; s != eventingv1alpha1.TriggerAnyFilter && s != "Any" {
+		parts = append(parts, "source="+s)
+	}
+	return strings.Join(parts, ",")
+}
+
+func subscriber(s *eventingv1alpha1.SubscriberSpec) string {
+	switch {
+	case s == nil:
+		return ""
+	case s.URI != nil:
+		return *s.URI
+	case s.DeprecatedDNSName != nil:
+		return *s.DeprecatedDNSName
+	}
+	return ref(s.Ref)
+}
+
+func ref(r *corev1.ObjectReference) string {
+	if r == nil || r.Name == "" {
+		return ""
+	}
+	return r.Kind + "/" + r.Name
+}
+
+func age(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return graph.ShortDuration(time.Since(t))
+}
+
+func rowsOrEmpty(rows []*row) []*row {
+	if rows == nil {
+		return []*row{}
+	}
+	return rows
+}
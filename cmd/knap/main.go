@@ -0,0 +1,207 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/n3wscott/knap/pkg/config"
+	"github.com/n3wscott/knap/pkg/graph"
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/dynamic"
+
+	// Uncomment the following line to load the gcp plugin (only required to authenticate against GKE clusters).
+	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+)
+
+// knap explores the eventing topology of a namespace. Installed on the PATH
+// as kubectl-knap it is also a kubectl plugin:
+//   go build -o $GOPATH/bin/kubectl-knap ./cmd/knap && kubectl knap graph
+
+type command struct {
+	name  string
+	args  string
+	short string
+	run   func(o *options, args []string) error
+}
+
+var commands = []*command{
+	{name: "graph", short: "Print the topology as a graph", run: graphCmd},
+	{name: "list", short: "List the resources of the topology", run: listCmd},
+	{name: "describe", args: "KIND/NAME | KEY", short: "Describe a resource and its connections", run: describeCmd},
+	{name: "explore", short: "Explore the topology in a full-screen terminal UI", run: exploreCmd},
+	{name: "lint", short: "Check the topology for problems", run: lintCmd},
+	{name: "export", short: "Print the topology's resources as manifests", run: exportCmd},
+	{name: "serve", short: "Serve the graphs over HTTP", run: serveCmd},
+	{name: "rbac", short: "Print the roles knap serve needs to read the topology", run: rbacCmd},
+}
+
+// options are the flags every command takes.
+type options struct {
+	config.Flags
+	Output string
+	File   string
+
+	fs         *pflag.FlagSet
+	formats    []string
+	extensions map[string]string // format of each file extension
+}
+
+// addOutput adds -o with the formats the command can write, the first being
+// the default.
+func (o *options) addOutput(formats ...string) {
+	o.fs.StringVarP(&o.Output, "output", "o", formats[0], "Output format: "+strings.Join(formats, ", ")+".")
+	o.formats = formats
+}
+
+// acceptFiles lets -o name a file to write instead, in the format of its
+// extension.
+func (o *options) acceptFiles(extensions map[string]string) {
+	o.extensions = extensions
+	var exts []string
+	for ext := range extensions {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	f := o.fs.Lookup("output")
+	f.Usage = strings.TrimSuffix(f.Usage, ".") + ", or a file ending in " + strings.Join(exts, ", ") + "."
+}
+
+// dynamic connects to the cluster and resolves the namespace.
+func (o *options) dynamic() (dynamic.Interface, string, error) {
+	cfg, err := o.RESTConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	ns, err := o.ResolveNamespace()
+	if err != nil {
+		return nil, "", err
+	}
+	dc, err := dynamic.NewForConfig(cfg)
+	return dc, ns, err
+}
+
+// clusters connects to the clusters of the kubeconfig contexts, each in the
+// namespace of its context unless -n says otherwise.
+func (o *options) clusters(contexts []string) ([]graph.Cluster, error) {
+	if o.Context != "" {
+		return nil, errors.New("--context and --contexts cannot both be set")
+	}
+	seen := make(map[string]bool)
+	var clusters []graph.Cluster
+	for _, name := range contexts {
+		if seen[name] {
+			return nil, fmt.Errorf("context %q is given twice", name)
+		}
+		seen[name] = true
+
+		f := o.Flags
+		f.Context = name
+		cfg, err := f.RESTConfig()
+		if err != nil {
+			return nil, fmt.Errorf("context %s: %v", name, err)
+		}
+		ns, err := f.ResolveNamespace()
+		if err != nil {
+			return nil, fmt.Errorf("context %s: %v", name, err)
+		}
+		dc, err := dynamic.NewForConfig(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("context %s: %v", name, err)
+		}
+		clusters = append(clusters, graph.Cluster{Name: name, Client: dc, Namespace: ns})
+	}
+	return clusters, nil
+}
+
+// errFailed exits with status 1 without printing anything more.
+var errFailed = errors.New("failed")
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	name, args := os.Args[1], os.Args[2:]
+	switch name {
+	case "help", "-h", "--help":
+		if len(args) == 0 {
+			usage()
+			return
+		}
+		name, args = args[0], []string{"--help"}
+	}
+
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+		fs := pflag.NewFlagSet(c.name, pflag.ContinueOnError)
+		fs.Usage = func() {
+			fmt.Fprintf(os.Stderr, "%s\n\nUsage:\n  %s %s [flags] %s\n\nFlags:\n%s", c.short, program(), c.name, c.args, fs.FlagUsages())
+		}
+		o := &options{fs: fs}
+		o.AddFlags(fs)
+
+		err := c.run(o, args)
+		switch {
+		case err == pflag.ErrHelp:
+		case err == errFailed:
+			os.Exit(1)
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Error: unknown command %q\n\n", name)
+	usage()
+	os.Exit(2)
+}
+
+// program is how the user ran knap, as a kubectl plugin or on its own.
+func program() string {
+	if base := filepath.Base(os.Args[0]); strings.HasPrefix(base, "kubectl-") {
+		return "kubectl " + strings.TrimPrefix(base, "kubectl-")
+	}
+	return "knap"
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "%s explores the Knative eventing topology of a namespace.\n\nCommands:\n", program())
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.short)
+	}
+	fmt.Fprintf(os.Stderr, "\nUse \"%s help <command>\" for the flags of a command.\n", program())
+}
+
+// parse parses the flags, checks the output format and returns the
+// arguments.
+func (o *options) parse(args []string) ([]string, error) {
+	if err := o.fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if o.formats != nil {
+		ok := false
+		for _, f := range o.formats {
+			ok = ok || f == o.Output
+		}
+		if format, isFile := o.extensions[strings.ToLower(filepath.Ext(o.Output))]; !ok && isFile {
+			if o.File != "" {
+				return nil, fmt.Errorf("-o %s and --file %s both name the file to write", o.Output, o.File)
+			}
+			o.File, o.Output, ok = o.Output, format, true
+		}
+		if !ok && o.extensions != nil {
+			return nil, fmt.Errorf("unknown output format %q, use one of %s or a file name with the extension of one", o.Output, strings.Join(o.formats, ", "))
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown output format %q, use one of %s", o.Output, strings.Join(o.formats, ", "))
+		}
+	}
+	return o.fs.Args(), nil
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/n3wscott/knap/pkg/knative"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var readOnly = []string{"get", "list", "watch"}
+
+// rbacCmd prints the roles and bindings that let a service account read the
+// topology, for knap serve:
+//
+//	knap rbac --namespaces=default,team-a | kubectl apply -f -
+func rbacCmd(o *options, args []string) error {
+	var name, serviceAccount, saNamespace, namespaces string
+	o.fs.StringVar(&name, "name", "knap",
+		"Name of the generated roles and bindings.")
+	o.fs.StringVar(&serviceAccount, "service-account", "knap",
+		"Name of the `service account` to grant the roles to.")
+	o.fs.StringVar(&saNamespace, "service-account-namespace", "default",
+		"Namespace of the service account.")
+	o.fs.StringVar(&namespaces, "namespaces", "default",
+		"Comma separated `namespaces` knap may read, or * for the whole cluster.")
+	o.addOutput("yaml", "json")
+	if _, err := o.parse(args); err != nil {
+		return err
+	}
+	dc, _, err := o.dynamic()
+	if err != nil {
+		return err
+	}
+
+	c := knative.New(dc)
+	resources := c.TopologyResources()
+	if denied := c.Denied(); len(denied) > 0 {
+		return fmt.Errorf("unable to discover the source CRDs, not permitted to list %s", denied[0].String())
+	}
+
+	// Source CRDs are cluster scoped, so discovering them always needs a
+	// ClusterRole. So do the reviews that check callers with AUTH=token or
+	// AUTH=header.
+	clusterRules := []rbacv1.PolicyRule{{
+		APIGroups: []string{"apiextensions.k8s.io"},
+		Resources: []string{"customresourcedefinitions"},
+		Verbs:     readOnly,
+	}, {
+		APIGroups: []string{"authentication.k8s.io"},
+		Resources: []string{"tokenreviews"},
+		Verbs:     []string{"create"},
+	}, {
+		APIGroups: []string{"authorization.k8s.io"},
+		Resources: []string{"subjectaccessreviews"},
+		Verbs:     []string{"create"},
+	}}
+	subjects := []rbacv1.Subject{{
+		Kind:      rbacv1.ServiceAccountKind,
+		Name:      serviceAccount,
+		Namespace: saNamespace,
+	}}
+
+	var objs []interface{}
+	if namespaces == "*" {
+		objs = append(objs,
+			clusterRole(name, append(clusterRules, rules(resources)...)),
+			clusterRoleBinding(name, name, subjects),
+		)
+	} else {
+		objs = append(objs,
+			clusterRole(name, clusterRules),
+			clusterRoleBinding(name, name, subjects),
+		)
+		for _, ns := range strings.Split(namespaces, ",") {
+			if ns = strings.TrimSpace(ns); ns == "" {
+				continue
+			}
+			objs = append(objs,
+				role(name, ns, rules(resources)),
+				roleBinding(name, ns, subjects),
+			)
+		}
+	}
+
+	if o.Output == "json" {
+		b, err := json.MarshalIndent(map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "List",
+			"items":      objs,
+		}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+	for i, obj := range objs {
+		b, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Println("---")
+		}
+		fmt.Print(string(b))
+	}
+	return nil
+}
+
+// rules grants read access to the resources, one rule per API group.
+func rules(resources []knative.Resource) []rbacv1.PolicyRule {
+	byGroup := make(map[string]map[string]bool)
+	for _, r := range resources {
+		if byGroup[r.Group] == nil {
+			byGroup[r.Group] = make(map[string]bool)
+		}
+		byGroup[r.Group][r.Resource] = true
+	}
+
+	var groups []string
+	for group := range byGroup {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	var rules []rbacv1.PolicyRule
+	for _, group := range groups {
+		var names []string
+		for resource := range byGroup[group] {
+			names = append(names, resource)
+		}
+		sort.Strings(names)
+		rules = append(rules, rbacv1.PolicyRule{
+			APIGroups: []string{group},
+			Resources: names,
+			Verbs:     readOnly,
+		})
+	}
+	return rules
+}
+
+func typeMeta(kind string) metav1.TypeMeta {
+	return metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: kind}
+}
+
+func clusterRole(name string, rules []rbacv1.PolicyRule) *rbacv1.ClusterRole {
+	return &rbacv1.ClusterRole{
+		TypeMeta:   typeMeta("ClusterRole"),
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Rules:      rules,
+	}
+}
+
+func clusterRoleBinding(name, roleName string, subjects []rbacv1.Subject) *rbacv1.ClusterRoleBinding {
+	return &rbacv1.ClusterRoleBinding{
+		TypeMeta:   typeMeta("ClusterRoleBinding"),
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     roleName,
+		},
+		Subjects: subjects,
+	}
+}
+
+func role(name, ns string, rules []rbacv1.PolicyRule) *rbacv1.Role {
+	return &rbacv1.Role{
+		TypeMeta:   typeMeta("Role"),
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns},
+		Rules:      rules,
+	}
+}
+
+func roleBinding(name, ns string, subjects []rbacv1.Subject) *rbacv1.RoleBinding {
+	return &rbacv1.RoleBinding{
+		TypeMeta:   typeMeta("RoleBinding"),
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "Role",
+			Name:     name,
+		},
+		Subjects: subjects,
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"github.com/kelseyhightower/envconfig"
+	"github.com/n3wscott/knap/pkg/server"
+)
+
+// serveCmd runs the graph server against the selected cluster. The
+// environment configures it as it does cmd/graph; the flags override it.
+func serveCmd(o *options, args []string) error {
+	var c server.Config
+	if err := envconfig.Process("", &c); err != nil {
+		return err
+	}
+	o.fs.StringVar(&c.ListenAddress, "address", c.ListenAddress,
+		"The host:port to listen on. Defaults to :$PORT.")
+	o.fs.StringVar(&c.Renderer, "renderer", c.Renderer,
+		"How images are drawn: graphviz, builtin or auto.")
+	if _, err := o.parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := o.RESTConfig()
+	if err != nil {
+		return err
+	}
+	if c.Namespace == "" || o.fs.Changed("namespace") {
+		if c.Namespace, err = o.ResolveNamespace(); err != nil {
+			return err
+		}
+	}
+	return server.Run(cfg, c)
+}
@@ -22,8 +22,11 @@ source $(dirname $0)/../vendor/github.com/knative/test-infra/scripts/library.sh
 
 CODEGEN_PKG=${CODEGEN_PKG:-$(cd ${REPO_ROOT_DIR}; ls -d -1 ./vendor/k8s.io/code-generator 2>/dev/null || echo ../../../k8s.io/code-generator)}
 
-# Only deepcopy the Duck types, as they are not real resources.
-${CODEGEN_PKG}/generate-groups.sh "deepcopy" \
+# Only deepcopy the Duck types and generate their listers. No typed clientset
+# or informers are generated: the Duck types are not real resources, and
+# +genclient on SourceType is only there for lister-gen. The sources are read
+# through the dynamic client, and cached by pkg/knative's SourceInformers.
+${CODEGEN_PKG}/generate-groups.sh "deepcopy,lister" \
   github.com/n3wscott/knap/pkg/client github.com/n3wscott/knap/pkg/apis \
   "duck:v1alpha1"
 
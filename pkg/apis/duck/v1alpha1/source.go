@@ -1,7 +1,11 @@
 package v1alpha1
 
 import (
+	"github.com/knative/pkg/apis"
+	duckv1alpha1 "github.com/knative/pkg/apis/duck/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // +genclient
@@ -11,9 +15,39 @@ type SourceType struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
+	Spec   SourceSpec   `json:"spec"`
 	Status SourceStatus `json:"status"`
 }
 
+// SourceSpec is the part of the spec sources share.
+type SourceSpec struct {
+	// Sink is the addressable the source sends events to.
+	Sink *corev1.ObjectReference `json:"sink,omitempty"`
+}
+
 type SourceStatus struct {
+	// Conditions and the generation they were observed at.
+	duckv1alpha1.Status `json:",inline"`
+
+	// SinkURI is the address Sink resolved to, set once the source is
+	// ready to send.
 	SinkURI *string `json:"sinkUri,omitempty"`
 }
+
+// SourceType is listed as a duck type, see knative.SourceInformers.
+var _ apis.Listable = (*SourceType)(nil)
+
+// GetListType implements apis.Listable.
+func (*SourceType) GetListType() runtime.Object {
+	return &SourceTypeList{}
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// SourceTypeList is a list of sources of any kind.
+type SourceTypeList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []SourceType `json:"items"`
+}
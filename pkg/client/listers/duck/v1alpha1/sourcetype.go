@@ -0,0 +1,94 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	v1alpha1 "github.com/n3wscott/knap/pkg/apis/duck/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// SourceTypeLister helps list SourceTypes.
+type SourceTypeLister interface {
+	// List lists all SourceTypes in the indexer.
+	List(selector labels.Selector) (ret []*v1alpha1.SourceType, err error)
+	// SourceTypes returns an object that can list and get SourceTypes.
+	SourceTypes(namespace string) SourceTypeNamespaceLister
+	SourceTypeListerExpansion
+}
+
+// sourceTypeLister implements the SourceTypeLister interface.
+type sourceTypeLister struct {
+	indexer cache.Indexer
+}
+
+// NewSourceTypeLister returns a new SourceTypeLister.
+func NewSourceTypeLister(indexer cache.Indexer) SourceTypeLister {
+	return &sourceTypeLister{indexer: indexer}
+}
+
+// List lists all SourceTypes in the indexer.
+func (s *sourceTypeLister) List(selector labels.Selector) (ret []*v1alpha1.SourceType, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.SourceType))
+	})
+	return ret, err
+}
+
+// SourceTypes returns an object that can list and get SourceTypes.
+func (s *sourceTypeLister) SourceTypes(namespace string) SourceTypeNamespaceLister {
+	return sourceTypeNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// SourceTypeNamespaceLister helps list and get SourceTypes.
+type SourceTypeNamespaceLister interface {
+	// List lists all SourceTypes in the indexer for a given namespace.
+	List(selector labels.Selector) (ret []*v1alpha1.SourceType, err error)
+	// Get retrieves the SourceType from the indexer for a given namespace and name.
+	Get(name string) (*v1alpha1.SourceType, error)
+	SourceTypeNamespaceListerExpansion
+}
+
+// sourceTypeNamespaceLister implements the SourceTypeNamespaceLister
+// interface.
+type sourceTypeNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all SourceTypes in the indexer for a given namespace.
+func (s sourceTypeNamespaceLister) List(selector labels.Selector) (ret []*v1alpha1.SourceType, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.SourceType))
+	})
+	return ret, err
+}
+
+// Get retrieves the SourceType from the indexer for a given namespace and name.
+func (s sourceTypeNamespaceLister) Get(name string) (*v1alpha1.SourceType, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1alpha1.Resource("sourcetype"), name)
+	}
+	return obj.(*v1alpha1.SourceType), nil
+}
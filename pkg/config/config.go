@@ -2,25 +2,13 @@ package config
 
 import (
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
 )
 
-// BuildClientConfig builds the client config specified by the config path and the cluster name
+// BuildClientConfig builds the client config of the kubeconfig context
+// clusterName, or of the current context, with the files in $KUBECONFIG
+// merged unless kubeConfigPath names one. Without either, in a cluster, it
+// uses the pod's service account. See Flags for the other settings.
 func BuildClientConfig(kubeConfigPath string, clusterName string) (*rest.Config, error) {
-
-	if cfg, err := clientcmd.BuildConfigFromFlags("", ""); err == nil {
-		// success!
-		return cfg, nil
-	}
-	// try local...
-
-	overrides := clientcmd.ConfigOverrides{}
-	// Override the cluster name if provided.
-	if clusterName != "" {
-		overrides.Context.Cluster = clusterName
-	}
-
-	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
-		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeConfigPath},
-		&overrides).ClientConfig()
+	f := &Flags{Kubeconfig: kubeConfigPath, Context: clusterName}
+	return f.RESTConfig()
 }
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Where the cluster configuration comes from.
+const (
+	SourceAuto       = "auto"
+	SourceKubeconfig = "kubeconfig"
+	SourceInCluster  = "in-cluster"
+)
+
+// namespaceFile holds the namespace of the pod's service account.
+const namespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// Flags are the kubectl style flags that pick the cluster and namespace, and
+// tune the clients made for it.
+type Flags struct {
+	// Source is where the configuration comes from, see RESTConfig.
+	Source string
+
+	// Kubeconfig is the kubeconfig file. Without it the files listed in
+	// $KUBECONFIG are merged, or ~/.kube/config is used.
+	Kubeconfig string
+	Context    string
+	Namespace  string
+
+	// AsUser and AsGroups impersonate a user and its groups.
+	AsUser   string
+	AsGroups []string
+
+	// Timeout limits every request, zero for no limit.
+	Timeout time.Duration
+
+	// QPS and Burst limit the rate of requests, zero for client-go's
+	// defaults.
+	QPS   float32
+	Burst int
+}
+
+// AddFlags adds the flags to fs.
+func (f *Flags) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&f.Source, "config-source", SourceAuto,
+		"Where the cluster configuration comes from: kubeconfig, in-cluster, or auto to use a kubeconfig given by flag or $KUBECONFIG, then the pod's service account, then ~/.kube/config.")
+	fs.StringVar(&f.Kubeconfig, "kubeconfig", "",
+		"Path to the kubeconfig file. Defaults to the files in $KUBECONFIG, merged, or ~/.kube/config.")
+	fs.StringVar(&f.Context, "context", "",
+		"The kubeconfig context to use. Defaults to the current context.")
+	fs.StringVarP(&f.Namespace, "namespace", "n", "",
+		"The namespace to use. Defaults to the namespace of the kubeconfig context or the pod.")
+	fs.StringVar(&f.AsUser, "as", "",
+		"Username to impersonate.")
+	fs.StringArrayVar(&f.AsGroups, "as-group", nil,
+		"Group to impersonate, may be repeated.")
+	fs.DurationVar(&f.Timeout, "request-timeout", 0,
+		"How long to wait for a single request, such as 30s. Zero waits forever.")
+	fs.Float32Var(&f.QPS, "qps", 0,
+		"Requests per second to the API server. Defaults to client-go's 5.")
+	fs.IntVar(&f.Burst, "burst", 0,
+		"Requests allowed in a burst above --qps. Defaults to client-go's 10.")
+}
+
+// AddGoFlags adds the flags to a flag.FlagSet of the standard library,
+// without the -n shorthand.
+func (f *Flags) AddGoFlags(fs *flag.FlagSet) {
+	pfs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	f.AddFlags(pfs)
+	pfs.VisitAll(func(pf *pflag.Flag) {
+		fs.Var(pf.Value, pf.Name, pf.Usage)
+	})
+}
+
+// ClientConfig loads the kubeconfig the way kubectl does.
+func (f *Flags) ClientConfig() clientcmd.ClientConfig {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = f.Kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: f.Context}
+	overrides.Context.Namespace = f.Namespace
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+}
+
+// source resolves SourceAuto: a kubeconfig named by flag or $KUBECONFIG, or a
+// context asked for, wins over the pod's service account, which wins over
+// ~/.kube/config.
+func (f *Flags) source() (string, error) {
+	switch f.Source {
+	case SourceKubeconfig, SourceInCluster:
+		return f.Source, nil
+	case SourceAuto, "":
+	default:
+		return "", fmt.Errorf("unknown config source %q, use auto, kubeconfig or in-cluster", f.Source)
+	}
+	switch {
+	case f.Kubeconfig != "" || f.Context != "" || os.Getenv(clientcmd.RecommendedConfigPathEnvVar) != "":
+		return SourceKubeconfig, nil
+	case os.Getenv("KUBERNETES_SERVICE_HOST") != "" && os.Getenv("KUBERNETES_SERVICE_PORT") != "":
+		return SourceInCluster, nil
+	}
+	return SourceKubeconfig, nil
+}
+
+// RESTConfig returns the config of the selected cluster, with the
+// impersonation, timeout and rate limits applied.
+func (f *Flags) RESTConfig() (*rest.Config, error) {
+	if len(f.AsGroups) > 0 && f.AsUser == "" {
+		return nil, errors.New("impersonating groups needs a user, set --as as well")
+	}
+	source, err := f.source()
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg *rest.Config
+	if source == SourceInCluster {
+		cfg, err = rest.InClusterConfig()
+	} else {
+		cfg, err = f.ClientConfig().ClientConfig()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if f.AsUser != "" {
+		cfg.Impersonate = rest.ImpersonationConfig{UserName: f.AsUser, Groups: f.AsGroups}
+	}
+	if f.Timeout != 0 {
+		cfg.Timeout = f.Timeout
+	}
+	if f.QPS != 0 {
+		cfg.QPS = f.QPS
+	}
+	if f.Burst != 0 {
+		cfg.Burst = f.Burst
+	}
+	return cfg, nil
+}
+
+// ResolveNamespace returns the namespace flag or, without it, the namespace
+// of the kubeconfig context or of the pod, or "default".
+func (f *Flags) ResolveNamespace() (string, error) {
+	if f.Namespace != "" {
+		return f.Namespace, nil
+	}
+	source, err := f.source()
+	if err != nil {
+		return "", err
+	}
+	if source == SourceInCluster {
+		if ns := os.Getenv("POD_NAMESPACE"); ns != "" {
+			return ns, nil
+		}
+		if b, err := ioutil.ReadFile(namespaceFile); err == nil {
+			if ns := strings.TrimSpace(string(b)); ns != "" {
+				return ns, nil
+			}
+		}
+		return "default", nil
+	}
+	ns, _, err := f.ClientConfig().Namespace()
+	return ns, err
+}
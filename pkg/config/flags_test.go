@@ -0,0 +1,236 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const alphaConfig = `
+apiVersion: v1
+kind: Config
+current-context: alpha
+clusters:
+- name: alpha
+  cluster: {server: "https://alpha.example.com"}
+contexts:
+- name: alpha
+  context: {cluster: alpha, user: alpha, namespace: shop}
+users:
+- name: alpha
+  user: {token: alpha-token}
+`
+
+const betaConfig = `
+apiVersion: v1
+kind: Config
+current-context: beta
+clusters:
+- name: beta
+  cluster: {server: "https://beta.example.com"}
+contexts:
+- name: beta
+  context: {cluster: beta, user: beta}
+users:
+- name: beta
+  user: {token: beta-token}
+`
+
+// kubeconfigs writes the alpha and beta kubeconfigs, and points $KUBECONFIG
+// at both, alpha first, returning their paths. Call the returned func to
+// restore the environment.
+func kubeconfigs(t *testing.T) (alpha, beta string, restore func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "knap-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	alpha = filepath.Join(dir, "alpha")
+	beta = filepath.Join(dir, "beta")
+	for path, content := range map[string]string{alpha: alphaConfig, beta: betaConfig} {
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	restoreEnv := setenv(map[string]string{
+		clientcmd.RecommendedConfigPathEnvVar: alpha + string(filepath.ListSeparator) + beta,
+		"KUBERNETES_SERVICE_HOST":             "",
+		"KUBERNETES_SERVICE_PORT":             "",
+	})
+	return alpha, beta, func() {
+		restoreEnv()
+		_ = os.RemoveAll(dir)
+	}
+}
+
+// setenv sets the variables, an empty value unsets one, and returns a func
+// restoring them.
+func setenv(vars map[string]string) func() {
+	prev := make(map[string]*string)
+	for k, v := range vars {
+		if old, ok := os.LookupEnv(k); ok {
+			prev[k] = &old
+		} else {
+			prev[k] = nil
+		}
+		if v == "" {
+			_ = os.Unsetenv(k)
+		} else {
+			_ = os.Setenv(k, v)
+		}
+	}
+	return func() {
+		for k, v := range prev {
+			if v == nil {
+				_ = os.Unsetenv(k)
+			} else {
+				_ = os.Setenv(k, *v)
+			}
+		}
+	}
+}
+
+func TestRESTConfigKubeconfig(t *testing.T) {
+	_, beta, restore := kubeconfigs(t)
+	defer restore()
+
+	tests := []struct {
+		name          string
+		flags         Flags
+		wantHost      string
+		wantNamespace string
+	}{{
+		name:          "merged, current context of the first file",
+		wantHost:      "https://alpha.example.com",
+		wantNamespace: "shop",
+	}, {
+		name:          "merged, context flag",
+		flags:         Flags{Context: "beta"},
+		wantHost:      "https://beta.example.com",
+		wantNamespace: "default",
+	}, {
+		name:          "kubeconfig flag wins over $KUBECONFIG",
+		flags:         Flags{Kubeconfig: beta},
+		wantHost:      "https://beta.example.com",
+		wantNamespace: "default",
+	}, {
+		name:          "namespace flag wins over the context's",
+		flags:         Flags{Namespace: "demo"},
+		wantHost:      "https://alpha.example.com",
+		wantNamespace: "demo",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := tt.flags.RESTConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Host != tt.wantHost {
+				t.Errorf("host = %s, want %s", cfg.Host, tt.wantHost)
+			}
+			ns, err := tt.flags.ResolveNamespace()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ns != tt.wantNamespace {
+				t.Errorf("namespace = %s, want %s", ns, tt.wantNamespace)
+			}
+		})
+	}
+
+	if _, err := (&Flags{Context: "gamma"}).RESTConfig(); err == nil {
+		t.Error("an unknown context did not fail")
+	}
+}
+
+func TestRESTConfigOverrides(t *testing.T) {
+	_, _, restore := kubeconfigs(t)
+	defer restore()
+
+	f := Flags{
+		AsUser:   "jane",
+		AsGroups: []string{"dev", "ops"},
+		Timeout:  30 * time.Second,
+		QPS:      50,
+		Burst:    100,
+	}
+	cfg, err := f.RESTConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Impersonate.UserName != "jane" || !reflect.DeepEqual(cfg.Impersonate.Groups, []string{"dev", "ops"}) {
+		t.Errorf("impersonating %+v, want jane in dev and ops", cfg.Impersonate)
+	}
+	if cfg.Timeout != 30*time.Second || cfg.QPS != 50 || cfg.Burst != 100 {
+		t.Errorf("timeout, qps and burst = %s, %g, %d", cfg.Timeout, cfg.QPS, cfg.Burst)
+	}
+
+	if _, err := (&Flags{AsGroups: []string{"dev"}}).RESTConfig(); err == nil {
+		t.Error("impersonating groups without a user did not fail")
+	}
+}
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		flags   Flags
+		env     map[string]string
+		want    string
+		wantErr bool
+	}{{
+		name: "nothing, outside a cluster",
+		want: SourceKubeconfig,
+	}, {
+		name: "in a cluster",
+		env:  map[string]string{"KUBERNETES_SERVICE_HOST": "10.0.0.1", "KUBERNETES_SERVICE_PORT": "443"},
+		want: SourceInCluster,
+	}, {
+		name:  "context flag, in a cluster",
+		flags: Flags{Context: "alpha"},
+		env:   map[string]string{"KUBERNETES_SERVICE_HOST": "10.0.0.1", "KUBERNETES_SERVICE_PORT": "443"},
+		want:  SourceKubeconfig,
+	}, {
+		name: "$KUBECONFIG, in a cluster",
+		env: map[string]string{
+			"KUBERNETES_SERVICE_HOST":             "10.0.0.1",
+			"KUBERNETES_SERVICE_PORT":             "443",
+			clientcmd.RecommendedConfigPathEnvVar: "/etc/kubeconfig",
+		},
+		want: SourceKubeconfig,
+	}, {
+		name:  "forced in-cluster",
+		flags: Flags{Source: SourceInCluster, Kubeconfig: "/etc/kubeconfig"},
+		want:  SourceInCluster,
+	}, {
+		name:    "unknown",
+		flags:   Flags{Source: "cloud"},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := map[string]string{
+				clientcmd.RecommendedConfigPathEnvVar: "",
+				"KUBERNETES_SERVICE_HOST":             "",
+				"KUBERNETES_SERVICE_PORT":             "",
+			}
+			for k, v := range tt.env {
+				env[k] = v
+			}
+			defer setenv(env)()
+
+			got, err := tt.flags.source()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %t", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("source = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package graph
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/n3wscott/knap/pkg/knative"
+	"github.com/tmc/dot"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+)
+
+// AddressesAnnotation lists, comma separated, more URLs a broker, channel or
+// service is reachable at, such as those of an ingress exposing it to other
+// clusters.
+const AddressesAnnotation = "knap/addresses"
+
+// Cluster is a cluster, and the namespace in it, to load the topology of.
+type Cluster struct {
+	// Name tells the cluster's resources apart from those of the others,
+	// usually the kubeconfig context.
+	Name      string
+	Client    dynamic.Interface
+	Namespace string
+
+	// Sources, if set, caches the sources of the namespace.
+	Sources *knative.SourceInformers
+}
+
+// WithSourceInformers lists the sources of the namespace from the informers'
+// caches instead of the API server. Clusters set their own.
+func WithSourceInformers(s *knative.SourceInformers) Option {
+	return func(g *Graph) {
+		g.sources = s
+	}
+}
+
+// LoadClusterTriggers builds one trigger graph of the namespaces in several
+// clusters. Sinks and subscribers addressed by an external URL of a broker or
+// service in another cluster are linked to it.
+func LoadClusterTriggers(clusters []Cluster, opts ...Option) *Graph {
+	g := newClusterGraph(clusters, opts...)
+	g.loadTriggers(clusters)
+	return g
+}
+
+// LoadClusterSubscriptions builds one subscription graph of the namespaces in
+// several clusters.
+func LoadClusterSubscriptions(clusters []Cluster, opts ...Option) *Graph {
+	g := newClusterGraph(clusters, opts...)
+	g.loadTriggers(clusters)
+	g.loadSubscriptions(clusters)
+	return g
+}
+
+func newClusterGraph(clusters []Cluster, opts ...Option) *Graph {
+	var ns string
+	var names []string
+	for _, c := range clusters {
+		if ns == "" {
+			ns = c.Namespace
+		}
+		names = append(names, c.Name)
+	}
+	g := New(ns, opts...)
+	_ = g.Set("label", "Triggers in "+ns+" on "+strings.Join(names, ", "))
+	return g
+}
+
+// in makes what is added next belong to the cluster c.
+func (g *Graph) in(c Cluster) {
+	g.cluster = c.Name
+	g.ns = c.Namespace
+}
+
+// scope qualifies key with the cluster being loaded, so the same resource in
+// two clusters gets two nodes.
+func (g *Graph) scope(key string) string {
+	if g.cluster == "" {
+		return key
+	}
+	return "cluster/" + strings.ToLower(g.cluster) + "/" + key
+}
+
+// newNode returns a node named name, qualified by the cluster being loaded
+// the same way as keys. The label stays name.
+func (g *Graph) newNode(name string) *dot.Node {
+	if g.cluster == "" {
+		return dot.NewNode(name)
+	}
+	n := dot.NewNode(g.cluster + "/" + name)
+	_ = n.Set("label", name)
+	return n
+}
+
+// unclustered returns from without its cluster, for drawing an address
+// outside every cluster in from's other groups.
+func unclustered(from *metav1.ObjectMeta) *metav1.ObjectMeta {
+	m := from.DeepCopy()
+	m.ClusterName = ""
+	return m
+}
+
+// register records that uri reaches the node with the key. Addresses only
+// reachable within a cluster are scoped to the cluster being loaded.
+func (g *Graph) register(uri, key string) {
+	if uri = normalizeURI(uri); uri != "" {
+		g.dnsToKey[g.addressKey(uri)] = key
+	}
+}
+
+// registerAnnotated registers the addresses listed in AddressesAnnotation.
+func (g *Graph) registerAnnotated(obj metav1.Object, key string) {
+	for _, uri := range strings.Split(obj.GetAnnotations()[AddressesAnnotation], ",") {
+		g.register(strings.TrimSpace(uri), key)
+	}
+}
+
+// resolve returns the key of the node uri reaches, if one was registered.
+func (g *Graph) resolve(uri string) (string, bool) {
+	key, ok := g.dnsToKey[g.addressKey(normalizeURI(uri))]
+	return key, ok
+}
+
+func (g *Graph) addressKey(uri string) string {
+	if clusterLocal(uri) {
+		return g.scope(uri)
+	}
+	return uri
+}
+
+// uriKey is the key of the node of an address no loaded object serves.
+func (g *Graph) uriKey(uri string) string {
+	if clusterLocal(uri) {
+		return g.scope(uriKey(uri))
+	}
+	return uriKey(uri)
+}
+
+// unknownSink returns the node of an address no loaded object serves, drawn in
+// the groups of from, the object sending to it.
+func (g *Graph) unknownSink(uri string, from *metav1.ObjectMeta) *dot.Node {
+	key := g.uriKey(uri)
+	if n, ok := g.nodes[key]; ok {
+		return n
+	}
+	var n *dot.Node
+	if clusterLocal(uri) {
+		n = g.newNode("UnknownSink " + uri)
+	} else {
+		n = dot.NewNode("UnknownSink " + uri)
+		from = unclustered(from)
+	}
+	g.addNode(g.groupFor(from), n)
+	g.nodes[key] = n
+	g.recordURI(n, uri)
+	return n
+}
+
+// normalizeURI ends uri in a slash, so addresses compare equal however they
+// were written, and adds the http scheme to a bare host name.
+func normalizeURI(uri string) string {
+	if uri == "" {
+		return ""
+	}
+	if !strings.Contains(uri, "://") {
+		uri = "http://" + uri
+	}
+	if !strings.HasSuffix(uri, "/") {
+		uri += "/"
+	}
+	return uri
+}
+
+// clusterLocal reports whether the host of uri only resolves within a
+// cluster: a service's short name or its name under .svc.
+func clusterLocal(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	return !strings.Contains(host, ".") ||
+		strings.HasSuffix(host, ".svc") ||
+		strings.Contains(host, ".svc.") ||
+		strings.HasSuffix(host, ".cluster.local")
+}
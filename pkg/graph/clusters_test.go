@@ -0,0 +1,89 @@
+package graph
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// eastYAML has a broker exposed to other clusters by an ingress.
+const eastYAML = `
+apiVersion: eventing.knative.dev/v1alpha1
+kind: Broker
+metadata:
+  name: default
+  namespace: demo
+  annotations: {knap/addresses: "https://events.east.example.com, https://events.example.com"}
+status:
+  address: {hostname: default-broker.demo.svc.cluster.local}
+`
+
+// westYAML has sources sending to east's broker by its external address, to
+// a broker of its own that does not exist, and outside both clusters.
+const westYAML = `
+apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+  name: cronjobsources.sources.eventing.knative.dev
+  labels: {eventing.knative.dev/source: "true"}
+spec:
+  group: sources.eventing.knative.dev
+  versions: [{name: v1alpha1, served: true, storage: true}]
+  names: {kind: CronJobSource, plural: cronjobsources}
+---
+apiVersion: sources.eventing.knative.dev/v1alpha1
+kind: CronJobSource
+metadata: {name: remote, namespace: demo}
+status: {sinkUri: "https://events.example.com"}
+---
+apiVersion: sources.eventing.knative.dev/v1alpha1
+kind: CronJobSource
+metadata: {name: local, namespace: demo}
+status: {sinkUri: "http://default-broker.demo.svc.cluster.local"}
+---
+apiVersion: sources.eventing.knative.dev/v1alpha1
+kind: CronJobSource
+metadata: {name: outside, namespace: demo}
+status: {sinkUri: "https://hooks.example.org/events"}
+`
+
+func TestClusterSinks(t *testing.T) {
+	g := LoadClusterTriggers([]Cluster{
+		{Name: "east", Client: snapshot(t, eastYAML), Namespace: "demo"},
+		{Name: "West", Client: snapshot(t, westYAML), Namespace: "demo"},
+	})
+
+	sinks := make(map[string]string)
+	for _, e := range g.edges {
+		if e.Relation == "sink" {
+			sinks[e.From] = e.To
+		}
+	}
+	const source = "sources.eventing.knative.dev/v1alpha1/cronjobsource/"
+	want := map[string]string{
+		// The external address reaches east's broker.
+		"cluster/west/" + source + "remote": "cluster/east/eventing.knative.dev/v1alpha1/broker/default",
+		// The same cluster local address does not, it is west's own.
+		"cluster/west/" + source + "local": "cluster/west/uri/http://default-broker.demo.svc.cluster.local/",
+		// An address nothing serves is in no cluster.
+		"cluster/west/" + source + "outside": "uri/https://hooks.example.org/events/",
+	}
+	if !reflect.DeepEqual(sinks, want) {
+		t.Errorf("sinks = %v, want %v", sinks, want)
+	}
+
+	var clusters []string
+	for key, n := range g.model {
+		if n.Kind == "URI" {
+			clusters = append(clusters, key+" in "+n.Cluster)
+		}
+	}
+	sort.Strings(clusters)
+	wantClusters := []string{
+		"cluster/west/uri/http://default-broker.demo.svc.cluster.local/ in West",
+		"uri/https://hooks.example.org/events/ in ",
+	}
+	if !reflect.DeepEqual(clusters, wantClusters) {
+		t.Errorf("addresses = %q, want %q", clusters, wantClusters)
+	}
+}
@@ -0,0 +1,166 @@
+package graph
+
+import (
+	"strings"
+)
+
+// maxPaths bounds the paths an explanation lists, topologies where every
+// trigger feeds the broker again have very many.
+const maxPaths = 20
+
+// Explanation is where the events of a node come from and where they go,
+// from the same edges the graph is drawn with.
+type Explanation struct {
+	Node *Node `json:"node"`
+
+	// Filter is the filter of a trigger, "type=... source=...", or empty
+	// when it passes every event.
+	Filter string `json:"filter,omitempty"`
+
+	In  []Edge `json:"in"`
+	Out []Edge `json:"out"`
+
+	// Replies are the nodes the replies of the node's subscriber go to.
+	Replies []string `json:"replies,omitempty"`
+
+	// Paths are the chains of keys events take through the node, from
+	// where they enter the namespace to where they end.
+	Paths [][]string `json:"paths"`
+
+	// Problems are what Lint finds on the paths, including references that
+	// do not resolve.
+	Problems []Problem `json:"problems,omitempty"`
+}
+
+// Explain explains the node with the key.
+func (g *Graph) Explain(key string) (*Explanation, bool) {
+	n, ok := g.Node(key)
+	if !ok {
+		return nil, false
+	}
+	in, out := g.Edges(n.Key)
+	e := &Explanation{Node: n, In: in, Out: out, Filter: triggerFilter(n)}
+
+	switch n.Kind {
+	case "Trigger":
+		// The broker's ingress receives the replies of its triggers.
+		for _, edge := range in {
+			if edge.Relation == "trigger" {
+				e.Replies = append(e.Replies, edge.From)
+			}
+		}
+	case "Subscription":
+		for _, edge := range out {
+			if edge.Relation == "reply" {
+				e.Replies = append(e.Replies, edge.To)
+			}
+		}
+	}
+
+	upstream := g.walk(n.Key, true)
+	downstream := g.walk(n.Key, false)
+	on := make(map[string]bool)
+	listed := make(map[string]bool)
+	for _, up := range upstream {
+		for _, down := range downstream {
+			if len(e.Paths) == maxPaths {
+				break
+			}
+			path := join(up, down)
+			if id := strings.Join(path, " "); !listed[id] {
+				listed[id] = true
+				e.Paths = append(e.Paths, path)
+			}
+			for _, k := range path {
+				on[k] = true
+			}
+		}
+	}
+	for _, p := range g.Lint() {
+		if on[p.Key] {
+			e.Problems = append(e.Problems, p)
+		}
+	}
+	return e, true
+}
+
+// walk returns the chains of keys from key to every end of the topology,
+// following edges backwards when up is set. Backward chains are returned in
+// the direction events flow, ending with key.
+func (g *Graph) walk(key string, up bool) [][]string {
+	var paths [][]string
+	var visit func(chain []string, seen map[string]bool)
+	visit = func(chain []string, seen map[string]bool) {
+		if len(paths) == maxPaths {
+			return
+		}
+		in, out := g.Edges(chain[len(chain)-1])
+		next := out
+		if up {
+			next = in
+		}
+		extended := false
+		for _, edge := range next {
+			k := edge.To
+			if up {
+				k = edge.From
+			}
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			visit(append(append([]string{}, chain...), k), seen)
+			delete(seen, k)
+			extended = true
+		}
+		if !extended {
+			paths = append(paths, append([]string{}, chain...))
+		}
+	}
+	visit([]string{key}, map[string]bool{key: true})
+
+	if up {
+		for _, p := range paths {
+			for i, j := 0, len(p)-1; i < j; i, j = i+1, j-1 {
+				p[i], p[j] = p[j], p[i]
+			}
+		}
+	}
+	return paths
+}
+
+// join joins the chain up to a node with the chain down from it. Each walk
+// visits a node once, but the two may share nodes when events loop back:
+// the path stops before it comes back to a node it has been through.
+func join(up, down []string) []string {
+	path := append([]string{}, up...)
+	seen := make(map[string]bool)
+	for _, k := range up {
+		seen[k] = true
+	}
+	for _, k := range down[1:] {
+		if seen[k] {
+			break
+		}
+		seen[k] = true
+		path = append(path, k)
+	}
+	return path
+}
+
+// triggerFilter formats the filter of a trigger node.
+func triggerFilter(n *Node) string {
+	if n.Kind != "Trigger" || n.Object == nil {
+		return ""
+	}
+	spec, _ := n.Object["spec"].(map[string]interface{})
+	filter, _ := spec["filter"].(map[string]interface{})
+	sat, _ := filter["sourceAndType"].(map[string]interface{})
+	var parts []string
+	for _, attr := range []string{"type", "source"} {
+		if v, _ := sat[attr].(string); v != "" && v != "Any" {
+			parts = append(parts, attr+"="+v)
+		}
+	}
+	return strings.Join(parts, " ")
+}
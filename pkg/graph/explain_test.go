@@ -0,0 +1,94 @@
+package graph
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// loopYAML is a source sending to a broker, whose trigger sends to a service
+// that sends back to the broker.
+const loopYAML = `
+apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+  name: cronjobsources.sources.eventing.knative.dev
+  labels: {eventing.knative.dev/source: "true"}
+spec:
+  group: sources.eventing.knative.dev
+  names: {kind: CronJobSource, plural: cronjobsources}
+  versions:
+  - {name: v1alpha1, served: true, storage: true}
+---
+apiVersion: sources.eventing.knative.dev/v1alpha1
+kind: CronJobSource
+metadata: {name: cron, namespace: demo}
+status:
+  sinkUri: http://default-broker.demo.svc.cluster.local/
+---
+apiVersion: eventing.knative.dev/v1alpha1
+kind: Broker
+metadata: {name: default, namespace: demo}
+status:
+  address: {hostname: default-broker.demo.svc.cluster.local}
+---
+apiVersion: eventing.knative.dev/v1alpha1
+kind: Trigger
+metadata: {name: t1, namespace: demo}
+spec:
+  broker: default
+  subscriber: {ref: {apiVersion: serving.knative.dev/v1alpha1, kind: Service, name: display}}
+---
+apiVersion: serving.knative.dev/v1alpha1
+kind: Service
+metadata: {name: display, namespace: demo}
+spec:
+  runLatest:
+    configuration:
+      revisionTemplate:
+        spec:
+          container:
+            env:
+            - {name: SINK, value: "http://default-broker.demo.svc.cluster.local/"}
+status:
+  address: {hostname: display.demo.svc.cluster.local}
+`
+
+func TestExplainLoop(t *testing.T) {
+	const (
+		cron    = "sources.eventing.knative.dev/v1alpha1/cronjobsource/cron"
+		broker  = "eventing.knative.dev/v1alpha1/broker/default"
+		trigger = "eventing.knative.dev/v1alpha1/trigger/t1"
+		display = "serving.knative.dev/v1alpha1/service/display"
+	)
+	g := LoadTopology(snapshot(t, loopYAML), "demo")
+	e, ok := g.Explain(trigger)
+	if !ok {
+		t.Fatal("trigger not found")
+	}
+
+	want := [][]string{
+		{display, broker, trigger},
+		{cron, broker, trigger, display},
+	}
+	if !reflect.DeepEqual(e.Paths, want) {
+		t.Errorf("paths =\n%s\nwant\n%s", formatPaths(e.Paths), formatPaths(want))
+	}
+	for _, path := range e.Paths {
+		seen := make(map[string]bool)
+		for _, k := range path {
+			if seen[k] {
+				t.Errorf("path %s visits %s twice", strings.Join(path, " -> "), k)
+			}
+			seen[k] = true
+		}
+	}
+}
+
+func formatPaths(paths [][]string) string {
+	var lines []string
+	for _, path := range paths {
+		lines = append(lines, strings.Join(path, " -> "))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -6,38 +6,92 @@ import (
 	knduckv1alpha1 "github.com/knative/pkg/apis/duck/v1alpha1"
 	servingv1alpha1 "github.com/knative/serving/pkg/apis/serving/v1alpha1"
 	duckv1alpha1 "github.com/n3wscott/knap/pkg/apis/duck/v1alpha1"
+	"github.com/n3wscott/knap/pkg/knative"
 	"github.com/tmc/dot"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"strings"
 )
 
 type Graph struct {
 	*dot.Graph
+	ns        string
+	cluster   string // the cluster being loaded, "" for a single cluster
 	nodes     map[string]*dot.Node
 	subgraphs map[string]*dot.SubGraph
 	dnsToKey  map[string]string // maps domain name to node key
 
-	edgeCount   int
-	rainbowEdge bool
+	groupings []Grouping
+	groups    map[string]*dot.SubGraph // maps group path to cluster
+
+	expandInternals bool
+
+	links Links // URL templates by kind
+
+	versions []string // resource versions of everything added
+
+	sources *knative.SourceInformers // caches the sources of ns
+
+	authorizers   []knative.Authorizer
+	hidden        map[string]bool // group/kind that may not be shown
+	notPermitted  map[string]bool // resource.group that could not be listed
+	badge         *dot.Node
+	redactedCount int
+
+	model     map[string]*Node     // nodes by key
+	modelKeys map[*dot.Node]string // maps drawn node to its key in the model
+	edges     []Edge
+
+	clusterCount int
+	edgeCount    int
+	rainbowEdge  bool
+}
+
+// Option configures a Graph.
+type Option func(*Graph)
+
+// WithGrouping nests resources into clusters using the given groupings,
+// outermost first.
+func WithGrouping(groupings ...Grouping) Option {
+	return func(g *Graph) {
+		g.groupings = append(g.groupings, groupings...)
+	}
 }
 
-func New(ns string) *Graph {
+func New(ns string, opts ...Option) *Graph {
 	g := dot.NewGraph("G")
 	_ = g.Set("shape", "box")
 	_ = g.Set("label", "Triggers in "+ns)
 	_ = g.Set("rankdir", "LR")
 
 	graph := &Graph{
-		Graph:       g,
-		nodes:       make(map[string]*dot.Node),
-		subgraphs:   make(map[string]*dot.SubGraph),
-		dnsToKey:    make(map[string]string),
-		rainbowEdge: true,
+		Graph:        g,
+		ns:           ns,
+		nodes:        make(map[string]*dot.Node),
+		subgraphs:    make(map[string]*dot.SubGraph),
+		dnsToKey:     make(map[string]string),
+		groups:       make(map[string]*dot.SubGraph),
+		hidden:       make(map[string]bool),
+		notPermitted: make(map[string]bool),
+		model:        make(map[string]*Node),
+		modelKeys:    make(map[*dot.Node]string),
+		rainbowEdge:  true,
+	}
+
+	for _, opt := range opts {
+		opt(graph)
 	}
 
 	return graph
 }
 
+// setNode registers n under key, which also becomes the node's id so it can
+// be found in the rendered SVG.
+func (g *Graph) setNode(key string, n *dot.Node) {
+	_ = n.Set("id", key)
+	g.nodes[key] = n
+}
+
 func (g *Graph) newEdge(src, dst *dot.Node) *dot.Edge {
 	e := dot.NewEdge(src, dst)
 	if g.rainbowEdge {
@@ -49,90 +103,140 @@ func (g *Graph) newEdge(src, dst *dot.Node) *dot.Edge {
 }
 
 func (g *Graph) AddChannel(channel eventingv1alpha1.Channel) {
-	ck := channelKey(channel.Name)
+	g.observe(&channel.ObjectMeta)
+
+	ck := g.scope(channelKey(channel.Name))
 	dns := addressableDNS(channel.Status.Address)
-	cn := dot.NewNode("Channel " + channel.Name)
+
+	owner, owned := g.ownerKey(&channel.ObjectMeta)
+	if owned && !g.expandInternals {
+		g.collapse(ck, owner)
+		g.register(dns, owner)
+		return
+	}
+
+	cn := g.newNode("Channel " + channel.Name)
 
 	setNodeShapeForKind(cn, channel.Kind, channel.APIVersion)
 
 	_ = cn.Set("shape", "oval") // TODO move to setNodeShapeForKind
 	_ = cn.Set("label", "Ingress")
 
-	g.nodes[ck] = cn
-	g.dnsToKey[dns] = ck
+	g.setNode(ck, cn)
+	g.record(ck, cn, &channel)
+	g.register(dns, ck)
+	g.registerAnnotated(&channel, ck)
 
-	cg := dot.NewSubgraph(fmt.Sprintf("cluster_%d", len(g.subgraphs)))
-	_ = cg.Set("label", fmt.Sprintf("Channel %s\n%s", channel.Name, dns))
+	cg := g.newCluster(fmt.Sprintf("Channel %s\n%s", channel.Name, dns))
 	g.subgraphs[ck] = cg
 	cg.AddNode(cn)
-	g.AddSubgraph(cg)
+	if og, ok := g.subgraphs[owner]; owned && ok {
+		// Internal channels are drawn inside their owner.
+		og.AddSubgraph(cg)
+	} else {
+		g.addSubgraph(g.groupFor(&channel.ObjectMeta), cg)
+	}
 }
 
 func (g *Graph) AddSubscription(subscription eventingv1alpha1.Subscription) {
-	sk := subscriptionKey(subscription.Name)
-	sn := dot.NewNode("Subscription " + subscription.Name)
+	g.observe(&subscription.ObjectMeta)
 
-	ck := gvkKey(subscription.Spec.Channel.GroupVersionKind(), subscription.Spec.Channel.Name)
+	sk := g.scope(subscriptionKey(subscription.Name))
+
+	if owner, owned := g.ownerKey(&subscription.ObjectMeta); owned && !g.expandInternals {
+		g.collapse(sk, owner)
+		return
+	}
+
+	sn := g.newNode("Subscription " + subscription.Name)
+
+	ck := g.scope(gvkKey(subscription.Spec.Channel.GroupVersionKind(), subscription.Spec.Channel.Name))
 
 	if cg, ok := g.subgraphs[ck]; !ok {
-		g.AddNode(sn)
+		g.addNode(g.groupFor(&subscription.ObjectMeta), sn)
 	} else {
 		cg.AddNode(sn)
 	}
-	g.nodes[sk] = sn
+	g.setNode(sk, sn)
+	g.record(sk, sn, &subscription)
+	if cn, ok := g.nodes[ck]; ok {
+		g.link(cn, sn, "subscription")
+	} else if g.isHidden(subscription.Spec.Channel.APIVersion, subscription.Spec.Channel.Kind) {
+		cn = g.placeholder(ck, subscription.Spec.Channel.Kind, &subscription.ObjectMeta)
+		g.AddEdge(g.newEdge(cn, sn))
+		g.link(cn, sn, "subscription")
+	}
 
-	if sub := g.getOrCreateSubscriber(subscription.Spec.Subscriber); sub != nil {
+	if sub := g.getOrCreateSubscriber(subscription.Spec.Subscriber, &subscription.ObjectMeta); sub != nil {
 		e := dot.NewEdge(sn, sub)
 		_ = e.Set("dir", "both")
 		g.AddEdge(e)
+		g.link(sn, sub, "subscriber")
 	}
 
-	if rep := g.getOrCreateReply(subscription.Spec.Reply); rep != nil {
+	if rep := g.getOrCreateReply(subscription.Spec.Reply, &subscription.ObjectMeta); rep != nil {
 		e := g.newEdge(sn, rep)
 		_ = e.Set("dir", "forward")
 		g.AddEdge(e)
+		g.link(sn, rep, "reply")
 	}
 }
 
 func (g *Graph) AddBroker(broker eventingv1alpha1.Broker) {
-	key := brokerKey(broker.Name)
+	g.observe(&broker.ObjectMeta)
+
+	key := g.scope(brokerKey(broker.Name))
 	dns := addressableDNS(broker.Status.Address)
-	bn := dot.NewNode("Broker " + dns)
+	bn := g.newNode("Broker " + dns)
 	_ = bn.Set("shape", "oval")
 	_ = bn.Set("label", "Ingress")
 
-	g.nodes[key] = bn
-	g.dnsToKey[dns] = key
+	g.setNode(key, bn)
+	g.record(key, bn, &broker)
+	g.register(dns, key)
+	if broker.Status.Address.URL != nil {
+		g.register(broker.Status.Address.URL.String(), key)
+	}
+	g.registerAnnotated(&broker, key)
 
-	bg := dot.NewSubgraph(fmt.Sprintf("cluster_%d", len(g.subgraphs)))
-	_ = bg.Set("label", fmt.Sprintf("Broker %s\n%s", broker.Name, dns))
+	bg := g.newCluster(fmt.Sprintf("Broker %s\n%s", broker.Name, dns))
 	g.subgraphs[key] = bg
 	bg.AddNode(bn)
-	g.AddSubgraph(bg)
+	g.addSubgraph(g.groupFor(&broker.ObjectMeta), bg)
+}
+
+// AddEventType adds the event type to the model. Event types are not drawn.
+func (g *Graph) AddEventType(eventType eventingv1alpha1.EventType) {
+	g.observe(&eventType.ObjectMeta)
+
+	g.record(g.scope(eventingKey("eventtype", eventType.Name)), nil, &eventType)
 }
 
 func (g *Graph) AddSource(source duckv1alpha1.SourceType) {
-	key := gvkKey(source.GroupVersionKind(), source.Name)
-	sn := dot.NewNode(fmt.Sprintf("Source %s\nKind: %s\n%s", source.Name, source.Kind, source.APIVersion))
+	g.observe(&source.ObjectMeta)
+
+	key := g.scope(gvkKey(source.GroupVersionKind(), source.Name))
+	sn := g.newNode(fmt.Sprintf("Source %s\nKind: %s\n%s", source.Name, source.Kind, source.APIVersion))
 	_ = sn.Set("shape", "box")
-	g.AddNode(sn)
-	g.nodes[key] = sn
+	g.addNode(g.groupFor(&source.ObjectMeta), sn)
+	g.setNode(key, sn)
+	g.record(key, sn, &source)
 
 	sink := sinkDNS(source)
 
 	if sink != "" {
 		var bn *dot.Node
-		var bk string
-		var ok bool
-		if bk, ok = g.dnsToKey[sink]; !ok {
-			// TODO: unknown sink.
-			bn = dot.NewNode("UnknownSink " + sink)
-			g.AddNode(bn)
-		} else {
-			if bn, ok = g.nodes[bk]; !ok {
-				// TODO: unknown broker.
-				bn = dot.NewNode("UnknownSink " + sink)
-				g.AddNode(bn)
+		bk, ok := g.resolve(sink)
+		if ok {
+			bn, ok = g.nodes[bk]
+		}
+		if !ok {
+			if name, hidden := g.hiddenBroker(sink); hidden {
+				// Hidden brokers are not loaded, and their address
+				// would reveal the name.
+				bn = g.placeholder(g.scope(brokerKey(name)), "Broker", &source.ObjectMeta)
+			} else {
+				bn = g.unknownSink(sink, &source.ObjectMeta)
 			}
 		}
 
@@ -142,45 +246,65 @@ func (g *Graph) AddSource(source duckv1alpha1.SourceType) {
 			_ = e.Set("lhead", sg.Name())
 		}
 		g.AddEdge(e)
+		g.link(sn, bn, "sink")
+	} else if source.Spec.Sink != nil {
+		// The sink is not resolved yet, likely the source is not ready.
+		// Draw the edge to the object the spec refers to, dashed.
+		bn := g.getOrCreateSubscriber(&eventingv1alpha1.SubscriberSpec{Ref: source.Spec.Sink}, &source.ObjectMeta)
+		e := dot.NewEdge(sn, bn)
+		_ = e.Set("style", "dashed")
+		g.AddEdge(e)
+		g.link(sn, bn, "sink")
 	}
 }
 
 func (g *Graph) AddTrigger(trigger eventingv1alpha1.Trigger) {
+	g.observe(&trigger.ObjectMeta)
+
 	broker := trigger.Spec.Broker
-	bk := brokerKey(broker)
+	bk := g.scope(brokerKey(broker))
 	bn, ok := g.nodes[bk]
-	if !ok {
-		bn = dot.NewNode("UnknownBroker " + broker)
-		g.AddNode(bn)
-		g.nodes[bk] = bn
+	if !ok && g.isHidden("eventing.knative.dev/v1alpha1", "Broker") {
+		bn = g.placeholder(bk, "Broker", &trigger.ObjectMeta)
+	} else if !ok {
+		bn = g.newNode("UnknownBroker " + broker)
+		g.addNode(g.groupFor(&trigger.ObjectMeta), bn)
+		g.setNode(bk, bn)
+		g.recordRef(bk, bn, "eventing.knative.dev/v1alpha1", "Broker", broker)
 	}
 
-	tn := dot.NewNode("Trigger " + trigger.Name)
+	tk := g.scope(triggerKey(trigger.Name))
+	tn := g.newNode("Trigger " + trigger.Name)
 	_ = tn.Set("shape", "box")
 
 	if sg, ok := g.subgraphs[bk]; ok {
 		sg.AddNode(tn)
 	} else {
-		g.AddNode(tn)
+		g.addNode(g.groupFor(&trigger.ObjectMeta), tn)
 	}
-	g.nodes[triggerKey(trigger.Name)] = tn
+	g.setNode(tk, tn)
+	g.record(tk, tn, &trigger)
+	g.link(bn, tn, "trigger")
 
 	if trigger.Spec.Filter != nil && trigger.Spec.Filter.SourceAndType != nil {
 		label := fmt.Sprintf("Source:%s\nType:%s",
 			trigger.Spec.Filter.SourceAndType.Source,
 			trigger.Spec.Filter.SourceAndType.Type,
 		)
-		_ = tn.Set("label", fmt.Sprintf("%s\n%s", tn.Name(), label))
+		_ = tn.Set("label", fmt.Sprintf("Trigger %s\n%s", trigger.Name, label))
 	}
 
-	if sub := g.getOrCreateSubscriber(trigger.Spec.Subscriber); sub != nil {
+	if sub := g.getOrCreateSubscriber(trigger.Spec.Subscriber, &trigger.ObjectMeta); sub != nil {
 		e := dot.NewEdge(tn, sub)
 		_ = e.Set("dir", "both")
 		g.AddEdge(e)
+		g.link(tn, sub, "subscriber")
 	}
 }
 
 func (g *Graph) AddKnService(service servingv1alpha1.Service) {
+	g.observe(&service.ObjectMeta)
+
 	/*
 	   spec:
 	     runLatest:
@@ -206,7 +330,7 @@ func (g *Graph) AddKnService(service servingv1alpha1.Service) {
 	}
 	_ = config
 
-	key := servingKey(service.Kind, service.Name)
+	key := g.scope(servingKey(service.Kind, service.Name))
 
 	var svc *dot.Node
 	var ok bool
@@ -217,14 +341,28 @@ func (g *Graph) AddKnService(service servingv1alpha1.Service) {
 			service.Kind,
 			service.APIVersion,
 		)
-		svc = dot.NewNode(label)
+		svc = g.newNode(label)
 		setNodeShapeForKind(svc, service.Kind, service.APIVersion)
 
 		_ = svc.Set("shape", "septagon")
 
-		g.nodes[key] = svc
-		g.AddNode(svc)
+		g.setNode(key, svc)
+		g.addNode(g.groupFor(&service.ObjectMeta), svc)
+	}
+	g.record(key, svc, &service)
+	if address := service.Status.Address; address != nil {
+		if address.Hostname != "" {
+			g.register(addressableDNS(*address), key)
+		}
+		if address.URL != nil {
+			g.register(address.URL.String(), key)
+		}
 	}
+	if domain := service.Status.Domain; domain != "" {
+		g.register("http://"+domain, key)
+		g.register("https://"+domain, key)
+	}
+	g.registerAnnotated(&service, key)
 
 	for _, env := range config.RevisionTemplate.Spec.Container.Env {
 		switch env.Name {
@@ -232,9 +370,10 @@ func (g *Graph) AddKnService(service servingv1alpha1.Service) {
 			fallthrough
 		case "TARGET":
 			// Assume full dns name.
-			target := g.getOrCreateSink(env.Value)
+			target := g.getOrCreateSink(env.Value, &service.ObjectMeta)
 			e := dot.NewEdge(svc, target)
 			g.AddEdge(e)
+			g.link(svc, target, "sink")
 		}
 	}
 }
@@ -248,64 +387,90 @@ func setNodeShapeForKind(node *dot.Node, kind, apiVersion string) {
 	}
 }
 
-func (g *Graph) getOrCreateSink(uri string) *dot.Node {
-	if !strings.HasSuffix(uri, "/") {
-		uri += "/"
-	}
-
-	var node *dot.Node
-	var key string
-	var ok bool
-	if key, ok = g.dnsToKey[uri]; !ok {
-		node = dot.NewNode("UnknownSink " + uri)
-		g.AddNode(node)
+// getOrCreateSink returns the node serving uri, or a node for the address
+// drawn next to from, the object sending to it.
+func (g *Graph) getOrCreateSink(uri string, from *metav1.ObjectMeta) *dot.Node {
+	uri = normalizeURI(uri)
+	if key, ok := g.resolve(uri); ok {
+		if n, ok := g.nodes[key]; ok {
+			return n
+		}
 	}
-	return g.nodes[key]
+	return g.unknownSink(uri, from)
 }
 
-func (g *Graph) getOrCreateSubscriber(subscriber *eventingv1alpha1.SubscriberSpec) *dot.Node {
+// getOrCreateSubscriber returns the node of the subscriber. One that was not
+// loaded is drawn in the groups of from, the object referring to it.
+func (g *Graph) getOrCreateSubscriber(subscriber *eventingv1alpha1.SubscriberSpec, from *metav1.ObjectMeta) *dot.Node {
 	key := "?"
 	label := "?"
 
 	if subscriber != nil {
 		if subscriber.URI != nil {
+			// An address served by a loaded object, perhaps in another
+			// cluster, is that object.
+			if k, ok := g.resolve(*subscriber.URI); ok {
+				if n, ok := g.nodes[k]; ok {
+					return n
+				}
+			}
 			label = *subscriber.URI
-			key = uriKey(*subscriber.URI)
+			key = g.uriKey(*subscriber.URI)
 		} else if subscriber.Ref != nil {
+			if g.isHidden(subscriber.Ref.APIVersion, subscriber.Ref.Kind) {
+				return g.placeholder(g.scope(refKey(
+					subscriber.Ref.APIVersion,
+					subscriber.Ref.Kind,
+					subscriber.Ref.Name,
+				)), subscriber.Ref.Kind, from)
+			}
 			label = fmt.Sprintf("%s\nKind: %s\n%s",
 				subscriber.Ref.Name,
 				subscriber.Ref.Kind,
 				subscriber.Ref.APIVersion,
 			)
-			key = refKey(
+			key = g.scope(refKey(
 				subscriber.Ref.APIVersion,
 				subscriber.Ref.Kind,
 				subscriber.Ref.Name,
-			)
+			))
 		}
 	}
 	var sub *dot.Node
 	var ok bool
 	if sub, ok = g.nodes[key]; !ok {
-		sub = dot.NewNode(label)
+		if subscriber != nil && subscriber.URI != nil && !clusterLocal(*subscriber.URI) {
+			sub = dot.NewNode(label)
+		} else {
+			sub = g.newNode(label)
+		}
 		if subscriber != nil && subscriber.Ref != nil {
 			setNodeShapeForKind(sub, subscriber.Ref.Kind, subscriber.Ref.APIVersion)
 		}
 
-		g.nodes[key] = sub
-		g.AddNode(sub)
+		g.setNode(key, sub)
+		if subscriber != nil && subscriber.URI != nil && !clusterLocal(*subscriber.URI) {
+			from = unclustered(from)
+		}
+		g.addNode(g.groupFor(from), sub)
+		if subscriber != nil && subscriber.URI != nil {
+			g.recordURI(sub, *subscriber.URI)
+		} else if subscriber != nil && subscriber.Ref != nil {
+			g.recordRef(key, sub, subscriber.Ref.APIVersion, subscriber.Ref.Kind, subscriber.Ref.Name)
+		}
 	}
 	return sub
 }
 
-func (g *Graph) getOrCreateReply(rep *eventingv1alpha1.ReplyStrategy) *dot.Node {
+func (g *Graph) getOrCreateReply(rep *eventingv1alpha1.ReplyStrategy, from *metav1.ObjectMeta) *dot.Node {
 	if rep != nil && rep.Channel != nil {
-		ck := channelKey(rep.Channel.Name)
-		if cn, ok := g.nodes[ck]; !ok {
-			cn = dot.NewNode("Unknown Channel " + rep.Channel.Name)
-		} else {
+		ck := g.scope(channelKey(rep.Channel.Name))
+		if cn, ok := g.nodes[ck]; ok {
 			return cn
 		}
+		if g.isHidden(rep.Channel.APIVersion, rep.Channel.Kind) {
+			return g.placeholder(ck, rep.Channel.Kind, from)
+		}
 	}
 	return nil
 }
@@ -353,6 +518,11 @@ func uriKey(uri string) string {
 	return strings.ToLower(fmt.Sprintf("uri/%s", uri))
 }
 
+// Key returns the key, and SVG id, of the node drawn for the named resource.
+func Key(apiVersion, kind, name string) string {
+	return refKey(apiVersion, kind, name)
+}
+
 func refKey(apiVersion, kind, name string) string {
 	return strings.ToLower(fmt.Sprintf("%s/%s/%s", apiVersion, kind, name))
 }
@@ -0,0 +1,122 @@
+package graph
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/n3wscott/knap/pkg/knative"
+	"github.com/n3wscott/knap/pkg/layout"
+	"k8s.io/client-go/dynamic"
+)
+
+// snapshot returns a client over the objects of the YAML, a stream of
+// objects separated by "---".
+func snapshot(t *testing.T, objs string) dynamic.Interface {
+	t.Helper()
+	list, err := knative.ReadSnapshot(strings.NewReader(objs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return knative.NewSnapshot(list)
+}
+
+// groupsOf returns the labels of the clusters each drawn node is nested in,
+// outermost first, by node key.
+func groupsOf(t *testing.T, g *Graph) map[string][]string {
+	t.Helper()
+	lg, err := layout.Parse([]byte(g.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups := make(map[string][]string)
+	for _, n := range lg.Nodes {
+		var labels []string
+		for c := n.Cluster; c != nil; c = c.Parent {
+			labels = append([]string{c.Attrs["label"]}, labels...)
+		}
+		groups[n.Attrs["id"]] = labels
+	}
+	return groups
+}
+
+// sinksYAML has sources before and after their sink resolved, one whose
+// status is behind its spec, and one without a sink.
+const sinksYAML = `
+apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+  name: cronjobsources.sources.eventing.knative.dev
+  labels: {eventing.knative.dev/source: "true"}
+spec:
+  group: sources.eventing.knative.dev
+  versions: [{name: v1alpha1, served: true, storage: true}]
+  names: {kind: CronJobSource, plural: cronjobsources}
+---
+apiVersion: eventing.knative.dev/v1alpha1
+kind: Broker
+metadata: {name: default, namespace: demo}
+status:
+  address: {hostname: default-broker.demo.svc.cluster.local}
+---
+apiVersion: sources.eventing.knative.dev/v1alpha1
+kind: CronJobSource
+metadata: {name: pending, namespace: demo}
+spec:
+  sink: {apiVersion: eventing.knative.dev/v1alpha1, kind: Broker, name: default}
+---
+apiVersion: sources.eventing.knative.dev/v1alpha1
+kind: CronJobSource
+metadata: {name: ready, namespace: demo, generation: 3}
+spec:
+  sink: {apiVersion: eventing.knative.dev/v1alpha1, kind: Broker, name: default}
+status:
+  observedGeneration: 2
+  sinkUri: http://default-broker.demo.svc.cluster.local
+---
+apiVersion: sources.eventing.knative.dev/v1alpha1
+kind: CronJobSource
+metadata: {name: lost, namespace: demo}
+`
+
+func TestSourceSinks(t *testing.T) {
+	g := LoadTopology(snapshot(t, sinksYAML), "demo")
+
+	const (
+		broker  = "eventing.knative.dev/v1alpha1/broker/default"
+		source  = "sources.eventing.knative.dev/v1alpha1/cronjobsource/"
+		pending = source + "pending"
+		ready   = source + "ready"
+		lost    = source + "lost"
+	)
+	lg, err := layout.Parse([]byte(g.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	styles := make(map[string]string)
+	for _, e := range lg.Edges {
+		if e.Head.Attrs["id"] == broker {
+			styles[e.Tail.Attrs["id"]] = e.Attrs["style"]
+		}
+	}
+	// An unresolved sink is drawn dashed, to the object the spec names.
+	want := map[string]string{pending: "dashed", ready: ""}
+	if !reflect.DeepEqual(styles, want) {
+		t.Errorf("edges to the broker have styles %q, want %q", styles, want)
+	}
+
+	problems := make(map[string][]string)
+	for _, p := range g.Lint() {
+		if strings.HasPrefix(p.Key, source) {
+			problems[p.Key] = append(problems[p.Key], p.Severity+": "+p.Message)
+		}
+	}
+	wantProblems := map[string][]string{
+		pending: {SeverityWarning + ": sink is not resolved yet, no events are sent"},
+		ready:   {SeverityWarning + ": status is of generation 2, the spec is at 3"},
+		lost:    {SeverityError + ": source has no sink"},
+	}
+	if !reflect.DeepEqual(problems, wantProblems) {
+		t.Errorf("problems = %q, want %q", problems, wantProblems)
+	}
+}
@@ -0,0 +1,168 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tmc/dot"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// PartOfLabel is the recommended label naming the application a resource
+	// belongs to.
+	PartOfLabel = "app.kubernetes.io/part-of"
+
+	// TeamLabel is the label, or annotation, naming the team that owns a
+	// resource.
+	TeamLabel = "team"
+)
+
+// Grouping returns the label of the cluster an object belongs to, or "" to
+// leave the object ungrouped at that level.
+type Grouping func(obj metav1.Object) string
+
+// GroupByNamespace groups resources by namespace.
+func GroupByNamespace() Grouping {
+	return func(obj metav1.Object) string {
+		if ns := obj.GetNamespace(); ns != "" {
+			return "Namespace " + ns
+		}
+		return ""
+	}
+}
+
+// GroupByCluster groups resources by the cluster they were loaded from, when
+// several are.
+func GroupByCluster() Grouping {
+	return func(obj metav1.Object) string {
+		if c := obj.GetClusterName(); c != "" {
+			return "Cluster " + c
+		}
+		return ""
+	}
+}
+
+// GroupByLabel groups resources by the value of the given label.
+func GroupByLabel(key string) Grouping {
+	return func(obj metav1.Object) string {
+		if v, ok := obj.GetLabels()[key]; ok && v != "" {
+			return fmt.Sprintf("%s: %s", key, v)
+		}
+		return ""
+	}
+}
+
+// GroupByOwner groups resources by their controlling owner, or their first
+// owner if none of the owners is a controller.
+func GroupByOwner() Grouping {
+	return func(obj metav1.Object) string {
+		refs := obj.GetOwnerReferences()
+		if len(refs) == 0 {
+			return ""
+		}
+		owner := refs[0]
+		if c := metav1.GetControllerOf(obj); c != nil {
+			owner = *c
+		}
+		return fmt.Sprintf("%s %s", owner.Kind, owner.Name)
+	}
+}
+
+// GroupByTeam groups resources by the TeamLabel label, falling back to an
+// annotation of the same name.
+func GroupByTeam() Grouping {
+	return func(obj metav1.Object) string {
+		team := obj.GetLabels()[TeamLabel]
+		if team == "" {
+			team = obj.GetAnnotations()[TeamLabel]
+		}
+		if team != "" {
+			return "Team " + team
+		}
+		return ""
+	}
+}
+
+// ParseGroupings parses a comma separated list of groupings, outermost first.
+// Valid entries are "cluster", "namespace", "owner", "team", "part-of" and
+// "label=<key>".
+func ParseGroupings(spec string) ([]Grouping, error) {
+	groupings := make([]Grouping, 0)
+	for _, s := range strings.Split(spec, ",") {
+		s = strings.TrimSpace(s)
+		switch {
+		case s == "":
+			continue
+		case s == "cluster":
+			groupings = append(groupings, GroupByCluster())
+		case s == "namespace", s == "ns":
+			groupings = append(groupings, GroupByNamespace())
+		case s == "owner":
+			groupings = append(groupings, GroupByOwner())
+		case s == "team":
+			groupings = append(groupings, GroupByTeam())
+		case s == "part-of":
+			groupings = append(groupings, GroupByLabel(PartOfLabel))
+		case strings.HasPrefix(s, "label="):
+			key := strings.TrimPrefix(s, "label=")
+			if key == "" {
+				return nil, fmt.Errorf("grouping %q is missing a label key", s)
+			}
+			groupings = append(groupings, GroupByLabel(key))
+		default:
+			return nil, fmt.Errorf("unknown grouping %q", s)
+		}
+	}
+	return groupings, nil
+}
+
+// groupFor returns the innermost cluster for obj, creating the nested clusters
+// as needed. It returns nil if obj is not grouped.
+func (g *Graph) groupFor(obj metav1.Object) *dot.SubGraph {
+	var parent *dot.SubGraph
+	path := ""
+	for _, grouping := range g.groupings {
+		label := grouping(obj)
+		if label == "" {
+			continue
+		}
+		path += "/" + label
+
+		sg, ok := g.groups[path]
+		if !ok {
+			sg = g.newCluster(label)
+			g.groups[path] = sg
+			g.addSubgraph(parent, sg)
+		}
+		parent = sg
+	}
+	return parent
+}
+
+func (g *Graph) newCluster(label string) *dot.SubGraph {
+	sg := dot.NewSubgraph(fmt.Sprintf("cluster_%d", g.clusterCount))
+	_ = sg.Set("label", label)
+	g.clusterCount++
+	return sg
+}
+
+// addNode adds the node to the parent cluster, or the root graph if parent is
+// nil.
+func (g *Graph) addNode(parent *dot.SubGraph, node *dot.Node) {
+	if parent != nil {
+		parent.AddNode(node)
+	} else {
+		g.AddNode(node)
+	}
+}
+
+// addSubgraph adds the cluster to the parent cluster, or the root graph if
+// parent is nil.
+func (g *Graph) addSubgraph(parent *dot.SubGraph, sg *dot.SubGraph) {
+	if parent != nil {
+		parent.AddSubgraph(sg)
+	} else {
+		g.AddSubgraph(sg)
+	}
+}
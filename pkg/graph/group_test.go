@@ -0,0 +1,118 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+const groupedYAML = `
+apiVersion: serving.knative.dev/v1alpha1
+kind: Service
+metadata:
+  name: checkout
+  namespace: demo
+  labels: {team: payments, app.kubernetes.io/part-of: shop}
+spec: {runLatest: {configuration: {}}}
+---
+apiVersion: serving.knative.dev/v1alpha1
+kind: Service
+metadata:
+  name: refunds
+  namespace: demo
+  annotations: {team: payments}
+spec: {runLatest: {configuration: {}}}
+---
+apiVersion: serving.knative.dev/v1alpha1
+kind: Service
+metadata:
+  name: catalog
+  namespace: demo
+  labels: {app.kubernetes.io/part-of: shop}
+spec: {runLatest: {configuration: {}}}
+---
+apiVersion: serving.knative.dev/v1alpha1
+kind: Service
+metadata:
+  name: display
+  namespace: demo
+  ownerReferences:
+  - {apiVersion: example.dev/v1, kind: Widget, name: first, uid: "1"}
+  - {apiVersion: example.dev/v1, kind: Widget, name: second, uid: "2", controller: true}
+spec: {runLatest: {configuration: {}}}
+`
+
+func TestGroupings(t *testing.T) {
+	const (
+		checkout = "serving.knative.dev/v1alpha1/service/checkout"
+		refunds  = "serving.knative.dev/v1alpha1/service/refunds"
+		catalog  = "serving.knative.dev/v1alpha1/service/catalog"
+		display  = "serving.knative.dev/v1alpha1/service/display"
+	)
+	tests := []struct {
+		spec string
+		want map[string][]string
+	}{{
+		spec: "",
+		want: map[string][]string{checkout: nil, refunds: nil, catalog: nil, display: nil},
+	}, {
+		spec: "ns",
+		want: map[string][]string{
+			checkout: {"Namespace demo"},
+			refunds:  {"Namespace demo"},
+			catalog:  {"Namespace demo"},
+			display:  {"Namespace demo"},
+		},
+	}, {
+		spec: "team,part-of",
+		want: map[string][]string{
+			checkout: {"Team payments", "app.kubernetes.io/part-of: shop"},
+			refunds:  {"Team payments"},
+			catalog:  {"app.kubernetes.io/part-of: shop"},
+			display:  nil,
+		},
+	}, {
+		spec: "label=team",
+		want: map[string][]string{checkout: {"team: payments"}, refunds: nil, catalog: nil, display: nil},
+	}, {
+		spec: "owner",
+		want: map[string][]string{checkout: nil, refunds: nil, catalog: nil, display: {"Widget second"}},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			groupings, err := ParseGroupings(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			g := LoadTopology(snapshot(t, groupedYAML), "demo", WithGrouping(groupings...))
+
+			got := groupsOf(t, g)
+			for key, want := range tt.want {
+				if !reflect.DeepEqual(got[key], want) {
+					t.Errorf("%s is grouped in %q, want %q", key, got[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestGroupsAreShared(t *testing.T) {
+	groupings, err := ParseGroupings("namespace, team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := LoadTopology(snapshot(t, groupedYAML), "demo", WithGrouping(groupings...))
+
+	// Both payments services are drawn in one team cluster, in one
+	// namespace cluster.
+	if n := len(g.groups); n != 2 {
+		t.Errorf("%d clusters, want 2: %v", n, g.groups)
+	}
+}
+
+func TestParseGroupingsErrors(t *testing.T) {
+	for _, spec := range []string{"label=", "namespace,colour"} {
+		if _, err := ParseGroupings(spec); err == nil {
+			t.Errorf("ParseGroupings(%q) did not fail", spec)
+		}
+	}
+}
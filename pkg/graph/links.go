@@ -0,0 +1,120 @@
+package graph
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/tmc/dot"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Links maps lowercase kinds, or "*" for any other kind, to templates of the
+// URL a node of that kind links to.
+type Links map[string]*template.Template
+
+// LinkData is what link templates are executed with.
+type LinkData struct {
+	Key        string
+	Cluster    string
+	APIVersion string
+	Group      string
+	Version    string
+	Kind       string
+	Namespace  string
+	Name       string
+}
+
+// ParseLinks parses "Kind=template" entries, for example
+// "Service=https://dashboard/#/service/{{.Namespace}}/{{.Name}}". The
+// templates are text/template executed with LinkData.
+func ParseLinks(specs ...string) (Links, error) {
+	links := make(Links)
+	for _, s := range specs {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		parts := strings.SplitN(s, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("link %q is not of the form Kind=template", s)
+		}
+		kind := strings.ToLower(parts[0])
+		t, err := template.New(kind).Parse(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("link for %s: %v", parts[0], err)
+		}
+		links[kind] = t
+	}
+	return links, nil
+}
+
+// WithLinks links each node to the URL made from the template for its kind.
+func WithLinks(links Links) Option {
+	return func(g *Graph) {
+		g.links = links
+	}
+}
+
+// decorate sets the tooltip of a drawn node and, if there is a template for
+// its kind, the URL it links to.
+func (g *Graph) decorate(n *dot.Node, node *Node, created time.Time) {
+	if n == nil {
+		return
+	}
+
+	tooltip := []string{node.Kind + " " + node.Name}
+	if node.Cluster != "" {
+		tooltip = append(tooltip, "Cluster: "+node.Cluster)
+	}
+	if node.Namespace != "" {
+		tooltip = append(tooltip, "Namespace: "+node.Namespace)
+	}
+	if !created.IsZero() {
+		tooltip = append(tooltip, "Age: "+ShortDuration(time.Since(created)))
+	}
+	if node.Ready != "" {
+		tooltip = append(tooltip, "Ready: "+node.Ready)
+	}
+	_ = n.Set("tooltip", strings.Join(tooltip, "\n"))
+
+	t, ok := g.links[strings.ToLower(node.Kind)]
+	if !ok {
+		if t, ok = g.links["*"]; !ok {
+			return
+		}
+	}
+	gv, _ := schema.ParseGroupVersion(node.APIVersion)
+	var url bytes.Buffer
+	if err := t.Execute(&url, LinkData{
+		Key:        node.Key,
+		Cluster:    node.Cluster,
+		APIVersion: node.APIVersion,
+		Group:      gv.Group,
+		Version:    gv.Version,
+		Kind:       node.Kind,
+		Namespace:  node.Namespace,
+		Name:       node.Name,
+	}); err != nil {
+		return
+	}
+	_ = n.Set("URL", url.String())
+	_ = n.Set("target", "_top")
+}
+
+// ShortDuration formats d the way kubectl shows ages.
+func ShortDuration(d time.Duration) string {
+	switch {
+	case d < 0:
+		return "0s"
+	case d < 2*time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < 2*time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
@@ -0,0 +1,155 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Severities of problems.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Problem is something wrong with a node of the topology.
+type Problem struct {
+	Key      string `json:"key"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// Lint checks the topology for resources that are not ready, references to
+// resources that do not exist, addresses nothing serves, events that go
+// nowhere and sources whose status lags their spec.
+func (g *Graph) Lint() []Problem {
+	var problems []Problem
+	add := func(n *Node, severity, format string, args ...interface{}) {
+		problems = append(problems, Problem{Key: n.Key, Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, n := range g.Nodes() {
+		if strings.HasPrefix(n.Key, hiddenPrefix) {
+			continue
+		}
+		in, out := g.Edges(n.Key)
+
+		if n.URI != "" {
+			add(n, SeverityWarning, "nothing in namespace %s serves %s", g.ns, n.URI)
+			continue
+		}
+		if n.Object == nil {
+			if loadedKind(n) {
+				add(n, SeverityError, "%s %s does not exist, it is referenced by %s", n.Kind, n.Name, strings.Join(referrers(n.Key, in, out), ", "))
+			}
+			continue
+		}
+
+		switch cond := readyCondition(n.Status); {
+		case cond == nil:
+		case cond["status"] == "False":
+			add(n, SeverityError, "not ready: %s", conditionReason(cond))
+		case cond["status"] == "Unknown":
+			add(n, SeverityWarning, "readiness unknown: %s", conditionReason(cond))
+		}
+
+		switch {
+		case n.Kind == "Broker" && !has(out, "trigger"):
+			add(n, SeverityWarning, "has no triggers, its events are dropped")
+		case n.Kind == "Trigger" && !has(out, "subscriber"):
+			add(n, SeverityError, "has no subscriber")
+		case n.Kind == "Channel" && !has(out, "subscription"):
+			add(n, SeverityWarning, "has no subscriptions, its events are dropped")
+		case n.Kind == "Subscription" && !has(out, "subscriber") && !has(out, "reply"):
+			add(n, SeverityWarning, "has neither a subscriber nor a reply")
+		case n.IsSource() && !has(out, "sink"):
+			add(n, SeverityError, "source has no sink")
+		case n.IsSource() && n.Status["sinkUri"] == nil:
+			add(n, SeverityWarning, "sink is not resolved yet, no events are sent")
+		}
+
+		observed, _, _ := unstructured.NestedInt64(n.Object, "status", "observedGeneration")
+		generation, _, _ := unstructured.NestedInt64(n.Object, "metadata", "generation")
+		if n.IsSource() && observed != 0 && observed < generation {
+			add(n, SeverityWarning, "status is of generation %d, the spec is at %d", observed, generation)
+		}
+	}
+	return problems
+}
+
+// IsSource reports whether the node is a loaded source: everything loaded
+// that is not an eventing or serving kind is.
+func (n *Node) IsSource() bool {
+	gv, _ := schema.ParseGroupVersion(n.APIVersion)
+	return n.Object != nil && gv.Group != "eventing.knative.dev" && gv.Group != "serving.knative.dev"
+}
+
+// loadedKind reports whether every object of the node's kind is loaded, so
+// a reference to one that is not in the model does not exist.
+func loadedKind(n *Node) bool {
+	gv, _ := schema.ParseGroupVersion(n.APIVersion)
+	switch gv.Group {
+	case "eventing.knative.dev":
+		return n.Kind == "Broker" || n.Kind == "Trigger" || n.Kind == "Channel" || n.Kind == "Subscription" || n.Kind == "EventType"
+	case "serving.knative.dev":
+		return n.Kind == "Service"
+	}
+	return false
+}
+
+// referrers returns the other ends of the node's edges. Events flow both
+// ways through references: a trigger refers to the broker it receives from,
+// and to the subscriber it sends to.
+func referrers(key string, in, out []Edge) []string {
+	var keys []string
+	seen := map[string]bool{key: true}
+	for _, e := range in {
+		if !seen[e.From] {
+			seen[e.From] = true
+			keys = append(keys, e.From)
+		}
+	}
+	for _, e := range out {
+		if !seen[e.To] {
+			seen[e.To] = true
+			keys = append(keys, e.To)
+		}
+	}
+	return keys
+}
+
+func has(edges []Edge, relation string) bool {
+	for _, e := range edges {
+		if e.Relation == relation {
+			return true
+		}
+	}
+	return false
+}
+
+// readyCondition returns the Ready condition of the status, or nil.
+func readyCondition(status map[string]interface{}) map[string]interface{} {
+	conditions, _ := status["conditions"].([]interface{})
+	for _, c := range conditions {
+		if cond, ok := c.(map[string]interface{}); ok && cond["type"] == "Ready" {
+			return cond
+		}
+	}
+	return nil
+}
+
+func conditionReason(cond map[string]interface{}) string {
+	reason, _ := cond["reason"].(string)
+	message, _ := cond["message"].(string)
+	switch {
+	case reason != "" && message != "":
+		return reason + ": " + message
+	case reason != "":
+		return reason
+	case message != "":
+		return message
+	}
+	return "no reason given"
+}
@@ -0,0 +1,32 @@
+package graph
+
+import (
+	"testing"
+)
+
+func TestLintMissingReferences(t *testing.T) {
+	dc := snapshot(t, `
+apiVersion: eventing.knative.dev/v1alpha1
+kind: Trigger
+metadata: {name: display, namespace: demo}
+spec:
+  broker: default
+  subscriber: {ref: {apiVersion: serving.knative.dev/v1alpha1, kind: Service, name: display}}
+`)
+	problems := make(map[string]string)
+	for _, p := range LoadTopology(dc, "demo").Lint() {
+		if p.Severity == SeverityError {
+			problems[p.Key] = p.Message
+		}
+	}
+
+	want := map[string]string{
+		"eventing.knative.dev/v1alpha1/broker/default": "Broker default does not exist, it is referenced by eventing.knative.dev/v1alpha1/trigger/display",
+		"serving.knative.dev/v1alpha1/service/display": "Service display does not exist, it is referenced by eventing.knative.dev/v1alpha1/trigger/display",
+	}
+	for key, msg := range want {
+		if problems[key] != msg {
+			t.Errorf("problem of %s = %q, want %q", key, problems[key], msg)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Mermaid returns the topology as a Mermaid flowchart.
+func (g *Graph) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+
+	ids := make(map[string]string, len(g.model))
+	for i, n := range g.Nodes() {
+		id := fmt.Sprintf("n%d", i)
+		ids[n.Key] = id
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", id, mermaidLabel(n))
+	}
+	for _, e := range g.edges {
+		fmt.Fprintf(&b, "  %s -->|%s| %s\n", ids[e.From], e.Relation, ids[e.To])
+	}
+	return b.String()
+}
+
+func mermaidLabel(n *Node) string {
+	var label string
+	switch {
+	case strings.HasPrefix(n.Key, hiddenPrefix):
+		label = "(hidden)<br/>Kind: " + n.Kind
+	case n.URI != "":
+		label = n.URI
+	default:
+		label = n.Kind + " " + n.Name
+	}
+	if n.Cluster != "" {
+		label = n.Cluster + "<br/>" + label
+	}
+	return strings.Replace(label, `"`, "#quot;", -1)
+}
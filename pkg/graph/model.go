@@ -0,0 +1,213 @@
+package graph
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tmc/dot"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Node is a resource in the topology, or a reference to one that was not
+// loaded.
+type Node struct {
+	Key        string `json:"key"`
+	Cluster    string `json:"cluster,omitempty"`
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name,omitempty"`
+	URI        string `json:"uri,omitempty"`
+
+	// Ready is the status of the Ready condition: True, False or Unknown.
+	Ready string `json:"ready,omitempty"`
+
+	Status map[string]interface{} `json:"status,omitempty"`
+	Object map[string]interface{} `json:"object,omitempty"`
+}
+
+// Summary returns the node without its status and raw object.
+func (n *Node) Summary() *Node {
+	s := *n
+	s.Status, s.Object = nil, nil
+	return &s
+}
+
+// Edge is a hop events take from one node to another.
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+
+	// Relation says why events flow: sink, trigger, subscriber,
+	// subscription or reply.
+	Relation string `json:"relation"`
+}
+
+// record adds a loaded object to the model under key.
+func (g *Graph) record(key string, n *dot.Node, obj interface{}) {
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return
+	}
+	o := &unstructured.Unstructured{Object: u}
+	node := &Node{
+		Key:        key,
+		Cluster:    g.cluster,
+		APIVersion: o.GetAPIVersion(),
+		Kind:       o.GetKind(),
+		Namespace:  o.GetNamespace(),
+		Name:       o.GetName(),
+		Object:     u,
+	}
+	if status, ok := u["status"].(map[string]interface{}); ok {
+		node.Status = status
+		node.Ready = readyStatus(status)
+	}
+	g.model[key] = node
+	if n != nil {
+		g.modelKeys[n] = key
+	}
+	g.decorate(n, node, o.GetCreationTimestamp().Time)
+}
+
+// recordRef adds a referenced object that was not loaded, unless it already
+// is in the model.
+func (g *Graph) recordRef(key string, n *dot.Node, apiVersion, kind, name string) {
+	if _, ok := g.model[key]; ok {
+		return
+	}
+	node := &Node{Key: key, Cluster: g.cluster, APIVersion: apiVersion, Kind: kind, Namespace: g.ns, Name: name}
+	g.model[key] = node
+	g.modelKeys[n] = key
+	g.decorate(n, node, time.Time{})
+}
+
+// recordURI adds an address that no loaded object serves.
+func (g *Graph) recordURI(n *dot.Node, uri string) {
+	key := g.uriKey(uri)
+	if _, ok := g.model[key]; ok {
+		return
+	}
+	node := &Node{Key: key, Kind: "URI", URI: uri}
+	if clusterLocal(uri) {
+		node.Cluster = g.cluster
+	}
+	g.model[key] = node
+	g.modelKeys[n] = key
+}
+
+// link records that events flow from one node to another.
+func (g *Graph) link(from, to *dot.Node, relation string) {
+	f, ok := g.modelKeys[from]
+	if !ok {
+		return
+	}
+	t, ok := g.modelKeys[to]
+	if !ok {
+		return
+	}
+	e := Edge{From: f, To: t, Relation: relation}
+	for _, existing := range g.edges {
+		if existing == e {
+			return
+		}
+	}
+	g.edges = append(g.edges, e)
+}
+
+// readyStatus returns the status of the Ready condition.
+func readyStatus(status map[string]interface{}) string {
+	s, _ := readyCondition(status)["status"].(string)
+	return s
+}
+
+// Nodes returns every node, ordered by key.
+func (g *Graph) Nodes() []*Node {
+	nodes := make([]*Node, 0, len(g.model))
+	for _, n := range g.model {
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Key < nodes[j].Key })
+	return nodes
+}
+
+// Node returns the node with the key. The key of a collapsed internal
+// resource returns the node of its owner.
+func (g *Graph) Node(key string) (*Node, bool) {
+	key = strings.ToLower(key)
+	if n, ok := g.model[key]; ok {
+		return n, true
+	}
+	if dn, ok := g.nodes[key]; ok {
+		if k, ok := g.modelKeys[dn]; ok && !strings.HasPrefix(k, hiddenPrefix) {
+			return g.model[k], true
+		}
+	}
+	return nil, false
+}
+
+// Document is the topology as it is written as json.
+type Document struct {
+	Namespace string  `json:"namespace"`
+	Nodes     []*Node `json:"nodes"`
+	Edges     []Edge  `json:"edges"`
+}
+
+// Document returns the summaries of every node and every edge.
+func (g *Graph) Document() *Document {
+	d := &Document{Namespace: g.ns, Nodes: []*Node{}, Edges: g.AllEdges()}
+	for _, n := range g.Nodes() {
+		d.Nodes = append(d.Nodes, n.Summary())
+	}
+	return d
+}
+
+// AllEdges returns every edge, in the order they were added.
+func (g *Graph) AllEdges() []Edge {
+	return append([]Edge{}, g.edges...)
+}
+
+// Edges returns the edges into and out of the node with the key.
+func (g *Graph) Edges(key string) (in, out []Edge) {
+	for _, e := range g.edges {
+		if e.To == key {
+			in = append(in, e)
+		}
+		if e.From == key {
+			out = append(out, e)
+		}
+	}
+	return in, out
+}
+
+// Path returns the shortest chain of edges events can take from one node to
+// another, or nil if there is none.
+func (g *Graph) Path(from, to string) []Edge {
+	if from == to {
+		return []Edge{}
+	}
+	via := map[string]Edge{from: {}}
+	queue := []string{from}
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+		_, out := g.Edges(key)
+		for _, e := range out {
+			if _, seen := via[e.To]; seen {
+				continue
+			}
+			via[e.To] = e
+			if e.To == to {
+				var path []Edge
+				for k := to; k != from; k = via[k].From {
+					path = append([]Edge{via[k]}, path...)
+				}
+				return path
+			}
+			queue = append(queue, e.To)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package graph
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ExpandInternals draws the channels and subscriptions created by brokers,
+// triggers and other controllers instead of collapsing them into their owner.
+func ExpandInternals() Option {
+	return func(g *Graph) {
+		g.expandInternals = true
+	}
+}
+
+// ownerKey returns the key of the node for the controller of obj, if that
+// controller is already in the graph.
+func (g *Graph) ownerKey(obj metav1.Object) (string, bool) {
+	owner := metav1.GetControllerOf(obj)
+	if owner == nil {
+		return "", false
+	}
+	key := g.scope(refKey(owner.APIVersion, owner.Kind, owner.Name))
+	if _, ok := g.nodes[key]; !ok {
+		return "", false
+	}
+	return key, true
+}
+
+// collapse makes key resolve to the owner's node and cluster, so references
+// to an internal resource land on the resource that created it.
+func (g *Graph) collapse(key, owner string) {
+	g.nodes[key] = g.nodes[owner]
+	if sg, ok := g.subgraphs[owner]; ok {
+		g.subgraphs[key] = sg
+	}
+}
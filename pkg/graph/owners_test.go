@@ -0,0 +1,84 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+// ownedYAML has a broker with the channel it created, and a subscription to
+// that channel made by hand.
+const ownedYAML = `
+apiVersion: eventing.knative.dev/v1alpha1
+kind: Broker
+metadata: {name: default, namespace: demo}
+status:
+  address: {hostname: default-broker.demo.svc.cluster.local}
+---
+apiVersion: eventing.knative.dev/v1alpha1
+kind: Channel
+metadata:
+  name: default-kn2
+  namespace: demo
+  ownerReferences:
+  - {apiVersion: eventing.knative.dev/v1alpha1, kind: Broker, name: default, uid: "1", controller: true}
+status:
+  address: {hostname: default-kn2-channel.demo.svc.cluster.local}
+---
+apiVersion: eventing.knative.dev/v1alpha1
+kind: Subscription
+metadata: {name: audit, namespace: demo}
+spec:
+  channel: {apiVersion: eventing.knative.dev/v1alpha1, kind: Channel, name: default-kn2}
+`
+
+func TestCollapseOwned(t *testing.T) {
+	const (
+		broker       = "eventing.knative.dev/v1alpha1/broker/default"
+		channel      = "eventing.knative.dev/v1alpha1/channel/default-kn2"
+		subscription = "eventing.knative.dev/v1alpha1/subscription/audit"
+		// Cluster labels keep the escapes of the DOT source.
+		brokerLabel  = `Broker default\nhttp://default-broker.demo.svc.cluster.local/`
+		channelLabel = `Channel default-kn2\nhttp://default-kn2-channel.demo.svc.cluster.local/`
+	)
+	tests := []struct {
+		name      string
+		opts      []Option
+		want      map[string][]string
+		wantEdges []Edge
+	}{{
+		name: "collapsed",
+		want: map[string][]string{
+			broker:       {brokerLabel},
+			channel:      nil,
+			subscription: {brokerLabel},
+		},
+		wantEdges: []Edge{{From: broker, To: subscription, Relation: "subscription"}},
+	}, {
+		name: "expanded",
+		opts: []Option{ExpandInternals()},
+		want: map[string][]string{
+			broker:       {brokerLabel},
+			channel:      {brokerLabel, channelLabel},
+			subscription: {brokerLabel, channelLabel},
+		},
+		wantEdges: []Edge{{From: channel, To: subscription, Relation: "subscription"}},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := LoadTopology(snapshot(t, ownedYAML), "demo", tt.opts...)
+
+			got := groupsOf(t, g)
+			for key, want := range tt.want {
+				if !reflect.DeepEqual(got[key], want) {
+					t.Errorf("%s is drawn in %q, want %q", key, got[key], want)
+				}
+			}
+			if _, drawn := got[channel]; drawn != (tt.want[channel] != nil) {
+				t.Errorf("channel drawn = %t", drawn)
+			}
+			if !reflect.DeepEqual(g.edges, tt.wantEdges) {
+				t.Errorf("edges = %v, want %v", g.edges, tt.wantEdges)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package graph
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/n3wscott/knap/pkg/knative"
+	"github.com/tmc/dot"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// WithAuthorizer only loads the kinds the authorizer allows. References to
+// the other kinds are drawn as redacted placeholders. Several authorizers
+// must all allow a kind.
+func WithAuthorizer(a knative.Authorizer) Option {
+	return func(g *Graph) {
+		g.authorizers = append(g.authorizers, a)
+	}
+}
+
+// client returns a knative client of the cluster applying the graph's
+// authorizers.
+func (g *Graph) client(cl Cluster) *knative.Client {
+	var opts []knative.ClientOption
+	for _, a := range g.authorizers {
+		opts = append(opts, knative.WithAuthorizer(a))
+	}
+	if cl.Sources != nil {
+		opts = append(opts, knative.WithSourceInformers(cl.Sources))
+	}
+	return knative.New(cl.Client, opts...)
+}
+
+// redact hides the kinds the client was not allowed to list, and lists them
+// on the "not permitted" badge.
+func (g *Graph) redact(denied []knative.Resource) {
+	if len(denied) == 0 {
+		return
+	}
+	for _, d := range denied {
+		g.hidden[strings.ToLower(d.Group+"/"+d.Kind)] = true
+		gr := d.GroupResource()
+		g.notPermitted[gr.String()] = true
+	}
+
+	if g.badge == nil {
+		g.badge = dot.NewNode("Not permitted")
+		_ = g.badge.Set("shape", "note")
+		_ = g.badge.Set("color", "red")
+		_ = g.badge.Set("fontcolor", "red")
+		g.AddNode(g.badge)
+	}
+	var resources []string
+	for gr := range g.notPermitted {
+		resources = append(resources, gr)
+	}
+	sort.Strings(resources)
+	_ = g.badge.Set("label", "Not permitted to list:\n"+strings.Join(resources, "\n"))
+}
+
+// isHidden reports whether objects of the kind may not be shown.
+func (g *Graph) isHidden(apiVersion, kind string) bool {
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return false
+	}
+	return g.hidden[strings.ToLower(gv.Group+"/"+kind)]
+}
+
+// hiddenBroker returns the name of the broker at the cluster local address
+// uri, if brokers are hidden. A broker is served at
+// <name>-broker.<namespace>.svc.
+func (g *Graph) hiddenBroker(uri string) (string, bool) {
+	if !g.isHidden("eventing.knative.dev/v1alpha1", "Broker") || !clusterLocal(uri) {
+		return "", false
+	}
+	u, err := url.Parse(normalizeURI(uri))
+	if err != nil {
+		return "", false
+	}
+	labels := strings.Split(strings.ToLower(u.Hostname()), ".")
+	if !strings.HasSuffix(labels[0], "-broker") || (len(labels) > 1 && labels[1] != g.ns) {
+		return "", false
+	}
+	return strings.TrimSuffix(labels[0], "-broker"), true
+}
+
+// Hidden returns the kinds that were redacted, as group/kind.
+func (g *Graph) Hidden() []string {
+	hidden := make([]string, 0, len(g.hidden))
+	for gk := range g.hidden {
+		hidden = append(hidden, gk)
+	}
+	sort.Strings(hidden)
+	return hidden
+}
+
+// hiddenPrefix starts the model keys of placeholders.
+const hiddenPrefix = "hidden/"
+
+// placeholder returns the node standing in for a hidden object, drawn in the
+// groups of from, the object referring to it. It shows only the kind, and has
+// no id, so the name of the object is not revealed.
+func (g *Graph) placeholder(key, kind string, from *metav1.ObjectMeta) *dot.Node {
+	if n, ok := g.nodes[key]; ok {
+		return n
+	}
+	g.redactedCount++
+	n := dot.NewNode(fmt.Sprintf("Hidden %d", g.redactedCount))
+	_ = n.Set("label", "(hidden)\nKind: "+kind)
+	_ = n.Set("style", "dashed")
+	_ = n.Set("color", "gray")
+	_ = n.Set("fontcolor", "gray")
+	g.nodes[key] = n
+	g.addNode(g.groupFor(from), n)
+	hk := fmt.Sprintf("%s%d", hiddenPrefix, g.redactedCount)
+	g.model[hk] = &Node{Key: hk, Kind: kind}
+	g.modelKeys[n] = hk
+	return n
+}
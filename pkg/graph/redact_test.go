@@ -0,0 +1,81 @@
+package graph
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// redactedYAML has a source and a trigger of the default broker, which the
+// viewer may not list.
+const redactedYAML = `
+apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+  name: cronjobsources.sources.eventing.knative.dev
+  labels: {eventing.knative.dev/source: "true"}
+spec:
+  group: sources.eventing.knative.dev
+  versions: [{name: v1alpha1, served: true, storage: true}]
+  names: {kind: CronJobSource, plural: cronjobsources}
+---
+apiVersion: eventing.knative.dev/v1alpha1
+kind: Broker
+metadata: {name: default, namespace: demo}
+status:
+  address: {hostname: default-broker.demo.svc.cluster.local}
+---
+apiVersion: sources.eventing.knative.dev/v1alpha1
+kind: CronJobSource
+metadata: {name: cron, namespace: demo}
+status:
+  sinkUri: http://default-broker.demo.svc.cluster.local
+---
+apiVersion: eventing.knative.dev/v1alpha1
+kind: Trigger
+metadata: {name: display, namespace: demo}
+spec:
+  broker: default
+`
+
+func TestRedactedPlaceholders(t *testing.T) {
+	noBrokers := func(namespace string, gvr schema.GroupVersionResource) bool {
+		return gvr.Resource != "brokers"
+	}
+	g := LoadTopology(snapshot(t, redactedYAML), "demo", WithAuthorizer(noBrokers))
+
+	// The source and the trigger refer to one placeholder, which is all
+	// the model has of the broker.
+	const (
+		source  = "sources.eventing.knative.dev/v1alpha1/cronjobsource/cron"
+		trigger = "eventing.knative.dev/v1alpha1/trigger/display"
+	)
+	wantEdges := []Edge{
+		{From: source, To: "hidden/1", Relation: "sink"},
+		{From: "hidden/1", To: trigger, Relation: "trigger"},
+	}
+	if !reflect.DeepEqual(g.edges, wantEdges) {
+		t.Errorf("edges = %v, want %v", g.edges, wantEdges)
+	}
+	if n := g.model["hidden/1"]; !reflect.DeepEqual(n, &Node{Key: "hidden/1", Kind: "Broker"}) {
+		t.Errorf("placeholder = %+v, want only its kind", n)
+	}
+	if len(g.model) != 3 {
+		t.Errorf("model has %d nodes, want the source, trigger and placeholder", len(g.model))
+	}
+
+	if got, want := g.Hidden(), []string{"eventing.knative.dev/broker"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Hidden() = %v, want %v", got, want)
+	}
+	out := g.String()
+	if !strings.Contains(out, `Not permitted to list:\nbrokers.eventing.knative.dev`) {
+		t.Errorf("the graph has no badge for brokers:\n%s", out)
+	}
+	for _, leak := range []string{"broker/default", "default-broker"} {
+		if strings.Contains(out, leak) {
+			t.Errorf("the graph reveals %q:\n%s", leak, out)
+		}
+	}
+}
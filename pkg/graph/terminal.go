@@ -0,0 +1,152 @@
+package graph
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ANSI escapes the terminal rendering colors readiness with.
+const (
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiDim    = "\x1b[2m"
+	ansiReset  = "\x1b[0m"
+)
+
+// Terminal draws the topology with box-drawing characters as a tree of the
+// paths events take from each node nothing sends events to, sources first:
+//
+//	PingSource/ping ✔
+//	└─sink─▶ Broker/default ✔
+//	         └─trigger─▶ Trigger/display [type=dev.ping] ✔
+//	                     └─subscriber─▶ Service/display ✔
+//
+// A node reached again is not expanded a second time. With color set nodes
+// are colored by readiness using ANSI escapes.
+func (g *Graph) Terminal(color bool) string {
+	t := &terminal{g: g, color: color, drawn: make(map[string]bool)}
+
+	var roots, rest []*Node
+	for _, n := range g.Nodes() {
+		if n.Kind == "EventType" {
+			continue
+		}
+		if in, _ := g.Edges(n.Key); len(in) == 0 {
+			roots = append(roots, n)
+		} else {
+			rest = append(rest, n)
+		}
+	}
+	sort.SliceStable(roots, func(i, j int) bool { return rootRank(roots[i]) < rootRank(roots[j]) })
+
+	for _, n := range roots {
+		t.root(n)
+	}
+	// What is left only receives events from cycles, such as a broker whose
+	// triggers reply to it.
+	for _, n := range rest {
+		if !t.drawn[n.Key] {
+			t.root(n)
+		}
+	}
+	return t.b.String()
+}
+
+// rootRank orders the trees: sources, then brokers and channels, then
+// everything else.
+func rootRank(n *Node) int {
+	switch {
+	case n.IsSource():
+		return 0
+	case n.Kind == "Broker" || n.Kind == "Channel":
+		return 1
+	}
+	return 2
+}
+
+type terminal struct {
+	g     *Graph
+	b     strings.Builder
+	color bool
+	drawn map[string]bool
+}
+
+func (t *terminal) root(n *Node) {
+	t.b.WriteString(t.label(n) + "\n")
+	t.drawn[n.Key] = true
+	t.children(n.Key, "", map[string]bool{n.Key: true})
+}
+
+// children draws the nodes key sends events to, each line starting with
+// prefix.
+func (t *terminal) children(key, prefix string, path map[string]bool) {
+	_, out := t.g.Edges(key)
+	for i, e := range out {
+		branch, indent := "├─", "│"
+		if i == len(out)-1 {
+			branch, indent = "└─", " "
+		}
+		connector := branch + e.Relation + "─▶ "
+		t.b.WriteString(prefix + connector)
+
+		n, ok := t.g.model[e.To]
+		switch {
+		case !ok:
+			t.b.WriteString(e.To + "\n")
+		case path[e.To]:
+			t.b.WriteString(t.label(n) + t.paint(ansiDim, " ↺ cycle") + "\n")
+		case t.drawn[e.To]:
+			t.b.WriteString(t.label(n) + t.paint(ansiDim, " (see above)") + "\n")
+		default:
+			t.b.WriteString(t.label(n) + "\n")
+			t.drawn[e.To] = true
+			path[e.To] = true
+			next := prefix + indent + strings.Repeat(" ", utf8.RuneCountInString(connector)-1)
+			t.children(e.To, next, path)
+			delete(path, e.To)
+		}
+	}
+}
+
+// label names the node, with the filter of a trigger and a mark for its
+// readiness.
+func (t *terminal) label(n *Node) string {
+	switch {
+	case strings.HasPrefix(n.Key, hiddenPrefix):
+		return t.paint(ansiDim, "(hidden) "+n.Kind)
+	case n.URI != "":
+		return t.paint(ansiYellow, n.URI) + " ?"
+	}
+
+	name := n.Kind + "/" + n.Name
+	if n.Cluster != "" {
+		name = n.Cluster + ": " + name
+	}
+	if f := triggerFilter(n); f != "" {
+		name += " [" + f + "]"
+	}
+	switch {
+	case n.Object == nil && loadedKind(n):
+		return t.paint(ansiRed, name) + " ✘ not found"
+	case n.Object == nil:
+		return name
+	}
+	switch n.Ready {
+	case "True":
+		return t.paint(ansiGreen, name) + " ✔"
+	case "False":
+		return t.paint(ansiRed, name) + " ✘"
+	case "Unknown":
+		return t.paint(ansiYellow, name) + " …"
+	}
+	return name
+}
+
+func (t *terminal) paint(code, s string) string {
+	if !t.color {
+		return s
+	}
+	return code + s + ansiReset
+}
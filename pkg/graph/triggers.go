@@ -1,69 +1,134 @@
 package graph
 
 import (
-	"github.com/n3wscott/knap/pkg/knative"
+	eventingv1alpha1 "github.com/knative/eventing/pkg/apis/eventing/v1alpha1"
+	servingv1alpha1 "github.com/knative/serving/pkg/apis/serving/v1alpha1"
+	duckv1alpha1 "github.com/n3wscott/knap/pkg/apis/duck/v1alpha1"
 	"k8s.io/client-go/dynamic"
 )
 
-func ForTriggers(client dynamic.Interface, ns string) string {
-	g := New(ns)
+func ForTriggers(client dynamic.Interface, ns string, opts ...Option) string {
+	return LoadTriggers(client, ns, opts...).String()
+}
+
+func ForSubscriptions(client dynamic.Interface, ns string, opts ...Option) string {
+	return LoadSubscriptions(client, ns, opts...).String()
+}
+
+// LoadTriggers builds the graph of brokers, sources, services and triggers in
+// the namespace.
+func LoadTriggers(client dynamic.Interface, ns string, opts ...Option) *Graph {
+	g := New(ns, opts...)
+	g.loadTriggers([]Cluster{{Client: client, Namespace: ns, Sources: g.sources}})
+	return g
+}
 
-	c := knative.New(client)
+// loadTriggers adds the brokers, sources, services and triggers of each
+// cluster. Every kind is added for all the clusters before the next, so the
+// addresses of brokers and services are known when sinks are resolved.
+func (g *Graph) loadTriggers(clusters []Cluster) {
+	type objects struct {
+		brokers  []eventingv1alpha1.Broker
+		sources  []duckv1alpha1.SourceType
+		services []servingv1alpha1.Service
+		triggers []eventingv1alpha1.Trigger
+	}
+	all := make([]objects, len(clusters))
+	for i, cl := range clusters {
+		c := g.client(cl)
+		all[i] = objects{
+			brokers:  c.Brokers(cl.Namespace),
+			sources:  c.Sources(cl.Namespace),
+			services: c.KnServices(cl.Namespace),
+			triggers: c.Triggers(cl.Namespace),
+		}
+		g.redact(c.Denied())
+	}
+	defer g.in(clusters[0])
 
 	// load the brokers
-	for _, broker := range c.Brokers(ns) {
-		g.AddBroker(broker)
+	for i, cl := range clusters {
+		g.in(cl)
+		for _, broker := range all[i].brokers {
+			g.AddBroker(broker)
+		}
 	}
 
-	// load the sources
-	for _, source := range c.Sources(ns) {
-		g.AddSource(source)
+	// load the services before the sources and triggers, so sinks resolve to
+	// them and subscribers are grouped with the service's metadata.
+	for i, cl := range clusters {
+		g.in(cl)
+		for _, service := range all[i].services {
+			g.AddKnService(service)
+		}
 	}
 
-	// load the triggers
-	for _, trigger := range c.Triggers(ns) {
-		g.AddTrigger(trigger)
+	// load the sources
+	for i, cl := range clusters {
+		g.in(cl)
+		for _, source := range all[i].sources {
+			g.AddSource(source)
+		}
 	}
 
-	// load the services
-	for _, service := range c.KnServices(ns) {
-		g.AddKnService(service)
+	// load the triggers
+	for i, cl := range clusters {
+		g.in(cl)
+		for _, trigger := range all[i].triggers {
+			g.AddTrigger(trigger)
+		}
 	}
-	return g.String()
 }
 
-func ForSubscriptions(client dynamic.Interface, ns string) string {
-	g := New(ns)
-
-	c := knative.New(client)
+// LoadSubscriptions builds the trigger graph plus the channels and
+// subscriptions in the namespace.
+func LoadSubscriptions(client dynamic.Interface, ns string, opts ...Option) *Graph {
+	g := LoadTriggers(client, ns, opts...)
+	g.loadSubscriptions([]Cluster{{Client: client, Namespace: ns}})
+	return g
+}
 
-	// load the brokers
-	for _, broker := range c.Brokers(ns) {
-		g.AddBroker(broker)
+// loadSubscriptions adds the channels, then the subscriptions, of each
+// cluster.
+func (g *Graph) loadSubscriptions(clusters []Cluster) {
+	channels := make([][]eventingv1alpha1.Channel, len(clusters))
+	subscriptions := make([][]eventingv1alpha1.Subscription, len(clusters))
+	for i, cl := range clusters {
+		c := g.client(cl)
+		channels[i] = c.Channels(cl.Namespace)
+		subscriptions[i] = c.Subscriptions(cl.Namespace)
+		g.redact(c.Denied())
 	}
+	defer g.in(clusters[0])
 
-	// load the sources
-	for _, source := range c.Sources(ns) {
-		g.AddSource(source)
+	for i, cl := range clusters {
+		g.in(cl)
+		for _, channel := range channels[i] {
+			g.AddChannel(channel)
+		}
 	}
 
-	// load the triggers
-	for _, trigger := range c.Triggers(ns) {
-		g.AddTrigger(trigger)
+	for i, cl := range clusters {
+		g.in(cl)
+		for _, subscription := range subscriptions[i] {
+			g.AddSubscription(subscription)
+		}
 	}
+}
 
-	// load the services
-	for _, service := range c.KnServices(ns) {
-		g.AddKnService(service)
-	}
+// LoadTopology builds the subscription graph and adds the event types in the
+// namespace to its model.
+func LoadTopology(client dynamic.Interface, ns string, opts ...Option) *Graph {
+	g := LoadSubscriptions(client, ns, opts...)
 
-	for _, channel := range c.Channels(ns) {
-		g.AddChannel(channel)
-	}
+	c := g.client(Cluster{Client: client, Namespace: ns})
+
+	eventTypes := c.EventTypes(ns)
+	g.redact(c.Denied())
 
-	for _, subscription := range c.Subscriptions(ns) {
-		g.AddSubscription(subscription)
+	for _, eventType := range eventTypes {
+		g.AddEventType(eventType)
 	}
 
-	return g.String()
+	return g
 }
@@ -0,0 +1,33 @@
+package graph
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// observe records the resource version of an object added to the graph, and
+// names the cluster it was loaded from.
+func (g *Graph) observe(obj metav1.Object) {
+	if g.cluster != "" {
+		obj.SetClusterName(g.cluster)
+	}
+	g.versions = append(g.versions, string(obj.GetUID())+"/"+obj.GetName()+"@"+obj.GetResourceVersion())
+}
+
+// ResourceVersion summarizes the resource versions of every object in the
+// graph. It changes whenever an object is created, updated or deleted, so it
+// can be used to key anything derived from the graph.
+func (g *Graph) ResourceVersion() string {
+	versions := append([]string(nil), g.versions...)
+	sort.Strings(versions)
+
+	h := sha256.New()
+	for _, v := range versions {
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
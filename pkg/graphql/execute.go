@@ -0,0 +1,352 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Request is a query as it is posted to a GraphQL endpoint.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Response is the result of a query. Data is nil when the query could not
+// be run at all.
+type Response struct {
+	Data   interface{} `json:"data"`
+	Errors []*Error    `json:"errors,omitempty"`
+}
+
+// Error is an error running a query, with the path of the field it was
+// raised by.
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Execute runs the query against the schema.
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+	if op.kind != "query" {
+		return &Response{Errors: []*Error{{Message: op.kind + " operations are not supported"}}}
+	}
+
+	vars := make(map[string]interface{})
+	for _, v := range op.vars {
+		if value, ok := req.Variables[v.name]; ok {
+			vars[v.name] = value
+		} else if v.has {
+			vars[v.name] = v.def
+		}
+	}
+
+	s.init()
+	if err := s.validate(doc, op, vars); err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+
+	e := &executor{ctx: ctx, doc: doc, vars: vars, budget: maxResults}
+	data := e.selectionSet(s.root, nil, op.sel, nil)
+	return &Response{Data: data, Errors: e.errors}
+}
+
+// maxResults bounds how many fields a query resolves in all, as relationships
+// can be followed back and forth, each time for every item of a list.
+const maxResults = 50000
+
+// operation picks the operation to run.
+func (d *document) operation(name string) (*operation, error) {
+	if name == "" {
+		if len(d.operations) != 1 {
+			return nil, fmt.Errorf("the document has %d operations, an operationName is required", len(d.operations))
+		}
+		return d.operations[0], nil
+	}
+	for _, op := range d.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+type executor struct {
+	ctx    context.Context
+	doc    *document
+	vars   map[string]interface{}
+	errors []*Error
+
+	// budget is how many more fields may be resolved.
+	budget int
+}
+
+func (e *executor) fail(path []interface{}, format string, args ...interface{}) {
+	e.errors = append(e.errors, &Error{
+		Message: fmt.Sprintf(format, args...),
+		Path:    append([]interface{}{}, path...),
+	})
+}
+
+// selectionSet resolves the selections on a value of the object type.
+func (e *executor) selectionSet(obj *Object, source interface{}, sel []selection, path []interface{}) *orderedMap {
+	result := &orderedMap{values: make(map[string]interface{})}
+	keys, groups := e.collect(obj, sel, nil, nil, make(map[string]bool))
+	for _, key := range keys {
+		fields := groups[key]
+		f := fields[0]
+		fieldPath := append(path[:len(path):len(path)], key)
+
+		e.budget--
+		if e.budget == -1 {
+			e.fail(fieldPath, "the query resolves more than %d fields, the result is cut short", maxResults)
+		}
+		if e.budget < 0 {
+			result.set(key, nil)
+			continue
+		}
+
+		if f.name == "__typename" {
+			result.set(key, obj.Name)
+			continue
+		}
+		def, ok := obj.Fields[f.name]
+		if !ok {
+			e.fail(fieldPath, "cannot query field %q on type %q", f.name, obj.Name)
+			result.set(key, nil)
+			continue
+		}
+
+		args, err := e.arguments(def, f)
+		if err != nil {
+			e.fail(fieldPath, "%s", err)
+			result.set(key, nil)
+			continue
+		}
+
+		var value interface{}
+		if def.Resolve != nil {
+			value, err = def.Resolve(Params{Context: e.ctx, Source: source, Args: args})
+		} else if m, ok := source.(map[string]interface{}); ok {
+			value = m[f.name]
+		}
+		if err != nil {
+			e.fail(fieldPath, "%s", err)
+			result.set(key, nil)
+			continue
+		}
+		result.set(key, e.complete(def.Type, fields, value, fieldPath))
+	}
+	return result
+}
+
+// collect groups the fields selected on the object type by response key,
+// expanding fragments and applying directives.
+func (e *executor) collect(obj *Object, sel []selection, keys []string, groups map[string][]*field, visited map[string]bool) ([]string, map[string][]*field) {
+	if groups == nil {
+		groups = make(map[string][]*field)
+	}
+	for _, s := range sel {
+		switch s := s.(type) {
+		case *field:
+			if !e.include(s.dirs) {
+				continue
+			}
+			if _, ok := groups[s.key()]; !ok {
+				keys = append(keys, s.key())
+			}
+			groups[s.key()] = append(groups[s.key()], s)
+
+		case *fragmentSpread:
+			if visited[s.name] || !e.include(s.dirs) {
+				continue
+			}
+			visited[s.name] = true
+			f, ok := e.doc.fragments[s.name]
+			if !ok {
+				e.fail(nil, "unknown fragment %q", s.name)
+				continue
+			}
+			if !e.include(f.dirs) || !obj.implements(f.on) {
+				continue
+			}
+			keys, groups = e.collect(obj, f.sel, keys, groups, visited)
+
+		case *inlineFragment:
+			if !e.include(s.dirs) || !obj.implements(s.on) {
+				continue
+			}
+			keys, groups = e.collect(obj, s.sel, keys, groups, visited)
+		}
+	}
+	return keys, groups
+}
+
+// include applies the @skip and @include directives.
+func (e *executor) include(dirs []*directive) bool {
+	for _, d := range dirs {
+		if d.name != "skip" && d.name != "include" {
+			continue
+		}
+		var cond bool
+		for _, a := range d.args {
+			if a.name == "if" {
+				cond, _ = e.value(a.value).(bool)
+			}
+		}
+		if cond == (d.name == "skip") {
+			return false
+		}
+	}
+	return true
+}
+
+// arguments resolves the arguments of a field.
+func (e *executor) arguments(def *Field, f *field) (map[string]interface{}, error) {
+	args := make(map[string]interface{})
+	for _, a := range f.args {
+		known := false
+		for _, name := range def.Args {
+			if name == a.name {
+				known = true
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown argument %q on field %q", a.name, f.name)
+		}
+		if v := e.value(a.value); v != nil {
+			args[a.name] = v
+		}
+	}
+	return args, nil
+}
+
+// value resolves variables in a literal.
+func (e *executor) value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case variable:
+		return e.vars[string(v)]
+	case enum:
+		return string(v)
+	case []interface{}:
+		list := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			list = append(list, e.value(item))
+		}
+		return list
+	case objectValue:
+		obj := make(map[string]interface{}, len(v))
+		for _, a := range v {
+			obj[a.name] = e.value(a.value)
+		}
+		return obj
+	}
+	return v
+}
+
+// complete shapes a resolved value by its type.
+func (e *executor) complete(t Type, fields []*field, value interface{}, path []interface{}) interface{} {
+	if _, ok := t.(*List); !ok && isNil(value) {
+		return nil
+	}
+	switch t := t.(type) {
+	case *List:
+		if value == nil {
+			return nil
+		}
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			e.fail(path, "expected a list, got %T", value)
+			return nil
+		}
+		list := make([]interface{}, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			list = append(list, e.complete(t.Of, fields, rv.Index(i).Interface(), append(path[:len(path):len(path)], i)))
+		}
+		return list
+
+	case *Object:
+		return e.selectionSet(t, value, subselection(fields), path)
+
+	case *Interface:
+		obj := t.ResolveType(value)
+		if obj == nil {
+			e.fail(path, "unable to resolve the type of %s", t.Name)
+			return nil
+		}
+		return e.selectionSet(obj, value, subselection(fields), path)
+	}
+	return value
+}
+
+// subselection merges the selection sets of fields with the same response
+// key.
+func subselection(fields []*field) []selection {
+	var sel []selection
+	for _, f := range fields {
+		sel = append(sel, f.sel...)
+	}
+	return sel
+}
+
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// orderedMap keeps fields in the order they were selected.
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (m *orderedMap) set(key string, value interface{}) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
@@ -0,0 +1,539 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// testSchema has people owning pets, pets having owners, and
+// robots, which are only reachable through the Named interface.
+func testSchema() *Schema {
+	named := &Interface{Name: "Named"}
+	person := &Object{Name: "Person", Interfaces: []*Interface{named}}
+	pet := &Object{Name: "Pet", Interfaces: []*Interface{named}}
+	robot := &Object{Name: "Robot", Interfaces: []*Interface{named}}
+
+	people := map[string]map[string]interface{}{}
+	pets := map[string]map[string]interface{}{}
+	var allPets []map[string]interface{}
+	for _, owner := range []string{"alice", "bob"} {
+		people[owner] = map[string]interface{}{"name": owner, "type": "Person"}
+	}
+	for i := 0; i < 10; i++ {
+		owner := "alice"
+		kind := "cat"
+		if i%2 == 1 {
+			owner, kind = "bob", "dog"
+		}
+		p := map[string]interface{}{"name": fmt.Sprintf("pet%d", i), "kind": kind, "owner": owner, "type": "Pet"}
+		pets[p["name"].(string)] = p
+		allPets = append(allPets, p)
+	}
+	ofOwner := func(owner string) []map[string]interface{} {
+		var list []map[string]interface{}
+		for _, p := range allPets {
+			if p["owner"] == owner {
+				list = append(list, p)
+			}
+		}
+		return list
+	}
+
+	named.Fields = Fields{"name": {Type: String}}
+	named.ResolveType = func(v interface{}) *Object {
+		switch v.(map[string]interface{})["type"] {
+		case "Person":
+			return person
+		case "Pet":
+			return pet
+		case "Robot":
+			return robot
+		}
+		return nil
+	}
+	person.Fields = Fields{
+		"name": {Type: String},
+		"pets": {Type: ListOf(pet), Resolve: func(p Params) (interface{}, error) {
+			return ofOwner(p.Source.(map[string]interface{})["name"].(string)), nil
+		}},
+	}
+	pet.Fields = Fields{
+		"name": {Type: String},
+		"kind": {Type: String},
+		"owner": {Type: person, Resolve: func(p Params) (interface{}, error) {
+			return people[p.Source.(map[string]interface{})["owner"].(string)], nil
+		}},
+	}
+	robot.Fields = Fields{
+		"name":  {Type: String},
+		"model": {Type: Int},
+	}
+
+	query := &Object{Name: "Query", Fields: Fields{
+		"pets": {Type: ListOf(pet), Args: []string{"kind"}, Resolve: func(p Params) (interface{}, error) {
+			var list []map[string]interface{}
+			for _, pet := range allPets {
+				if kind := p.String("kind"); kind == "" || pet["kind"] == kind {
+					list = append(list, pet)
+				}
+			}
+			return list, nil
+		}},
+		"pet": {Type: pet, Args: []string{"name"}, Resolve: func(p Params) (interface{}, error) {
+			if pet, ok := pets[p.String("name")]; ok {
+				return pet, nil
+			}
+			return nil, nil
+		}},
+		"named": {Type: ListOf(named), Resolve: func(p Params) (interface{}, error) {
+			return []map[string]interface{}{
+				people["alice"],
+				pets["pet1"],
+				{"name": "r2", "model": 2, "type": "Robot"},
+			}, nil
+		}},
+		"broken": {Type: String, Resolve: func(p Params) (interface{}, error) {
+			return nil, fmt.Errorf("broken resolver")
+		}},
+	}}
+	return &Schema{Query: query, Types: []*Object{robot}}
+}
+
+// nested returns a query selecting the owner's pets depth times.
+func nested(depth int) string {
+	return "{ pets { " + strings.Repeat("owner { pets { ", depth) + "name" + strings.Repeat(" } }", depth) + " } }"
+}
+
+// fanOut returns fragments spreading the next one twice, n deep.
+func fanOut(n int) string {
+	var b strings.Builder
+	b.WriteString("{ ...F0 }\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "fragment F%d on Query { ...F%d ...F%d }\n", i, i+1, i+1)
+	}
+	fmt.Fprintf(&b, "fragment F%d on Query { __typename }\n", n)
+	return b.String()
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		op      string
+		vars    map[string]interface{}
+		want    string
+		wantErr string
+	}{{
+		name:  "field",
+		query: `{ pet(name: "pet0") { name kind } }`,
+		want:  `{"pet":{"name":"pet0","kind":"cat"}}`,
+	}, {
+		name:  "alias and argument",
+		query: `{ first: pet(name: "pet0") { name } second: pet(name: "pet1") { n: name } }`,
+		want:  `{"first":{"name":"pet0"},"second":{"n":"pet1"}}`,
+	}, {
+		name:  "list with enum argument",
+		query: `{ pets(kind: dog) { name } }`,
+		want:  `{"pets":[{"name":"pet1"},{"name":"pet3"},{"name":"pet5"},{"name":"pet7"},{"name":"pet9"}]}`,
+	}, {
+		name:  "relationship back and forth",
+		query: `{ pet(name: "pet2") { owner { name pets { name } } } }`,
+		want:  `{"pet":{"owner":{"name":"alice","pets":[{"name":"pet0"},{"name":"pet2"},{"name":"pet4"},{"name":"pet6"},{"name":"pet8"}]}}}`,
+	}, {
+		name:  "null object",
+		query: `{ pet(name: "nope") { name } }`,
+		want:  `{"pet":null}`,
+	}, {
+		name:  "resolver error",
+		query: `{ broken pet(name: "pet0") { name } }`,
+		want:  `{"broken":null,"pet":{"name":"pet0"}}`,
+		// The error is reported next to the data, not instead of it.
+		wantErr: "broken resolver",
+	}, {
+		name:  "typename",
+		query: `{ pet(name: "pet0") { __typename } }`,
+		want:  `{"pet":{"__typename":"Pet"}}`,
+	}, {
+		// Variables.
+		name:  "variable",
+		query: `query Pet($name: String!) { pet(name: $name) { name } }`,
+		vars:  map[string]interface{}{"name": "pet3"},
+		want:  `{"pet":{"name":"pet3"}}`,
+	}, {
+		name:  "variable default",
+		query: `query Pet($name: String = "pet4") { pet(name: $name) { name } }`,
+		want:  `{"pet":{"name":"pet4"}}`,
+	}, {
+		name:  "variable overrides default",
+		query: `query Pet($name: String = "pet4") { pet(name: $name) { name } }`,
+		vars:  map[string]interface{}{"name": "pet5"},
+		want:  `{"pet":{"name":"pet5"}}`,
+	}, {
+		name:    "undefined variable",
+		query:   `{ pet(name: $name) { name } }`,
+		wantErr: "variable $name is not defined",
+	}, {
+		name:    "undefined variable in a fragment",
+		query:   `{ ...F } fragment F on Query { pet(name: $name) { name } }`,
+		wantErr: "variable $name is not defined",
+	}, {
+		name:    "undefined variable in a list",
+		query:   `query Q($a: String) { pets(kind: [$a, $b]) { name } }`,
+		wantErr: "variable $b is not defined",
+	}, {
+		// Operations.
+		name:  "operation by name",
+		query: `query A { pet(name: "pet0") { name } } query B { pet(name: "pet1") { name } }`,
+		op:    "B",
+		want:  `{"pet":{"name":"pet1"}}`,
+	}, {
+		name:    "operation name required",
+		query:   `query A { __typename } query B { __typename }`,
+		wantErr: "an operationName is required",
+	}, {
+		name:    "unknown operation",
+		query:   `query A { __typename }`,
+		op:      "C",
+		wantErr: `unknown operation "C"`,
+	}, {
+		name:    "mutation",
+		query:   `mutation { __typename }`,
+		wantErr: "mutation operations are not supported",
+	}, {
+		// Fragments.
+		name:  "named fragment",
+		query: `{ pet(name: "pet0") { ...P } } fragment P on Pet { name owner { name } }`,
+		want:  `{"pet":{"name":"pet0","owner":{"name":"alice"}}}`,
+	}, {
+		name:  "fragment on interface",
+		query: `{ pet(name: "pet0") { ...N } } fragment N on Named { name }`,
+		want:  `{"pet":{"name":"pet0"}}`,
+	}, {
+		name:  "inline fragments by type",
+		query: `{ named { __typename name ... on Person { pets { name } } ... on Robot { model } } }`,
+		want:  `{"named":[{"__typename":"Person","name":"alice","pets":[{"name":"pet0"},{"name":"pet2"},{"name":"pet4"},{"name":"pet6"},{"name":"pet8"}]},{"__typename":"Pet","name":"pet1"},{"__typename":"Robot","name":"r2","model":2}]}`,
+	}, {
+		name:  "merged selections",
+		query: `{ pet(name: "pet0") { owner { name } ...O } } fragment O on Pet { owner { pets { name } } }`,
+		want:  `{"pet":{"owner":{"name":"alice","pets":[{"name":"pet0"},{"name":"pet2"},{"name":"pet4"},{"name":"pet6"},{"name":"pet8"}]}}}`,
+	}, {
+		name:    "unknown fragment",
+		query:   `{ ...Nope }`,
+		wantErr: `unknown fragment "Nope"`,
+	}, {
+		name:    "fragment on unknown type",
+		query:   `{ ...F } fragment F on Nope { __typename }`,
+		wantErr: `fragment "F" is on unknown type "Nope"`,
+	}, {
+		name:    "fragment cycle",
+		query:   `{ ...A } fragment A on Query { ...B } fragment B on Query { ...A }`,
+		wantErr: `fragment "A" spreads itself`,
+	}, {
+		// Directives.
+		name:  "skip and include literals",
+		query: `{ pet(name: "pet0") { name @skip(if: true) kind @include(if: true) owner @include(if: false) { name } } }`,
+		want:  `{"pet":{"kind":"cat"}}`,
+	}, {
+		name:  "directive variables",
+		query: `query Q($withOwner: Boolean!, $noKind: Boolean = true) { pet(name: "pet0") { name kind @skip(if: $noKind) owner @include(if: $withOwner) { name } } }`,
+		vars:  map[string]interface{}{"withOwner": true},
+		want:  `{"pet":{"name":"pet0","owner":{"name":"alice"}}}`,
+	}, {
+		name:  "directive on fragments",
+		query: `query Q($f: Boolean!) { pet(name: "pet0") { ...P @include(if: $f) ... on Pet @skip(if: $f) { kind } } } fragment P on Pet { name }`,
+		vars:  map[string]interface{}{"f": true},
+		want:  `{"pet":{"name":"pet0"}}`,
+	}, {
+		name:    "undefined directive variable",
+		query:   `{ pet(name: "pet0") { name @include(if: $show) } }`,
+		wantErr: "variable $show is not defined",
+	}, {
+		name:    "directive variable without a value",
+		query:   `query Q($show: Boolean) { pet(name: "pet0") { name @include(if: $show) } }`,
+		wantErr: "argument if of directive @include must be a Boolean",
+	}, {
+		name:    "directive without if",
+		query:   `{ pet(name: "pet0") { name @skip } }`,
+		wantErr: "directive @skip needs an if argument",
+	}, {
+		name:    "directive with a string",
+		query:   `{ pet(name: "pet0") { name @skip(if: "yes") } }`,
+		wantErr: "argument if of directive @skip must be a Boolean",
+	}, {
+		name:    "unknown directive",
+		query:   `{ pet(name: "pet0") { name @deprecated } }`,
+		wantErr: "unknown directive @deprecated",
+	}, {
+		// Validation.
+		name:    "unknown field",
+		query:   `{ pet(name: "pet0") { color } }`,
+		wantErr: `cannot query field "color" on type "Pet"`,
+	}, {
+		name:    "field of the interface only",
+		query:   `{ named { model } }`,
+		wantErr: `cannot query field "model" on type "Named"`,
+	}, {
+		name:    "object without selection",
+		query:   `{ pets }`,
+		wantErr: `field "pets" of type [Pet] must have a selection of subfields`,
+	}, {
+		name:    "nested object without selection",
+		query:   `{ pet(name: "pet0") { owner } }`,
+		wantErr: `field "owner" of type Person must have a selection of subfields`,
+	}, {
+		name:    "leaf with selection",
+		query:   `{ pet(name: "pet0") { name { length } } }`,
+		wantErr: `field "name" of type String must not have a selection`,
+	}, {
+		name:    "unknown argument",
+		query:   `{ pet(id: "pet0") { name } }`,
+		wantErr: `unknown argument "id" on field "pet"`,
+	}, {
+		// Limits.
+		name:    "too deep",
+		query:   nested(maxDepth),
+		wantErr: "levels deep",
+	}, {
+		name:    "deeply nested list",
+		query:   `{ pets(kind: ` + strings.Repeat("[", 10000) + strings.Repeat("]", 10000) + `) { name } }`,
+		wantErr: "levels deep",
+	}, {
+		name:    "fragments fanning out",
+		query:   fanOut(30),
+		wantErr: "more than 1000 fields",
+	}, {
+		name:    "too many fields",
+		query:   "{ " + strings.Repeat("__typename ", maxFields+1) + "}",
+		wantErr: "more than 1000 fields",
+	}, {
+		// Parse errors.
+		name:    "unterminated selection",
+		query:   `{ pets { name }`,
+		wantErr: "syntax error at 1:16: unexpected end of query",
+	}, {
+		name:    "unexpected character",
+		query:   "{\n  pets % }",
+		wantErr: "syntax error at 2:8: unexpected character '%'",
+	}, {
+		name:    "unterminated string",
+		query:   `{ pet(name: "pet0) { name } }`,
+		wantErr: "unterminated string",
+	}, {
+		name:    "missing colon",
+		query:   `query Q($name String) { __typename }`,
+		wantErr: `expected ":", found "String"`,
+	}, {
+		name:    "variable in a default",
+		query:   `query Q($a: String = $b) { __typename }`,
+		wantErr: `unexpected "$"`,
+	}, {
+		name:    "empty",
+		query:   ``,
+		wantErr: "the document has 0 operations",
+	}}
+
+	s := testSchema()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := s.Execute(context.Background(), Request{Query: tt.query, OperationName: tt.op, Variables: tt.vars})
+
+			var errs []string
+			for _, e := range res.Errors {
+				errs = append(errs, e.Message)
+			}
+			if tt.wantErr == "" && len(errs) > 0 {
+				t.Fatalf("errors: %v", errs)
+			}
+			if tt.wantErr != "" && !strings.Contains(strings.Join(errs, "\n"), tt.wantErr) {
+				t.Fatalf("errors = %v, want one containing %q", errs, tt.wantErr)
+			}
+			if tt.want == "" {
+				if res.Data != nil {
+					t.Errorf("data = %v, want none", res.Data)
+				}
+				return
+			}
+
+			got, err := json.Marshal(res.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("data =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteCutsFanOutShort(t *testing.T) {
+	// Following pets and owners back and forth multiplies the result by
+	// five each time.
+	res := testSchema().Execute(context.Background(), Request{Query: nested(7)})
+	if res.Data == nil || len(res.Errors) != 1 || !strings.Contains(res.Errors[0].Message, "the result is cut short") {
+		t.Errorf("errors = %v, want the result cut short", res.Errors)
+	}
+}
+
+func TestIntrospection(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{{
+		name:  "type",
+		query: `{ __type(name: "Pet") { kind name interfaces { name } fields { name type { kind name ofType { name } } } } }`,
+		want:  `{"__type":{"kind":"OBJECT","name":"Pet","interfaces":[{"name":"Named"}],"fields":[{"name":"kind","type":{"kind":"SCALAR","name":"String","ofType":null}},{"name":"name","type":{"kind":"SCALAR","name":"String","ofType":null}},{"name":"owner","type":{"kind":"OBJECT","name":"Person","ofType":null}}]}}`,
+	}, {
+		name:  "unknown type",
+		query: `{ __type(name: "Nope") { name } }`,
+		want:  `{"__type":null}`,
+	}, {
+		name:  "possible types include those only reachable through the interface",
+		query: `{ __type(name: "Named") { kind possibleTypes { name } } }`,
+		want:  `{"__type":{"kind":"INTERFACE","possibleTypes":[{"name":"Person"},{"name":"Pet"},{"name":"Robot"}]}}`,
+	}, {
+		name:  "query type without introspection fields",
+		query: `{ __schema { queryType { name fields { name args { name type { name } } type { kind ofType { name } } } } } }`,
+		want:  `{"__schema":{"queryType":{"name":"Query","fields":[{"name":"broken","args":[],"type":{"kind":"SCALAR","ofType":null}},{"name":"named","args":[],"type":{"kind":"LIST","ofType":{"name":"Named"}}},{"name":"pet","args":[{"name":"name","type":{"name":"String"}}],"type":{"kind":"OBJECT","ofType":null}},{"name":"pets","args":[{"name":"kind","type":{"name":"String"}}],"type":{"kind":"LIST","ofType":{"name":"Pet"}}}]}}}`,
+	}, {
+		name:  "type names",
+		query: `{ __schema { types { name } } }`,
+		want:  `{"__schema":{"types":[{"name":"Boolean"},{"name":"Int"},{"name":"Named"},{"name":"Person"},{"name":"Pet"},{"name":"Query"},{"name":"Robot"},{"name":"String"},{"name":"__Directive"},{"name":"__EnumValue"},{"name":"__Field"},{"name":"__InputValue"},{"name":"__Schema"},{"name":"__Type"}]}}`,
+	}, {
+		name:  "directives",
+		query: `{ __schema { directives { name locations args { name type { kind ofType { name } } } } } }`,
+		want:  `{"__schema":{"directives":[{"name":"include","locations":["FIELD","FRAGMENT_SPREAD","INLINE_FRAGMENT"],"args":[{"name":"if","type":{"kind":"NON_NULL","ofType":{"name":"Boolean"}}}]},{"name":"skip","locations":["FIELD","FRAGMENT_SPREAD","INLINE_FRAGMENT"],"args":[{"name":"if","type":{"kind":"NON_NULL","ofType":{"name":"Boolean"}}}]}]}}`,
+	}}
+
+	s := testSchema()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := s.Execute(context.Background(), Request{Query: tt.query})
+			if len(res.Errors) > 0 {
+				t.Fatalf("errors: %v", res.Errors[0])
+			}
+			got, err := json.Marshal(res.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("data =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// introspectionQuery is the query GraphiQL loads the schema with.
+const introspectionQuery = `
+query IntrospectionQuery {
+  __schema {
+    queryType { name }
+    mutationType { name }
+    subscriptionType { name }
+    types { ...FullType }
+    directives {
+      name
+      description
+      locations
+      args { ...InputValue }
+    }
+  }
+}
+
+fragment FullType on __Type {
+  kind
+  name
+  description
+  fields(includeDeprecated: true) {
+    name
+    description
+    args { ...InputValue }
+    type { ...TypeRef }
+    isDeprecated
+    deprecationReason
+  }
+  inputFields { ...InputValue }
+  interfaces { ...TypeRef }
+  enumValues(includeDeprecated: true) {
+    name
+    description
+    isDeprecated
+    deprecationReason
+  }
+  possibleTypes { ...TypeRef }
+}
+
+fragment InputValue on __InputValue {
+  name
+  description
+  type { ...TypeRef }
+  defaultValue
+}
+
+fragment TypeRef on __Type {
+  kind
+  name
+  ofType {
+    kind
+    name
+    ofType {
+      kind
+      name
+      ofType {
+        kind
+        name
+        ofType {
+          kind
+          name
+          ofType {
+            kind
+            name
+            ofType {
+              kind
+              name
+              ofType {
+                kind
+                name
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+func TestIntrospectionQuery(t *testing.T) {
+	res := testSchema().Execute(context.Background(), Request{Query: introspectionQuery, OperationName: "IntrospectionQuery"})
+	if len(res.Errors) > 0 {
+		t.Fatalf("errors: %v", res.Errors[0])
+	}
+	b, err := json.Marshal(res.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data struct {
+		Schema struct {
+			QueryType struct{ Name string }
+			Types     []struct {
+				Kind, Name string
+				Fields     []struct{ Name string }
+			}
+		} `json:"__schema"`
+	}
+	if err := json.Unmarshal(b, &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.Schema.QueryType.Name != "Query" {
+		t.Errorf("query type = %q, want Query", data.Schema.QueryType.Name)
+	}
+	for _, typ := range data.Schema.Types {
+		if typ.Name == "Robot" && len(typ.Fields) == 2 {
+			return
+		}
+	}
+	t.Errorf("Robot and its two fields are missing from the types: %s", b)
+}
@@ -0,0 +1,250 @@
+package graphql
+
+import "sort"
+
+// The introspection types serve maps built from the schema, so their fields
+// need no resolvers.
+var (
+	schemaType     = &Object{Name: "__Schema"}
+	typeType       = &Object{Name: "__Type"}
+	fieldType      = &Object{Name: "__Field"}
+	inputValueType = &Object{Name: "__InputValue"}
+	enumValueType  = &Object{Name: "__EnumValue"}
+	directiveType  = &Object{Name: "__Directive"}
+
+	introspectionTypes = []*Object{schemaType, typeType, fieldType, inputValueType, enumValueType, directiveType}
+)
+
+func init() {
+	includeDeprecated := func(t Type) *Field {
+		return &Field{Type: t, Args: []string{"includeDeprecated"}, ArgTypes: map[string]Type{"includeDeprecated": Boolean}}
+	}
+	deprecation := func(fields Fields) Fields {
+		fields["isDeprecated"] = &Field{Type: Boolean}
+		fields["deprecationReason"] = &Field{Type: String}
+		return fields
+	}
+
+	schemaType.Fields = Fields{
+		"description":      {Type: String},
+		"types":            {Type: ListOf(typeType)},
+		"queryType":        {Type: typeType},
+		"mutationType":     {Type: typeType},
+		"subscriptionType": {Type: typeType},
+		"directives":       {Type: ListOf(directiveType)},
+	}
+	typeType.Fields = Fields{
+		"kind":           {Type: String},
+		"name":           {Type: String},
+		"description":    {Type: String},
+		"specifiedByURL": {Type: String},
+		"specifiedByUrl": {Type: String},
+		"fields":         includeDeprecated(ListOf(fieldType)),
+		"interfaces":     {Type: ListOf(typeType)},
+		"possibleTypes":  {Type: ListOf(typeType)},
+		"enumValues":     includeDeprecated(ListOf(enumValueType)),
+		"inputFields":    includeDeprecated(ListOf(inputValueType)),
+		"ofType":         {Type: typeType},
+	}
+	fieldType.Fields = deprecation(Fields{
+		"name":        {Type: String},
+		"description": {Type: String},
+		"args":        includeDeprecated(ListOf(inputValueType)),
+		"type":        {Type: typeType},
+	})
+	inputValueType.Fields = deprecation(Fields{
+		"name":         {Type: String},
+		"description":  {Type: String},
+		"type":         {Type: typeType},
+		"defaultValue": {Type: String},
+	})
+	enumValueType.Fields = deprecation(Fields{
+		"name":        {Type: String},
+		"description": {Type: String},
+	})
+	directiveType.Fields = Fields{
+		"name":         {Type: String},
+		"description":  {Type: String},
+		"locations":    {Type: ListOf(String)},
+		"args":         includeDeprecated(ListOf(inputValueType)),
+		"isRepeatable": {Type: Boolean},
+	}
+}
+
+// init collects the named types of the schema, and adds __schema and __type
+// to the query type queries run against.
+func (s *Schema) init() {
+	s.once.Do(func() {
+		s.types = make(map[string]Type)
+		for _, t := range []Type{String, Boolean} {
+			s.collect(t)
+		}
+		s.collect(s.Query)
+		for _, t := range s.Types {
+			s.collect(t)
+		}
+		for _, t := range introspectionTypes {
+			s.collect(t)
+		}
+
+		intro := newIntrospection(s)
+		s.root = &Object{Name: s.Query.Name, Interfaces: s.Query.Interfaces, Fields: Fields{
+			"__schema": {Type: schemaType, Resolve: func(Params) (interface{}, error) {
+				return intro.schema, nil
+			}},
+			"__type": {Type: typeType, Args: []string{"name"}, Resolve: func(p Params) (interface{}, error) {
+				if t, ok := intro.types[p.String("name")]; ok {
+					return t, nil
+				}
+				return nil, nil
+			}},
+		}}
+		for name, f := range s.Query.Fields {
+			s.root.Fields[name] = f
+		}
+	})
+}
+
+// collect adds t and the types it refers to.
+func (s *Schema) collect(t Type) {
+	if l, ok := t.(*List); ok {
+		s.collect(l.Of)
+		return
+	}
+	if _, ok := s.types[t.String()]; ok {
+		return
+	}
+	s.types[t.String()] = t
+	switch t := t.(type) {
+	case *Object:
+		for _, i := range t.Interfaces {
+			s.collect(i)
+		}
+		s.collectFields(t.Fields)
+	case *Interface:
+		s.collectFields(t.Fields)
+	}
+}
+
+func (s *Schema) collectFields(fields Fields) {
+	for _, f := range fields {
+		s.collect(f.Type)
+		for _, t := range f.ArgTypes {
+			s.collect(t)
+		}
+	}
+}
+
+// introspection is what __schema and __type serve.
+type introspection struct {
+	s      *Schema
+	names  []string
+	types  map[string]map[string]interface{}
+	schema map[string]interface{}
+}
+
+func newIntrospection(s *Schema) *introspection {
+	in := &introspection{s: s, types: make(map[string]map[string]interface{})}
+
+	for name := range s.types {
+		in.names = append(in.names, name)
+	}
+	sort.Strings(in.names)
+	// Create every type first, so they can refer to each other.
+	for _, name := range in.names {
+		in.types[name] = map[string]interface{}{"name": name}
+	}
+	var types []interface{}
+	for _, name := range in.names {
+		in.fill(s.types[name])
+		types = append(types, in.types[name])
+	}
+
+	ifArg := map[string]interface{}{
+		"name":         "if",
+		"type":         map[string]interface{}{"kind": "NON_NULL", "ofType": in.types["Boolean"]},
+		"isDeprecated": false,
+	}
+	directive := func(name string) map[string]interface{} {
+		return map[string]interface{}{
+			"name":         name,
+			"locations":    []interface{}{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+			"args":         []interface{}{ifArg},
+			"isRepeatable": false,
+		}
+	}
+
+	in.schema = map[string]interface{}{
+		"types":      types,
+		"queryType":  in.types[s.Query.Name],
+		"directives": []interface{}{directive("include"), directive("skip")},
+	}
+	return in
+}
+
+// fill sets the fields of the named type.
+func (in *introspection) fill(t Type) {
+	m := in.types[t.String()]
+	switch t := t.(type) {
+	case Scalar:
+		m["kind"] = "SCALAR"
+	case *Object:
+		m["kind"] = "OBJECT"
+		m["fields"] = in.fields(t.Fields)
+		interfaces := []interface{}{}
+		for _, i := range t.Interfaces {
+			interfaces = append(interfaces, in.types[i.Name])
+		}
+		m["interfaces"] = interfaces
+	case *Interface:
+		m["kind"] = "INTERFACE"
+		m["fields"] = in.fields(t.Fields)
+		m["interfaces"] = []interface{}{}
+		var possible []interface{}
+		for _, name := range in.names {
+			if o, ok := in.s.types[name].(*Object); ok && o.implements(t.Name) {
+				possible = append(possible, in.types[name])
+			}
+		}
+		m["possibleTypes"] = possible
+	}
+}
+
+func (in *introspection) fields(fields Fields) []interface{} {
+	var list []interface{}
+	var names []string
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f := fields[name]
+		args := []interface{}{}
+		for _, arg := range f.Args {
+			t, ok := f.ArgTypes[arg]
+			if !ok {
+				t = String
+			}
+			args = append(args, map[string]interface{}{
+				"name":         arg,
+				"type":         in.ref(t),
+				"isDeprecated": false,
+			})
+		}
+		list = append(list, map[string]interface{}{
+			"name":         name,
+			"args":         args,
+			"type":         in.ref(f.Type),
+			"isDeprecated": false,
+		})
+	}
+	return list
+}
+
+// ref returns the introspection of a reference to t.
+func (in *introspection) ref(t Type) map[string]interface{} {
+	if l, ok := t.(*List); ok {
+		return map[string]interface{}{"kind": "LIST", "ofType": in.ref(l.Of)}
+	}
+	return in.types[t.String()]
+}
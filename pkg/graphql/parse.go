@@ -0,0 +1,661 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// document is a parsed GraphQL query document.
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	kind string // query, mutation or subscription
+	name string
+	vars []*variableDefinition
+	sel  []selection
+}
+
+type variableDefinition struct {
+	name string
+	def  interface{}
+	has  bool
+}
+
+type fragment struct {
+	name string
+	on   string
+	dirs []*directive
+	sel  []selection
+}
+
+// selection is a *field, *fragmentSpread or *inlineFragment.
+type selection interface{}
+
+type field struct {
+	alias string
+	name  string
+	args  []*argument
+	dirs  []*directive
+	sel   []selection
+}
+
+// key is the name of the field in the response.
+func (f *field) key() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+type fragmentSpread struct {
+	name string
+	dirs []*directive
+}
+
+type inlineFragment struct {
+	on   string
+	dirs []*directive
+	sel  []selection
+}
+
+type argument struct {
+	name  string
+	value interface{}
+}
+
+type directive struct {
+	name string
+	args []*argument
+}
+
+// variable is a reference to a variable in a value.
+type variable string
+
+// enum is an enum value, which is served as its name.
+type enum string
+
+// objectValue is an input object literal.
+type objectValue []*argument
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// Queries are limited in how deep selection sets, lists and input objects
+// nest, so they cannot exhaust the stack, and in how many fields they select,
+// counting those of fragments each time they are spread, so they cannot fan
+// out.
+const (
+	maxDepth  = 20
+	maxFields = 1000
+)
+
+type parser struct {
+	src string
+	pos int
+	tok token
+
+	depth  int
+	fields int
+}
+
+// nest enters a selection set, list or input object, failing when they nest
+// deeper than maxDepth. leave must be called when it ends.
+func (p *parser) nest() error {
+	p.depth++
+	if p.depth > maxDepth {
+		return p.errorf("the query nests more than %d levels deep", maxDepth)
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+// parse parses a query document.
+func parse(src string) (*document, error) {
+	p := &parser{src: src}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	doc := &document{fragments: make(map[string]*fragment)}
+	for p.tok.kind != tokEOF {
+		switch {
+		case p.is(tokPunct, "{"):
+			sel, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &operation{kind: "query", sel: sel})
+		case p.is(tokName, "query"), p.is(tokName, "mutation"), p.is(tokName, "subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case p.is(tokName, "fragment"):
+			f, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			doc.fragments[f.name] = f
+		default:
+			return nil, p.errorf("unexpected %q", p.tok.text)
+		}
+	}
+	return doc, nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	line, col := 1, 1
+	for _, r := range p.src[:p.tok.pos] {
+		if r == '\n' {
+			line, col = line+1, 1
+		} else {
+			col++
+		}
+	}
+	return fmt.Errorf("syntax error at %d:%d: %s", line, col, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) is(kind tokenKind, text string) bool {
+	return p.tok.kind == kind && p.tok.text == text
+}
+
+// skip consumes the punctuator if it is next.
+func (p *parser) skip(text string) (bool, error) {
+	if !p.is(tokPunct, text) {
+		return false, nil
+	}
+	return true, p.next()
+}
+
+func (p *parser) expect(text string) error {
+	if !p.is(tokPunct, text) {
+		return p.errorf("expected %q, found %q", text, p.tok.text)
+	}
+	return p.next()
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokName {
+		return "", p.errorf("expected name, found %q", p.tok.text)
+	}
+	name := p.tok.text
+	return name, p.next()
+}
+
+func (p *parser) operation() (*operation, error) {
+	op := &operation{kind: p.tok.text}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokName {
+		op.name = p.tok.text
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	if ok, err := p.skip("("); err != nil {
+		return nil, err
+	} else if ok {
+		for !p.is(tokPunct, ")") {
+			v, err := p.variableDefinition()
+			if err != nil {
+				return nil, err
+			}
+			op.vars = append(op.vars, v)
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	sel, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.sel = sel
+	return op, nil
+}
+
+func (p *parser) variableDefinition() (*variableDefinition, error) {
+	if err := p.expect("$"); err != nil {
+		return nil, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	if err := p.typeRef(); err != nil {
+		return nil, err
+	}
+	v := &variableDefinition{name: name}
+	if ok, err := p.skip("="); err != nil {
+		return nil, err
+	} else if ok {
+		if v.def, err = p.value(true); err != nil {
+			return nil, err
+		}
+		v.has = true
+	}
+	return v, nil
+}
+
+// typeRef skips a type reference, variables are not type checked.
+func (p *parser) typeRef() error {
+	if ok, err := p.skip("["); err != nil {
+		return err
+	} else if ok {
+		if err := p.nest(); err != nil {
+			return err
+		}
+		defer p.leave()
+		if err := p.typeRef(); err != nil {
+			return err
+		}
+		if err := p.expect("]"); err != nil {
+			return err
+		}
+	} else if _, err := p.name(); err != nil {
+		return err
+	}
+	_, err := p.skip("!")
+	return err
+}
+
+func (p *parser) fragment() (*fragment, error) {
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if !p.is(tokName, "on") {
+		return nil, p.errorf("expected \"on\", found %q", p.tok.text)
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	f := &fragment{name: name}
+	if f.on, err = p.name(); err != nil {
+		return nil, err
+	}
+	if f.dirs, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if f.sel, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (p *parser) selectionSet() ([]selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	if err := p.nest(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	var sel []selection
+	for !p.is(tokPunct, "}") {
+		if p.tok.kind == tokEOF {
+			return nil, p.errorf("unexpected end of query")
+		}
+		s, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		sel = append(sel, s)
+	}
+	return sel, p.next()
+}
+
+func (p *parser) selection() (selection, error) {
+	if ok, err := p.skip("..."); err != nil {
+		return nil, err
+	} else if ok {
+		if p.tok.kind == tokName && p.tok.text != "on" {
+			s := &fragmentSpread{name: p.tok.text}
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			if s.dirs, err = p.directives(); err != nil {
+				return nil, err
+			}
+			return s, nil
+		}
+		f := &inlineFragment{}
+		if p.is(tokName, "on") {
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			if f.on, err = p.name(); err != nil {
+				return nil, err
+			}
+		}
+		if f.dirs, err = p.directives(); err != nil {
+			return nil, err
+		}
+		if f.sel, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+
+	p.fields++
+	if p.fields > maxFields {
+		return nil, p.errorf("the query selects more than %d fields", maxFields)
+	}
+	f := &field{}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := p.skip(":"); err != nil {
+		return nil, err
+	} else if ok {
+		f.alias = name
+		if name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	f.name = name
+	if f.args, err = p.arguments(false); err != nil {
+		return nil, err
+	}
+	if f.dirs, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if p.is(tokPunct, "{") {
+		if f.sel, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (p *parser) arguments(constant bool) ([]*argument, error) {
+	if ok, err := p.skip("("); err != nil || !ok {
+		return nil, err
+	}
+	var args []*argument
+	for !p.is(tokPunct, ")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		v, err := p.value(constant)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, &argument{name: name, value: v})
+	}
+	return args, p.next()
+}
+
+func (p *parser) directives() ([]*directive, error) {
+	var dirs []*directive
+	for p.is(tokPunct, "@") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.arguments(false)
+		if err != nil {
+			return nil, err
+		}
+		dirs = append(dirs, &directive{name: name, args: args})
+	}
+	return dirs, nil
+}
+
+func (p *parser) value(constant bool) (interface{}, error) {
+	t := p.tok
+	switch {
+	case t.kind == tokPunct && t.text == "$" && !constant:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		return variable(name), err
+
+	case t.kind == tokInt:
+		n, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			return nil, p.errorf("invalid int %q", t.text)
+		}
+		return int(n), p.next()
+
+	case t.kind == tokFloat:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, p.errorf("invalid float %q", t.text)
+		}
+		return f, p.next()
+
+	case t.kind == tokString:
+		return t.text, p.next()
+
+	case t.kind == tokName:
+		var v interface{}
+		switch t.text {
+		case "true":
+			v = true
+		case "false":
+			v = false
+		case "null":
+			v = nil
+		default:
+			v = enum(t.text)
+		}
+		return v, p.next()
+
+	case t.kind == tokPunct && t.text == "[":
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if err := p.nest(); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+		list := []interface{}{}
+		for !p.is(tokPunct, "]") {
+			v, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, p.next()
+
+	case t.kind == tokPunct && t.text == "{":
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if err := p.nest(); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+		var obj objectValue
+		for !p.is(tokPunct, "}") {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			v, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, &argument{name: name, value: v})
+		}
+		return obj, p.next()
+	}
+	return nil, p.errorf("unexpected %q", t.text)
+}
+
+// next reads the next token, skipping whitespace, commas and comments.
+func (p *parser) next() error {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' || c == 0xEF || c == 0xBB || c == 0xBF {
+			p.pos++
+		} else if c == '#' {
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		} else {
+			break
+		}
+	}
+	start := p.pos
+	if p.pos >= len(p.src) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return nil
+	}
+
+	c := p.src[p.pos]
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], "..."):
+		p.pos += 3
+		p.tok = token{kind: tokPunct, text: "...", pos: start}
+
+	case strings.IndexByte("!$():=@[]{|}&", c) >= 0:
+		p.pos++
+		p.tok = token{kind: tokPunct, text: string(c), pos: start}
+
+	case c == '_' || isLetter(c):
+		for p.pos < len(p.src) && (p.src[p.pos] == '_' || isLetter(p.src[p.pos]) || isDigit(p.src[p.pos])) {
+			p.pos++
+		}
+		p.tok = token{kind: tokName, text: p.src[start:p.pos], pos: start}
+
+	case c == '-' || isDigit(c):
+		kind := tokInt
+		p.pos++
+		for p.pos < len(p.src) {
+			d := p.src[p.pos]
+			if isDigit(d) {
+				p.pos++
+			} else if d == '.' || d == 'e' || d == 'E' || ((d == '+' || d == '-') && kind == tokFloat) {
+				kind = tokFloat
+				p.pos++
+			} else {
+				break
+			}
+		}
+		p.tok = token{kind: kind, text: p.src[start:p.pos], pos: start}
+
+	case c == '"':
+		s, err := p.str()
+		if err != nil {
+			return err
+		}
+		p.tok = token{kind: tokString, text: s, pos: start}
+
+	default:
+		p.tok = token{pos: start, text: string(c)}
+		return p.errorf("unexpected character %q", c)
+	}
+	return nil
+}
+
+// str reads a quoted or block string.
+func (p *parser) str() (string, error) {
+	if strings.HasPrefix(p.src[p.pos:], `"""`) {
+		end := strings.Index(p.src[p.pos+3:], `"""`)
+		if end < 0 {
+			return "", fmt.Errorf("syntax error: unterminated block string")
+		}
+		s := p.src[p.pos+3 : p.pos+3+end]
+		p.pos += end + 6
+		return strings.TrimSpace(s), nil
+	}
+
+	var b strings.Builder
+	p.pos++
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == '"':
+			p.pos++
+			return b.String(), nil
+		case c == '\n':
+			return "", fmt.Errorf("syntax error: unterminated string")
+		case c == '\\' && p.pos+1 < len(p.src):
+			p.pos++
+			switch e := p.src[p.pos]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'u':
+				if p.pos+4 >= len(p.src) {
+					return "", fmt.Errorf("syntax error: invalid unicode escape")
+				}
+				r, err := strconv.ParseUint(p.src[p.pos+1:p.pos+5], 16, 32)
+				if err != nil {
+					return "", fmt.Errorf("syntax error: invalid unicode escape")
+				}
+				b.WriteRune(rune(r))
+				p.pos += 4
+			default:
+				b.WriteByte(e)
+			}
+			p.pos++
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", fmt.Errorf("syntax error: unterminated string")
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,120 @@
+// Package graphql is a small GraphQL query executor. It parses query
+// documents, with variables, aliases, fragments and the @skip and @include
+// directives, validates them and resolves them against a schema of object
+// and interface types built in Go. The schema can be introspected, with
+// __schema, __type and __typename. Mutations and subscriptions are not
+// supported.
+package graphql
+
+import (
+	"context"
+	"sync"
+)
+
+// Type is the type of a field: a Scalar, *List, *Object or *Interface.
+type Type interface {
+	String() string
+}
+
+// Scalar is a leaf type, its values are served as the resolver returns them.
+type Scalar string
+
+// The built in scalars, and JSON for free form objects.
+const (
+	String  Scalar = "String"
+	Int     Scalar = "Int"
+	Float   Scalar = "Float"
+	Boolean Scalar = "Boolean"
+	ID      Scalar = "ID"
+	JSON    Scalar = "JSON"
+)
+
+func (s Scalar) String() string { return string(s) }
+
+// List is a list of another type.
+type List struct {
+	Of Type
+}
+
+// ListOf returns a list of t.
+func ListOf(t Type) *List { return &List{Of: t} }
+
+func (l *List) String() string { return "[" + l.Of.String() + "]" }
+
+// Object is a type with fields. Fields may be set after the object is
+// created, so that types can refer to each other.
+type Object struct {
+	Name       string
+	Interfaces []*Interface
+	Fields     Fields
+}
+
+func (o *Object) String() string { return o.Name }
+
+// implements reports whether a fragment on the named type applies to o.
+func (o *Object) implements(name string) bool {
+	if name == "" || name == o.Name {
+		return true
+	}
+	for _, i := range o.Interfaces {
+		if i.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Interface is an abstract type, ResolveType returns the object type of a
+// value.
+type Interface struct {
+	Name        string
+	Fields      Fields
+	ResolveType func(value interface{}) *Object
+}
+
+func (i *Interface) String() string { return i.Name }
+
+// Fields are the fields of a type by name.
+type Fields map[string]*Field
+
+// Field is a field of an object.
+type Field struct {
+	Type Type
+
+	// Args are the names of the arguments the field accepts.
+	Args []string
+
+	// ArgTypes are the types of the arguments, as introspection shows
+	// them. Arguments not listed are Strings.
+	ArgTypes map[string]Type
+
+	// Resolve returns the value of the field. A nil Resolve serves the
+	// value of Source if it is a map[string]interface{}.
+	Resolve func(p Params) (interface{}, error)
+}
+
+// Params are passed to a resolver.
+type Params struct {
+	Context context.Context
+	Source  interface{}
+	Args    map[string]interface{}
+}
+
+// String returns the string argument name, or "".
+func (p Params) String(name string) string {
+	s, _ := p.Args[name].(string)
+	return s
+}
+
+// Schema is the root of the types queries are resolved against.
+type Schema struct {
+	Query *Object
+
+	// Types are object types only reachable through an interface, so
+	// that fragments and introspection know them.
+	Types []*Object
+
+	once  sync.Once
+	root  *Object
+	types map[string]Type
+}
@@ -0,0 +1,199 @@
+package graphql
+
+import "fmt"
+
+// validator checks an operation against the schema before it runs.
+type validator struct {
+	s       *Schema
+	doc     *document
+	defined map[string]bool
+	vars    map[string]interface{}
+	fields  int
+}
+
+// validate checks that the fields of the operation exist and take the
+// arguments given, that fields of object types and only those have
+// selections, that the fragments, directives and variables used are defined,
+// and that the query stays within maxDepth and maxFields with its fragments
+// expanded.
+func (s *Schema) validate(doc *document, op *operation, vars map[string]interface{}) error {
+	v := &validator{s: s, doc: doc, defined: make(map[string]bool), vars: vars}
+	for _, d := range op.vars {
+		v.defined[d.name] = true
+	}
+	return v.selectionSet(s.root, op.sel, 1, make(map[string]bool))
+}
+
+func (v *validator) selectionSet(t Type, sel []selection, depth int, spreads map[string]bool) error {
+	if depth > maxDepth {
+		return fmt.Errorf("the query nests more than %d levels deep", maxDepth)
+	}
+	for _, s := range sel {
+		// Spreads count too, so fragments spreading others cannot fan out
+		// without selecting anything.
+		v.fields++
+		if v.fields > maxFields {
+			return fmt.Errorf("the query selects more than %d fields", maxFields)
+		}
+
+		switch s := s.(type) {
+		case *field:
+			if err := v.field(t, s, depth, spreads); err != nil {
+				return err
+			}
+
+		case *fragmentSpread:
+			if err := v.directives(s.dirs); err != nil {
+				return err
+			}
+			f, ok := v.doc.fragments[s.name]
+			if !ok {
+				return fmt.Errorf("unknown fragment %q", s.name)
+			}
+			if spreads[s.name] {
+				return fmt.Errorf("fragment %q spreads itself", s.name)
+			}
+			on, ok := v.s.types[f.on]
+			if !ok {
+				return fmt.Errorf("fragment %q is on unknown type %q", f.name, f.on)
+			}
+			if err := v.directives(f.dirs); err != nil {
+				return err
+			}
+			spreads[s.name] = true
+			err := v.selectionSet(on, f.sel, depth, spreads)
+			delete(spreads, s.name)
+			if err != nil {
+				return err
+			}
+
+		case *inlineFragment:
+			if err := v.directives(s.dirs); err != nil {
+				return err
+			}
+			on := t
+			if s.on != "" {
+				var ok bool
+				if on, ok = v.s.types[s.on]; !ok {
+					return fmt.Errorf("inline fragment is on unknown type %q", s.on)
+				}
+			}
+			if err := v.selectionSet(on, s.sel, depth, spreads); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (v *validator) field(t Type, f *field, depth int, spreads map[string]bool) error {
+	if err := v.directives(f.dirs); err != nil {
+		return err
+	}
+	if f.name == "__typename" {
+		if len(f.args) > 0 || f.sel != nil {
+			return fmt.Errorf("field \"__typename\" takes no arguments or selection")
+		}
+		return nil
+	}
+
+	var def *Field
+	switch t := t.(type) {
+	case *Object:
+		def = t.Fields[f.name]
+	case *Interface:
+		def = t.Fields[f.name]
+	}
+	if def == nil {
+		return fmt.Errorf("cannot query field %q on type %q", f.name, t.String())
+	}
+
+	for _, a := range f.args {
+		known := false
+		for _, name := range def.Args {
+			if name == a.name {
+				known = true
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown argument %q on field %q", a.name, f.name)
+		}
+		if err := v.value(a.value); err != nil {
+			return err
+		}
+	}
+
+	named := def.Type
+	for {
+		l, ok := named.(*List)
+		if !ok {
+			break
+		}
+		named = l.Of
+	}
+	if _, leaf := named.(Scalar); leaf {
+		if f.sel != nil {
+			return fmt.Errorf("field %q of type %s must not have a selection", f.name, def.Type)
+		}
+		return nil
+	}
+	if len(f.sel) == 0 {
+		return fmt.Errorf("field %q of type %s must have a selection of subfields", f.name, def.Type)
+	}
+	return v.selectionSet(named, f.sel, depth+1, spreads)
+}
+
+// directives checks that only @skip and @include are used, with an if
+// argument that is a Boolean once variables are applied.
+func (v *validator) directives(dirs []*directive) error {
+	for _, d := range dirs {
+		if d.name != "skip" && d.name != "include" {
+			return fmt.Errorf("unknown directive @%s", d.name)
+		}
+		var cond interface{}
+		found := false
+		for _, a := range d.args {
+			if a.name != "if" {
+				return fmt.Errorf("unknown argument %q on directive @%s", a.name, d.name)
+			}
+			if err := v.value(a.value); err != nil {
+				return err
+			}
+			cond, found = a.value, true
+		}
+		if !found {
+			return fmt.Errorf("directive @%s needs an if argument", d.name)
+		}
+		if name, ok := cond.(variable); ok {
+			cond = v.vars[string(name)]
+		}
+		if _, ok := cond.(bool); !ok {
+			return fmt.Errorf("argument if of directive @%s must be a Boolean, got %v", d.name, cond)
+		}
+	}
+	return nil
+}
+
+// value checks that the variables used in a literal are defined by the
+// operation.
+func (v *validator) value(value interface{}) error {
+	switch value := value.(type) {
+	case variable:
+		if !v.defined[string(value)] {
+			return fmt.Errorf("variable $%s is not defined", string(value))
+		}
+	case []interface{}:
+		for _, item := range value {
+			if err := v.value(item); err != nil {
+				return err
+			}
+		}
+	case objectValue:
+		for _, a := range value {
+			if err := v.value(a.value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
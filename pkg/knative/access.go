@@ -0,0 +1,87 @@
+package knative
+
+import (
+	"log"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Authorizer reports whether a resource may be listed in the namespace.
+type Authorizer func(namespace string, gvr schema.GroupVersionResource) bool
+
+// WithAuthorizer makes the client skip the resources the authorizer denies,
+// returning no objects for them. Several authorizers must all allow a
+// resource.
+func WithAuthorizer(a Authorizer) ClientOption {
+	return func(c *Client) {
+		if a == nil {
+			return
+		}
+		if prev := c.authorizer; prev != nil {
+			c.authorizer = func(namespace string, gvr schema.GroupVersionResource) bool {
+				return prev(namespace, gvr) && a(namespace, gvr)
+			}
+			return
+		}
+		c.authorizer = a
+	}
+}
+
+// Resource is a kind of the topology and the resource it is listed through.
+type Resource struct {
+	schema.GroupVersionResource
+	Kind string
+}
+
+// Denied returns the kinds the client skipped, because they were not
+// allowed or listing them was forbidden.
+func (c *Client) Denied() []Resource {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Resource(nil), c.denied...)
+}
+
+// CanList reports whether any kind of the topology may be listed in the
+// namespace.
+func (c *Client) CanList(namespace string) bool {
+	if c.authorizer == nil {
+		return true
+	}
+	for _, gvr := range c.TopologyGVRs() {
+		if c.authorizer(namespace, gvr) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowed asks the authorizer whether gvr may be listed, remembering the kind
+// if not.
+func (c *Client) allowed(namespace string, gvr schema.GroupVersionResource, kind string) bool {
+	if c.authorizer == nil || c.authorizer(namespace, gvr) {
+		return true
+	}
+	c.deny(gvr, kind)
+	return false
+}
+
+// failed reports a failed list, or an object of the list that could not be
+// read and is left out, remembering the kind if it was forbidden.
+func (c *Client) failed(gvr schema.GroupVersionResource, kind string, err error) {
+	log.Printf("Failed to List %s, %v", gvr.String(), err)
+	if apierrors.IsForbidden(err) {
+		c.deny(gvr, kind)
+	}
+}
+
+func (c *Client) deny(gvr schema.GroupVersionResource, kind string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, d := range c.denied {
+		if d.GroupVersionResource == gvr {
+			return
+		}
+	}
+	c.denied = append(c.denied, Resource{GroupVersionResource: gvr, Kind: kind})
+}
@@ -0,0 +1,41 @@
+package knative
+
+import (
+	"sync"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestDeniedWhileListing(t *testing.T) {
+	dc := readSnapshot(t, twoVersionsYAML)
+	c := New(dc, WithAuthorizer(func(namespace string, gvr schema.GroupVersionResource) bool {
+		return gvr.Group != "sources.eventing.knative.dev"
+	}))
+
+	// Watches deny kinds from their own goroutines while requests read
+	// what was denied.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			c.Sources("demo")
+		}()
+		go func() {
+			defer wg.Done()
+			c.Denied()
+		}()
+	}
+	wg.Wait()
+
+	denied := c.Denied()
+	if len(denied) != 2 {
+		t.Fatalf("denied = %v, want both versions of cronjobsources", denied)
+	}
+	for _, d := range denied {
+		if d.Kind != "CronJobSource" {
+			t.Errorf("denied %s, want CronJobSource", d.Kind)
+		}
+	}
+}
@@ -1,16 +1,46 @@
 package knative
 
 import (
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/dynamic"
 )
 
-func New(dc dynamic.Interface) *Client {
+func New(dc dynamic.Interface, opts ...ClientOption) *Client {
 	c := &Client{
 		dc: dc,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
 	return c
 }
 
 type Client struct {
 	dc dynamic.Interface
+
+	authorizer Authorizer
+
+	mu     sync.Mutex // guards denied, also added to by watches
+	denied []Resource
+
+	selector string // label selector of the objects listed in a namespace
+
+	informers *SourceInformers // caches the sources of one namespace
+}
+
+// ClientOption configures a Client.
+type ClientOption func(*Client)
+
+// WithLabelSelector lists only the objects in a namespace that match the
+// label selector.
+func WithLabelSelector(selector string) ClientOption {
+	return func(c *Client) {
+		c.selector = selector
+	}
+}
+
+func (c *Client) listOptions() metav1.ListOptions {
+	return metav1.ListOptions{LabelSelector: c.selector}
 }
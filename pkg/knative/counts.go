@@ -0,0 +1,57 @@
+package knative
+
+import (
+	"log"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Counts returns the number of topology resources in each of the namespaces
+// that has any, by namespace and then kind. Each namespace is listed on its
+// own, without namespaces every namespace of the cluster is listed at once.
+func (c *Client) Counts(namespaces ...string) map[string]map[string]int {
+	counts := make(map[string]map[string]int)
+	seen := make(map[string]bool) // sources are served at several versions
+	add := func(r Resource, items []unstructured.Unstructured) {
+		for _, item := range items {
+			id := objectID(&item, item.GroupVersionKind().GroupKind())
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			ns := item.GetNamespace()
+			if !c.allowed(ns, r.GroupVersionResource, r.Kind) {
+				continue
+			}
+			if counts[ns] == nil {
+				counts[ns] = make(map[string]int)
+			}
+			counts[ns][item.GetKind()]++
+		}
+	}
+
+	for _, r := range c.TopologyResources() {
+		if len(namespaces) == 0 {
+			list, err := c.dc.Resource(r.GroupVersionResource).List(metav1.ListOptions{})
+			if err != nil {
+				log.Printf("Failed to List %s, %v", r.GroupVersionResource.String(), err)
+				continue
+			}
+			add(r, list.Items)
+			continue
+		}
+		for _, ns := range namespaces {
+			if !c.allowed(ns, r.GroupVersionResource, r.Kind) {
+				continue
+			}
+			list, err := c.dc.Resource(r.GroupVersionResource).Namespace(ns).List(c.listOptions())
+			if err != nil {
+				c.failed(r.GroupVersionResource, r.Kind, err)
+				continue
+			}
+			add(r, list.Items)
+		}
+	}
+	return counts
+}
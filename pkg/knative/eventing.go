@@ -1,9 +1,8 @@
 package knative
 
 import (
-	"log"
-
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
@@ -12,30 +11,68 @@ import (
 )
 
 func (c *Client) Sources(namespace string) []duckv1alpha1.SourceType {
-	gvrs := crdsToGVR(c.SourceCRDs())
 	all := make([]duckv1alpha1.SourceType, 0)
 
-	for _, gvr := range gvrs {
-		like := duckv1alpha1.SourceType{}
-		list, err := c.dc.Resource(gvr).Namespace(namespace).List(metav1.ListOptions{})
-		if err != nil {
-			log.Printf("Failed to List %s, %v", gvr.String(), err)
+	for _, crd := range c.SourceCRDs() {
+		all = append(all, c.sources(namespace, crd)...)
+	}
+	return all
+}
+
+// sources lists the sources of the CRD. A source is served at every version
+// of the CRD, it is listed once at the first version it is found at.
+func (c *Client) sources(namespace string, crd apiextensions.CustomResourceDefinition) []duckv1alpha1.SourceType {
+	all := make([]duckv1alpha1.SourceType, 0)
+	seen := make(map[string]bool)
+
+	for _, gvr := range crdsToGVR([]apiextensions.CustomResourceDefinition{crd}) {
+		if !c.allowed(namespace, gvr, crd.Spec.Names.Kind) {
 			continue
 		}
-
-		for _, item := range list.Items {
-			obj := like.DeepCopy()
-			if err = runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, obj); err != nil {
-				log.Fatalf("Error DefaultUnstructuredConverter.FromUnstructured. %v", err)
+		var sources []duckv1alpha1.SourceType
+		if c.informers != nil && c.informers.namespace == namespace {
+			sources = c.cachedSources(gvr, crd.Spec.Names.Kind)
+		} else {
+			list, err := listSources(c.dc.Resource(gvr).Namespace(namespace), c.listOptions())
+			if err != nil {
+				c.failed(gvr, crd.Spec.Names.Kind, err)
+				continue
+			}
+			sources = list.Items
+		}
+		gk := schema.GroupKind{Group: gvr.Group, Kind: crd.Spec.Names.Kind}
+		for _, obj := range sources {
+			if id := objectID(&obj, gk); !seen[id] {
+				seen[id] = true
+				obj.APIVersion = gvr.GroupVersion().String()
+				all = append(all, obj)
 			}
-			obj.ResourceVersion = gvr.Version
-			obj.APIVersion = gvr.GroupVersion().String()
-			all = append(all, *obj)
 		}
 	}
 	return all
 }
 
+// cachedSources lists the sources of the resource from its informer.
+func (c *Client) cachedSources(gvr schema.GroupVersionResource, kind string) []duckv1alpha1.SourceType {
+	_, lister, err := c.informers.Get(gvr)
+	if err != nil {
+		c.failed(gvr, kind, err)
+		return nil
+	}
+	sources, err := lister.List(labels.Everything())
+	if err != nil {
+		c.failed(gvr, kind, err)
+		return nil
+	}
+	all := make([]duckv1alpha1.SourceType, len(sources))
+	for i, source := range sources {
+		obj := source.DeepCopy()
+		obj.APIVersion = gvr.GroupVersion().String()
+		all[i] = *obj
+	}
+	return all
+}
+
 func (c *Client) Triggers(namespace string) []eventingv1alpha1.Trigger {
 	gvr := schema.GroupVersionResource{
 		Group:    "eventing.knative.dev",
@@ -44,21 +81,26 @@ func (c *Client) Triggers(namespace string) []eventingv1alpha1.Trigger {
 	}
 	like := eventingv1alpha1.Trigger{}
 
-	list, err := c.dc.Resource(gvr).Namespace(namespace).List(metav1.ListOptions{})
+	if !c.allowed(namespace, gvr, "Trigger") {
+		return nil
+	}
+
+	list, err := c.dc.Resource(gvr).Namespace(namespace).List(c.listOptions())
 	if err != nil {
-		log.Fatalf("Failed to List Triggers, %v", err)
+		c.failed(gvr, "Trigger", err)
+		return nil
 	}
 
-	all := make([]eventingv1alpha1.Trigger, len(list.Items))
+	all := make([]eventingv1alpha1.Trigger, 0, len(list.Items))
 
-	for i, item := range list.Items {
+	for _, item := range list.Items {
 		obj := like.DeepCopy()
 		if err = runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, obj); err != nil {
-			log.Fatalf("Error DefaultUnstructuree.Dynamiconverter. %v", err)
+			c.failed(gvr, "Trigger", err)
+			continue
 		}
-		obj.ResourceVersion = gvr.Version
 		obj.APIVersion = gvr.GroupVersion().String()
-		all[i] = *obj
+		all = append(all, *obj)
 	}
 	return all
 }
@@ -71,21 +113,26 @@ func (c *Client) Brokers(namespace string) []eventingv1alpha1.Broker {
 	}
 	like := eventingv1alpha1.Broker{}
 
-	list, err := c.dc.Resource(gvr).Namespace(namespace).List(metav1.ListOptions{})
+	if !c.allowed(namespace, gvr, "Broker") {
+		return nil
+	}
+
+	list, err := c.dc.Resource(gvr).Namespace(namespace).List(c.listOptions())
 	if err != nil {
-		log.Fatalf("Failed to List Brokers, %v", err)
+		c.failed(gvr, "Broker", err)
+		return nil
 	}
 
-	all := make([]eventingv1alpha1.Broker, len(list.Items))
+	all := make([]eventingv1alpha1.Broker, 0, len(list.Items))
 
-	for i, item := range list.Items {
+	for _, item := range list.Items {
 		obj := like.DeepCopy()
 		if err = runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, obj); err != nil {
-			log.Fatalf("Error DefaultUnstructuree.Dynamiconverter. %v", err)
+			c.failed(gvr, "Broker", err)
+			continue
 		}
-		obj.ResourceVersion = gvr.Version
 		obj.APIVersion = gvr.GroupVersion().String()
-		all[i] = *obj
+		all = append(all, *obj)
 	}
 	return all
 }
@@ -98,21 +145,26 @@ func (c *Client) Channels(namespace string) []eventingv1alpha1.Channel {
 	}
 	like := eventingv1alpha1.Channel{}
 
-	list, err := c.dc.Resource(gvr).Namespace(namespace).List(metav1.ListOptions{})
+	if !c.allowed(namespace, gvr, "Channel") {
+		return nil
+	}
+
+	list, err := c.dc.Resource(gvr).Namespace(namespace).List(c.listOptions())
 	if err != nil {
-		log.Fatalf("Failed to List Channels, %v", err)
+		c.failed(gvr, "Channel", err)
+		return nil
 	}
 
-	all := make([]eventingv1alpha1.Channel, len(list.Items))
+	all := make([]eventingv1alpha1.Channel, 0, len(list.Items))
 
-	for i, item := range list.Items {
+	for _, item := range list.Items {
 		obj := like.DeepCopy()
 		if err = runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, obj); err != nil {
-			log.Fatalf("Error DefaultUnstructuree.Dynamiconverter. %v", err)
+			c.failed(gvr, "Channel", err)
+			continue
 		}
-		obj.ResourceVersion = gvr.Version
 		obj.APIVersion = gvr.GroupVersion().String()
-		all[i] = *obj
+		all = append(all, *obj)
 	}
 	return all
 }
@@ -125,21 +177,26 @@ func (c *Client) Subscriptions(namespace string) []eventingv1alpha1.Subscription
 	}
 	like := eventingv1alpha1.Subscription{}
 
-	list, err := c.dc.Resource(gvr).Namespace(namespace).List(metav1.ListOptions{})
+	if !c.allowed(namespace, gvr, "Subscription") {
+		return nil
+	}
+
+	list, err := c.dc.Resource(gvr).Namespace(namespace).List(c.listOptions())
 	if err != nil {
-		log.Fatalf("Failed to List Subscriptions, %v", err)
+		c.failed(gvr, "Subscription", err)
+		return nil
 	}
 
-	all := make([]eventingv1alpha1.Subscription, len(list.Items))
+	all := make([]eventingv1alpha1.Subscription, 0, len(list.Items))
 
-	for i, item := range list.Items {
+	for _, item := range list.Items {
 		obj := like.DeepCopy()
 		if err = runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, obj); err != nil {
-			log.Fatalf("Error DefaultUnstructuree.Dynamiconverter. %v", err)
+			c.failed(gvr, "Subscription", err)
+			continue
 		}
-		obj.ResourceVersion = gvr.Version
 		obj.APIVersion = gvr.GroupVersion().String()
-		all[i] = *obj
+		all = append(all, *obj)
 	}
 	return all
 }
@@ -152,21 +209,26 @@ func (c *Client) EventTypes(namespace string) []eventingv1alpha1.EventType {
 	}
 	like := eventingv1alpha1.EventType{}
 
-	list, err := c.dc.Resource(gvr).Namespace(namespace).List(metav1.ListOptions{})
+	if !c.allowed(namespace, gvr, "EventType") {
+		return nil
+	}
+
+	list, err := c.dc.Resource(gvr).Namespace(namespace).List(c.listOptions())
 	if err != nil {
-		log.Fatalf("Failed to List EventTypes, %v", err)
+		c.failed(gvr, "EventType", err)
+		return nil
 	}
 
-	all := make([]eventingv1alpha1.EventType, len(list.Items))
+	all := make([]eventingv1alpha1.EventType, 0, len(list.Items))
 
-	for i, item := range list.Items {
+	for _, item := range list.Items {
 		obj := like.DeepCopy()
 		if err = runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, obj); err != nil {
-			log.Fatalf("Error DefaultUnstructuree.Dynamiconverter. %v", err)
+			c.failed(gvr, "EventType", err)
+			continue
 		}
-		obj.ResourceVersion = gvr.Version
 		obj.APIVersion = gvr.GroupVersion().String()
-		all[i] = *obj
+		all = append(all, *obj)
 	}
 	return all
 }
@@ -0,0 +1,64 @@
+package knative
+
+import (
+	"testing"
+)
+
+// twoVersionsYAML is a source CRD served at two versions. An API server
+// serves each source at both, so the snapshot has each source twice.
+const twoVersionsYAML = `
+apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+  name: cronjobsources.sources.eventing.knative.dev
+  labels: {eventing.knative.dev/source: "true"}
+spec:
+  group: sources.eventing.knative.dev
+  names: {kind: CronJobSource, plural: cronjobsources}
+  versions:
+  - {name: v1alpha2, served: true, storage: true}
+  - {name: v1alpha1, served: true, storage: false}
+---
+apiVersion: sources.eventing.knative.dev/v1alpha2
+kind: CronJobSource
+metadata: {name: cron, namespace: demo, uid: cron-uid}
+---
+apiVersion: sources.eventing.knative.dev/v1alpha1
+kind: CronJobSource
+metadata: {name: cron, namespace: demo, uid: cron-uid}
+---
+apiVersion: sources.eventing.knative.dev/v1alpha2
+kind: CronJobSource
+metadata: {name: nightly, namespace: demo}
+---
+apiVersion: sources.eventing.knative.dev/v1alpha1
+kind: CronJobSource
+metadata: {name: nightly, namespace: demo}
+`
+
+func TestSourcesServedAtSeveralVersions(t *testing.T) {
+	dc := readSnapshot(t, twoVersionsYAML)
+	stop := make(chan struct{})
+	defer close(stop)
+
+	for name, c := range map[string]*Client{
+		"listed":   New(dc),
+		"informed": New(dc, WithSourceInformers(New(dc).SourceInformers("demo", 0, stop))),
+	} {
+		t.Run(name, func(t *testing.T) {
+			sources := c.Sources("demo")
+			got := make(map[string]string)
+			for _, s := range sources {
+				got[s.Name] = s.APIVersion
+			}
+			if len(sources) != 2 || got["cron"] == "" || got["nightly"] == "" {
+				t.Fatalf("sources = %v, want cron and nightly once", got)
+			}
+			for name, v := range got {
+				if v != "sources.eventing.knative.dev/v1alpha2" {
+					t.Errorf("%s is listed at %s, want the first version", name, v)
+				}
+			}
+		})
+	}
+}
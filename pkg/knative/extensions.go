@@ -1,43 +1,57 @@
 package knative
 
 import (
-	"log"
-
 	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
-func (c *Client) SourceCRDs() []apiextensions.CustomResourceDefinition {
-	// kubectl get crd -l "eventing.knative.dev/source=true"
+var crdGVR = schema.GroupVersionResource{
+	Group:    "apiextensions.k8s.io",
+	Version:  "v1beta1",
+	Resource: "customresourcedefinitions",
+}
 
-	gvr := schema.GroupVersionResource{
-		Group:    "apiextensions.k8s.io",
-		Version:  "v1beta1",
-		Resource: "customresourcedefinitions",
-	}
+// sourceCRDSelector selects the CRDs of the sources.
+const sourceCRDSelector = "eventing.knative.dev/source=true"
+
+func (c *Client) SourceCRDs() []apiextensions.CustomResourceDefinition {
+	gvr := crdGVR
 	like := apiextensions.CustomResourceDefinition{}
 
-	list, err := c.dc.Resource(gvr).List(metav1.ListOptions{LabelSelector: "eventing.knative.dev/source=true"})
-	if err != nil {
-		log.Fatalf("Failed to List Triggers, %v", err)
+	list := c.sourceCRDList()
+	if list == nil {
+		return nil
 	}
 
-	all := make([]apiextensions.CustomResourceDefinition, len(list.Items))
+	all := make([]apiextensions.CustomResourceDefinition, 0, len(list.Items))
 
-	for i, item := range list.Items {
+	for _, item := range list.Items {
 		obj := like.DeepCopy()
-		if err = runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, obj); err != nil {
-			log.Fatalf("Error DefaultUnstructuree.Dynamiconverter. %v", err)
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, obj); err != nil {
+			c.failed(gvr, "CustomResourceDefinition", err)
+			continue
 		}
-		obj.ResourceVersion = gvr.Version
 		obj.APIVersion = gvr.GroupVersion().String()
-		all[i] = *obj
+		all = append(all, *obj)
 	}
 	return all
 }
 
+// sourceCRDList lists the CRDs of the sources as they are stored, or returns
+// nil if they cannot be listed.
+func (c *Client) sourceCRDList() *unstructured.UnstructuredList {
+	// kubectl get crd -l "eventing.knative.dev/source=true"
+	list, err := c.dc.Resource(crdGVR).List(metav1.ListOptions{LabelSelector: sourceCRDSelector})
+	if err != nil {
+		c.failed(crdGVR, "CustomResourceDefinition", err)
+		return nil
+	}
+	return list
+}
+
 func crdsToGVR(crds []apiextensions.CustomResourceDefinition) []schema.GroupVersionResource {
 	gvrs := make([]schema.GroupVersionResource, 0)
 	for _, crd := range crds {
@@ -0,0 +1,121 @@
+package knative
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/knative/pkg/apis/duck"
+	duckv1alpha1 "github.com/n3wscott/knap/pkg/apis/duck/v1alpha1"
+	duckv1alpha1listers "github.com/n3wscott/knap/pkg/client/listers/duck/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+)
+
+// SourceInformers is a duck typed informer factory for sources. Whatever
+// their kind, each served version of each source CRD gets one shared
+// informer of the namespace, caching SourceTypes.
+type SourceInformers struct {
+	dc        dynamic.Interface
+	namespace string
+	selector  string
+	resync    time.Duration
+	stop      <-chan struct{}
+
+	mu        sync.Mutex
+	informers map[schema.GroupVersionResource]cache.SharedIndexInformer
+}
+
+// SourceInformers returns the factory of the source informers of the
+// namespace, caching the sources the client would list. The informers run
+// until stop is closed.
+func (c *Client) SourceInformers(namespace string, resync time.Duration, stop <-chan struct{}) *SourceInformers {
+	return &SourceInformers{
+		dc:        c.dc,
+		namespace: namespace,
+		selector:  c.selector,
+		resync:    resync,
+		stop:      stop,
+		informers: make(map[schema.GroupVersionResource]cache.SharedIndexInformer),
+	}
+}
+
+// WithSourceInformers makes the client list the sources of the informers'
+// namespace from their caches.
+func WithSourceInformers(s *SourceInformers) ClientOption {
+	return func(c *Client) {
+		c.informers = s
+	}
+}
+
+// Get returns the informer of the source resource, started and synced, and
+// a lister of its sources. An informer that cannot list the resource is not
+// started, and the error returned.
+func (s *SourceInformers) Get(gvr schema.GroupVersionResource) (cache.SharedIndexInformer, duckv1alpha1listers.SourceTypeNamespaceLister, error) {
+	s.mu.Lock()
+	inf, ok := s.informers[gvr]
+	s.mu.Unlock()
+	if !ok {
+		var err error
+		if inf, err = s.start(gvr); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Wait without the lock, so the informers of other resources can be
+	// used meanwhile.
+	if !cache.WaitForCacheSync(s.stop, inf.HasSynced) {
+		return nil, nil, fmt.Errorf("stopped before the informer of %s synced", gvr.String())
+	}
+	return inf, duckv1alpha1listers.NewSourceTypeLister(inf.GetIndexer()).SourceTypes(s.namespace), nil
+}
+
+// start starts the informer of the source resource, unless another caller
+// already has.
+func (s *SourceInformers) start(gvr schema.GroupVersionResource) (cache.SharedIndexInformer, error) {
+	ri := s.dc.Resource(gvr).Namespace(s.namespace)
+	// An informer retries a failing list until stopped, so fail here instead
+	// of waiting forever for it to sync.
+	if _, err := ri.List(metav1.ListOptions{LabelSelector: s.selector, Limit: 1}); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if inf, ok := s.informers[gvr]; ok {
+		return inf, nil
+	}
+	lw := &cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			opts.LabelSelector = s.selector
+			return listSources(ri, opts)
+		},
+		WatchFunc: duck.AsStructuredWatcher(func(opts metav1.ListOptions) (watch.Interface, error) {
+			opts.LabelSelector = s.selector
+			return ri.Watch(opts)
+		}, &duckv1alpha1.SourceType{}),
+	}
+	inf := cache.NewSharedIndexInformer(lw, &duckv1alpha1.SourceType{}, s.resync, cache.Indexers{
+		cache.NamespaceIndex: cache.MetaNamespaceIndexFunc,
+	})
+	go inf.Run(s.stop)
+	s.informers[gvr] = inf
+	return inf, nil
+}
+
+// listSources lists the sources of the resource as SourceTypes.
+func listSources(ri dynamic.ResourceInterface, opts metav1.ListOptions) (*duckv1alpha1.SourceTypeList, error) {
+	ul, err := ri.List(opts)
+	if err != nil {
+		return nil, err
+	}
+	list := &duckv1alpha1.SourceTypeList{}
+	if err := duck.FromUnstructured(ul, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
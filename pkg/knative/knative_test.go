@@ -0,0 +1,19 @@
+package knative
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/dynamic"
+)
+
+// readSnapshot returns a client over the objects of the YAML, a stream of
+// objects separated by "---".
+func readSnapshot(t *testing.T, objs string) dynamic.Interface {
+	t.Helper()
+	list, err := ReadSnapshot(strings.NewReader(objs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewSnapshot(list)
+}
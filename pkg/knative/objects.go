@@ -0,0 +1,43 @@
+package knative
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Objects lists every object of the topology in the namespace as it is
+// stored. Kinds the authorizer denies are skipped.
+func (c *Client) Objects(namespace string) []unstructured.Unstructured {
+	var objs []unstructured.Unstructured
+	seen := make(map[string]bool) // sources are served at several versions
+	for _, r := range c.TopologyResources() {
+		if !c.allowed(namespace, r.GroupVersionResource, r.Kind) {
+			continue
+		}
+		list, err := c.dc.Resource(r.GroupVersionResource).Namespace(namespace).List(c.listOptions())
+		if err != nil {
+			c.failed(r.GroupVersionResource, r.Kind, err)
+			continue
+		}
+		for _, item := range list.Items {
+			id := objectID(&item, item.GroupVersionKind().GroupKind())
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			objs = append(objs, item)
+		}
+	}
+	return objs
+}
+
+// objectID tells the object apart from the others, whatever version it was
+// read at: by its UID or, for objects without one such as those of a hand
+// written snapshot, by its group, kind, namespace and name.
+func objectID(obj metav1.Object, gk schema.GroupKind) string {
+	if uid := obj.GetUID(); uid != "" {
+		return string(uid)
+	}
+	return gk.String() + "/" + obj.GetNamespace() + "/" + obj.GetName()
+}
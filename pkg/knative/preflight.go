@@ -0,0 +1,114 @@
+package knative
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+var selfSubjectAccessReviews = schema.GroupVersionResource{
+	Group:    "authorization.k8s.io",
+	Version:  "v1",
+	Resource: "selfsubjectaccessreviews",
+}
+
+// crds is the resource SourceCRDs lists, cluster wide.
+var crds = Resource{
+	GroupVersionResource: schema.GroupVersionResource{
+		Group:    "apiextensions.k8s.io",
+		Version:  "v1beta1",
+		Resource: "customresourcedefinitions",
+	},
+	Kind: "CustomResourceDefinition",
+}
+
+// SelfAuthorizer returns an Authorizer that asks, with
+// SelfSubjectAccessReviews, whether the client's own credentials may list a
+// resource. Answers are remembered for ttl.
+func SelfAuthorizer(dc dynamic.Interface, ttl time.Duration) Authorizer {
+	type answer struct {
+		allowed bool
+		at      time.Time
+	}
+	var mu sync.Mutex
+	answers := make(map[string]answer)
+
+	return func(namespace string, gvr schema.GroupVersionResource) bool {
+		key := namespace + "/" + gvr.String()
+
+		mu.Lock()
+		a, ok := answers[key]
+		mu.Unlock()
+		if ok && time.Since(a.at) < ttl {
+			return a.allowed
+		}
+
+		allowed, err := canI(dc, namespace, gvr)
+		if err != nil {
+			// Try anyway, a forbidden list is reported when it fails.
+			log.Printf("SelfSubjectAccessReview for %s failed, %v", gvr.String(), err)
+			return true
+		}
+
+		mu.Lock()
+		answers[key] = answer{allowed: allowed, at: time.Now()}
+		mu.Unlock()
+		return allowed
+	}
+}
+
+// canI runs a SelfSubjectAccessReview for listing gvr in the namespace.
+func canI(dc dynamic.Interface, namespace string, gvr schema.GroupVersionResource) (bool, error) {
+	review := &authorizationv1.SelfSubjectAccessReview{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: selfSubjectAccessReviews.GroupVersion().String(),
+			Kind:       "SelfSubjectAccessReview",
+		},
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      "list",
+				Group:     gvr.Group,
+				Version:   gvr.Version,
+				Resource:  gvr.Resource,
+			},
+		},
+	}
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(review)
+	if err != nil {
+		return false, err
+	}
+	res, err := dc.Resource(selfSubjectAccessReviews).Create(&unstructured.Unstructured{Object: obj}, metav1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(res.Object, review); err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
+
+// Preflight checks that the client's own credentials may list every kind of
+// the topology in the namespace, and the source CRDs, returning the ones
+// that are denied.
+func (c *Client) Preflight(namespace string) []Resource {
+	var denied []Resource
+	if ok, err := canI(c.dc, "", crds.GroupVersionResource); err == nil && !ok {
+		denied = append(denied, crds)
+	}
+	for _, r := range c.TopologyResources() {
+		if ok, err := canI(c.dc, namespace, r.GroupVersionResource); err != nil {
+			log.Printf("SelfSubjectAccessReview for %s failed, %v", r.String(), err)
+		} else if !ok {
+			denied = append(denied, r)
+		}
+	}
+	return denied
+}
@@ -0,0 +1,103 @@
+package knative
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// reviewer answers SelfSubjectAccessReviews, allowing the resources it
+// lists, and serves everything else from the snapshot.
+type reviewer struct {
+	dynamic.Interface
+	allowed map[string]bool // resource.group
+	reviews int
+}
+
+func (r *reviewer) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	ri := r.Interface.Resource(gvr)
+	if gvr != selfSubjectAccessReviews {
+		return ri
+	}
+	return &reviews{NamespaceableResourceInterface: ri, r: r}
+}
+
+type reviews struct {
+	dynamic.NamespaceableResourceInterface
+	r *reviewer
+}
+
+func (rs *reviews) Create(obj *unstructured.Unstructured, options metav1.CreateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	rs.r.reviews++
+	group, _, _ := unstructured.NestedString(obj.Object, "spec", "resourceAttributes", "group")
+	resource, _, _ := unstructured.NestedString(obj.Object, "spec", "resourceAttributes", "resource")
+	gr := schema.GroupResource{Group: group, Resource: resource}
+
+	res := obj.DeepCopy()
+	if err := unstructured.SetNestedField(res.Object, rs.r.allowed[gr.String()], "status", "allowed"); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func TestPreflight(t *testing.T) {
+	dc := &reviewer{
+		Interface: readSnapshot(t, twoVersionsYAML),
+		allowed: map[string]bool{
+			"brokers.eventing.knative.dev":       true,
+			"channels.eventing.knative.dev":      true,
+			"subscriptions.eventing.knative.dev": true,
+			"eventtypes.eventing.knative.dev":    true,
+			"services.serving.knative.dev":       true,
+		},
+	}
+
+	var denied []string
+	for _, r := range New(dc).Preflight("demo") {
+		denied = append(denied, r.GroupVersionResource.String()+" "+r.Kind)
+	}
+	want := []string{
+		"apiextensions.k8s.io/v1beta1, Resource=customresourcedefinitions CustomResourceDefinition",
+		"eventing.knative.dev/v1alpha1, Resource=triggers Trigger",
+		"sources.eventing.knative.dev/v1alpha2, Resource=cronjobsources CronJobSource",
+		"sources.eventing.knative.dev/v1alpha1, Resource=cronjobsources CronJobSource",
+	}
+	if !reflect.DeepEqual(denied, want) {
+		t.Errorf("denied = %q, want %q", denied, want)
+	}
+}
+
+func TestPreflightReviewsFail(t *testing.T) {
+	// A snapshot is read only, so every review fails. A kind is only
+	// reported when a review denies it.
+	if denied := New(readSnapshot(t, twoVersionsYAML)).Preflight("demo"); len(denied) != 0 {
+		t.Errorf("denied = %v, want none", denied)
+	}
+}
+
+func TestSelfAuthorizer(t *testing.T) {
+	dc := &reviewer{
+		Interface: readSnapshot(t, ""),
+		allowed:   map[string]bool{"brokers.eventing.knative.dev": true},
+	}
+	brokers := schema.GroupVersionResource{Group: "eventing.knative.dev", Version: "v1alpha1", Resource: "brokers"}
+	triggers := schema.GroupVersionResource{Group: "eventing.knative.dev", Version: "v1alpha1", Resource: "triggers"}
+
+	allowed := SelfAuthorizer(dc, time.Minute)
+	for i := 0; i < 3; i++ {
+		if !allowed("demo", brokers) {
+			t.Error("brokers are denied")
+		}
+		if allowed("demo", triggers) {
+			t.Error("triggers are allowed")
+		}
+	}
+	if dc.reviews != 2 {
+		t.Errorf("%d reviews, want one a resource", dc.reviews)
+	}
+}
@@ -1,10 +1,7 @@
 package knative
 
 import (
-	"log"
-
 	servingv1alpha1 "github.com/knative/serving/pkg/apis/serving/v1alpha1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
@@ -17,21 +14,26 @@ func (c *Client) KnServices(namespace string) []servingv1alpha1.Service {
 	}
 	like := servingv1alpha1.Service{}
 
-	list, err := c.dc.Resource(gvr).Namespace(namespace).List(metav1.ListOptions{})
+	if !c.allowed(namespace, gvr, "Service") {
+		return nil
+	}
+
+	list, err := c.dc.Resource(gvr).Namespace(namespace).List(c.listOptions())
 	if err != nil {
-		log.Fatalf("Failed to List Services, %v", err)
+		c.failed(gvr, "Service", err)
+		return nil
 	}
 
-	all := make([]servingv1alpha1.Service, len(list.Items))
+	all := make([]servingv1alpha1.Service, 0, len(list.Items))
 
-	for i, item := range list.Items {
+	for _, item := range list.Items {
 		obj := like.DeepCopy()
 		if err = runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, obj); err != nil {
-			log.Fatalf("Error DefaultUnstructuree.Dynamiconverter. %v", err)
+			c.failed(gvr, "Service", err)
+			continue
 		}
-		obj.ResourceVersion = gvr.Version
 		obj.APIVersion = gvr.GroupVersion().String()
-		all[i] = *obj
+		all = append(all, *obj)
 	}
 	return all
 }
@@ -0,0 +1,197 @@
+package knative
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+var errReadOnly = errors.New("a snapshot is read only")
+
+// Snapshot lists every object of the topology in the namespace as it is
+// stored, status included, with the source CRDs that say which kinds are
+// sources. NewSnapshot serves it again.
+func (c *Client) Snapshot(namespace string) []unstructured.Unstructured {
+	var objs []unstructured.Unstructured
+	if list := c.sourceCRDList(); list != nil {
+		objs = append(objs, list.Items...)
+	}
+	return append(objs, c.Objects(namespace)...)
+}
+
+// ReadSnapshot reads objects from YAML or JSON: a List, or a stream of
+// objects and Lists separated by "---".
+func ReadSnapshot(r io.Reader) ([]unstructured.Unstructured, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var objs []unstructured.Unstructured
+	for i, doc := range splitYAML(b) {
+		var m map[string]interface{}
+		if err := yaml.Unmarshal(doc, &m); err != nil {
+			return nil, fmt.Errorf("document %d: %v", i+1, err)
+		}
+		if len(m) == 0 {
+			continue
+		}
+		u := unstructured.Unstructured{Object: m}
+		if !u.IsList() {
+			objs = append(objs, u)
+			continue
+		}
+		if err := u.EachListItem(func(o runtime.Object) error {
+			objs = append(objs, *o.(*unstructured.Unstructured))
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("document %d: %v", i+1, err)
+		}
+	}
+	return objs, nil
+}
+
+// splitYAML splits a stream at lines that are "---".
+func splitYAML(b []byte) [][]byte {
+	var docs [][]byte
+	var doc []string
+	for _, line := range strings.Split(string(b), "\n") {
+		if strings.TrimRight(line, " \t\r") == "---" {
+			docs = append(docs, []byte(strings.Join(doc, "\n")))
+			doc = nil
+			continue
+		}
+		doc = append(doc, line)
+	}
+	return append(docs, []byte(strings.Join(doc, "\n")))
+}
+
+// NewSnapshot returns a read only dynamic client over the objects, so a
+// topology can be loaded without a cluster. Resources are matched to kinds
+// with the CRDs among the objects, the eventing and serving kinds, and
+// otherwise by pluralizing the kind.
+func NewSnapshot(objs []unstructured.Unstructured) dynamic.Interface {
+	s := &snapshot{objs: objs, kinds: make(map[schema.GroupVersionResource]string)}
+	for _, r := range staticResources() {
+		s.kinds[r.GroupVersionResource] = r.Kind
+	}
+	for _, o := range objs {
+		if o.GetKind() != "CustomResourceDefinition" {
+			continue
+		}
+		group, _, _ := unstructured.NestedString(o.Object, "spec", "group")
+		plural, _, _ := unstructured.NestedString(o.Object, "spec", "names", "plural")
+		kind, _, _ := unstructured.NestedString(o.Object, "spec", "names", "kind")
+		versions, _, _ := unstructured.NestedSlice(o.Object, "spec", "versions")
+		for _, v := range versions {
+			if name, ok := v.(map[string]interface{})["name"].(string); ok {
+				s.kinds[schema.GroupVersionResource{Group: group, Version: name, Resource: plural}] = kind
+			}
+		}
+		if version, ok, _ := unstructured.NestedString(o.Object, "spec", "version"); ok {
+			s.kinds[schema.GroupVersionResource{Group: group, Version: version, Resource: plural}] = kind
+		}
+	}
+	return s
+}
+
+type snapshot struct {
+	objs  []unstructured.Unstructured
+	kinds map[schema.GroupVersionResource]string // kind of each resource
+}
+
+func (s *snapshot) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return &snapshotResource{s: s, gvr: gvr}
+}
+
+type snapshotResource struct {
+	s         *snapshot
+	gvr       schema.GroupVersionResource
+	namespace string
+}
+
+func (r *snapshotResource) Namespace(ns string) dynamic.ResourceInterface {
+	return &snapshotResource{s: r.s, gvr: r.gvr, namespace: ns}
+}
+
+// matches reports whether o is an object of the resource in the namespace.
+func (r *snapshotResource) matches(o *unstructured.Unstructured) bool {
+	if o.GetAPIVersion() != r.gvr.GroupVersion().String() {
+		return false
+	}
+	if r.namespace != "" && o.GetNamespace() != r.namespace {
+		return false
+	}
+	if kind, ok := r.s.kinds[r.gvr]; ok {
+		return o.GetKind() == kind
+	}
+	return strings.ToLower(o.GetKind())+"s" == r.gvr.Resource
+}
+
+func (r *snapshotResource) List(opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	selector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+	list := &unstructured.UnstructuredList{Object: map[string]interface{}{
+		"apiVersion": r.gvr.GroupVersion().String(),
+		"kind":       "List",
+	}}
+	for i := range r.s.objs {
+		o := &r.s.objs[i]
+		if r.matches(o) && selector.Matches(labels.Set(o.GetLabels())) {
+			list.Items = append(list.Items, *o.DeepCopy())
+		}
+	}
+	return list, nil
+}
+
+func (r *snapshotResource) Get(name string, options metav1.GetOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	for i := range r.s.objs {
+		o := &r.s.objs[i]
+		if r.matches(o) && o.GetName() == name {
+			return o.DeepCopy(), nil
+		}
+	}
+	return nil, fmt.Errorf("%s %q not found in the snapshot", r.gvr.Resource, name)
+}
+
+// Watch never sends anything, a snapshot does not change.
+func (r *snapshotResource) Watch(opts metav1.ListOptions) (watch.Interface, error) {
+	return watch.NewFake(), nil
+}
+
+func (r *snapshotResource) Create(obj *unstructured.Unstructured, options metav1.CreateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	return nil, errReadOnly
+}
+
+func (r *snapshotResource) Update(obj *unstructured.Unstructured, options metav1.UpdateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	return nil, errReadOnly
+}
+
+func (r *snapshotResource) UpdateStatus(obj *unstructured.Unstructured, options metav1.UpdateOptions) (*unstructured.Unstructured, error) {
+	return nil, errReadOnly
+}
+
+func (r *snapshotResource) Delete(name string, options *metav1.DeleteOptions, subresources ...string) error {
+	return errReadOnly
+}
+
+func (r *snapshotResource) DeleteCollection(options *metav1.DeleteOptions, listOptions metav1.ListOptions) error {
+	return errReadOnly
+}
+
+func (r *snapshotResource) Patch(name string, pt types.PatchType, data []byte, options metav1.UpdateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	return nil, errReadOnly
+}
@@ -0,0 +1,327 @@
+package knative
+
+import (
+	"log"
+	"time"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+// retryInterval is how long to wait before retrying a failed list or watch.
+const retryInterval = 5 * time.Second
+
+// TopologyResources returns the kinds that make up the eventing topology: the
+// eventing and serving kinds plus every served version of the source CRDs.
+func (c *Client) TopologyResources() []Resource {
+	resources := staticResources()
+	for _, crd := range c.SourceCRDs() {
+		for _, gvr := range crdsToGVR([]apiextensions.CustomResourceDefinition{crd}) {
+			resources = append(resources, Resource{GroupVersionResource: gvr, Kind: crd.Spec.Names.Kind})
+		}
+	}
+	return resources
+}
+
+// staticResources returns the eventing and serving kinds of the topology.
+func staticResources() []Resource {
+	return []Resource{
+		{GroupVersionResource: schema.GroupVersionResource{Group: "eventing.knative.dev", Version: "v1alpha1", Resource: "brokers"}, Kind: "Broker"},
+		{GroupVersionResource: schema.GroupVersionResource{Group: "eventing.knative.dev", Version: "v1alpha1", Resource: "triggers"}, Kind: "Trigger"},
+		{GroupVersionResource: schema.GroupVersionResource{Group: "eventing.knative.dev", Version: "v1alpha1", Resource: "channels"}, Kind: "Channel"},
+		{GroupVersionResource: schema.GroupVersionResource{Group: "eventing.knative.dev", Version: "v1alpha1", Resource: "subscriptions"}, Kind: "Subscription"},
+		{GroupVersionResource: schema.GroupVersionResource{Group: "eventing.knative.dev", Version: "v1alpha1", Resource: "eventtypes"}, Kind: "EventType"},
+		{GroupVersionResource: schema.GroupVersionResource{Group: "serving.knative.dev", Version: "v1alpha1", Resource: "services"}, Kind: "Service"},
+	}
+}
+
+// TopologyGVRs returns the resources of TopologyResources.
+func (c *Client) TopologyGVRs() []schema.GroupVersionResource {
+	var gvrs []schema.GroupVersionResource
+	for _, r := range c.TopologyResources() {
+		gvrs = append(gvrs, r.GroupVersionResource)
+	}
+	return gvrs
+}
+
+// Change is an object that was added, modified or deleted.
+type Change struct {
+	GVR    schema.GroupVersionResource
+	Object *unstructured.Unstructured
+}
+
+// Watch sends every object of the topology in the namespace that is added,
+// modified or deleted, until stop is closed. Objects that exist when Watch is
+// called are not sent, and kinds the authorizer denies are not watched.
+// Source CRDs installed later are watched as well, sending the sources that
+// already exist. When a watch falls behind, the resource is listed again and
+// the objects that changed in the meantime are sent, deleted ones as they
+// were last seen.
+func (c *Client) Watch(namespace string, stop <-chan struct{}) <-chan Change {
+	changes := make(chan Change)
+	sources := make(map[schema.GroupVersionResource]chan struct{})
+	for _, r := range staticResources() {
+		if c.allowed(namespace, r.GroupVersionResource, r.Kind) {
+			go c.watchResource(r.GroupVersionResource, namespace, false, changes, stop, stop)
+		}
+	}
+	crds := c.sourceCRDList()
+	for _, r := range c.sourceResources(crds) {
+		if c.allowed(namespace, r.GroupVersionResource, r.Kind) {
+			done := make(chan struct{})
+			sources[r.GroupVersionResource] = done
+			go c.watchResource(r.GroupVersionResource, namespace, false, changes, done, stop)
+		}
+	}
+	if crds != nil {
+		go c.watchSourceCRDs(namespace, crds, sources, changes, stop)
+	}
+	return changes
+}
+
+// sourceResources returns every served version of the listed source CRDs.
+func (c *Client) sourceResources(list *unstructured.UnstructuredList) []Resource {
+	if list == nil {
+		return nil
+	}
+	var resources []Resource
+	for _, item := range list.Items {
+		crd := apiextensions.CustomResourceDefinition{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, &crd); err != nil {
+			log.Printf("Failed to convert CustomResourceDefinition %s, %v", item.GetName(), err)
+			continue
+		}
+		for _, gvr := range crdsToGVR([]apiextensions.CustomResourceDefinition{crd}) {
+			resources = append(resources, Resource{GroupVersionResource: gvr, Kind: crd.Spec.Names.Kind})
+		}
+	}
+	return resources
+}
+
+// watchSourceCRDs starts watching the sources of CRDs installed after Watch
+// was called, and stops watching those of CRDs that are removed. sources holds
+// the resources watched, closing its channel stops the watch.
+func (c *Client) watchSourceCRDs(namespace string, list *unstructured.UnstructuredList, sources map[schema.GroupVersionResource]chan struct{}, changes chan<- Change, stop <-chan struct{}) {
+	crds := make(map[string]*unstructured.Unstructured)
+	for i := range list.Items {
+		crds[list.Items[i].GetName()] = &list.Items[i]
+	}
+	update := func() {
+		current := &unstructured.UnstructuredList{}
+		for _, crd := range crds {
+			current.Items = append(current.Items, *crd)
+		}
+		served := make(map[schema.GroupVersionResource]bool)
+		for _, r := range c.sourceResources(current) {
+			served[r.GroupVersionResource] = true
+			if _, ok := sources[r.GroupVersionResource]; ok || !c.allowed(namespace, r.GroupVersionResource, r.Kind) {
+				continue
+			}
+			done := make(chan struct{})
+			sources[r.GroupVersionResource] = done
+			go c.watchResource(r.GroupVersionResource, namespace, true, changes, done, stop)
+		}
+		for gvr, done := range sources {
+			if !served[gvr] {
+				close(done)
+				delete(sources, gvr)
+			}
+		}
+	}
+
+	ri := c.dc.Resource(crdGVR)
+	c.follow(ri, crdGVR, list.GetResourceVersion(), crds, func(crd *unstructured.Unstructured, deleted bool) bool {
+		if deleted {
+			delete(crds, crd.GetName())
+		} else {
+			crds[crd.GetName()] = crd
+		}
+		update()
+		return true
+	}, stop)
+}
+
+// watchResource sends the changes of one resource until done or stop is
+// closed. With initial, the objects that exist when it starts are sent too.
+// Closing done sends the objects known at the time as deleted: their CRD was
+// removed.
+func (c *Client) watchResource(gvr schema.GroupVersionResource, namespace string, initial bool, changes chan<- Change, done, stop <-chan struct{}) {
+	ri := c.dc.Resource(gvr).Namespace(namespace)
+	send := func(obj *unstructured.Unstructured, _ bool) bool {
+		select {
+		case changes <- Change{GVR: gvr, Object: obj}:
+			return true
+		case <-stop:
+			return false
+		}
+	}
+
+	known := make(map[string]*unstructured.Unstructured)
+	rv := ""
+	if !initial {
+		list, ok := c.list(ri, gvr, done)
+		if !ok {
+			return
+		}
+		rv = list.GetResourceVersion()
+		relist(list, known, nil)
+	}
+	c.follow(ri, gvr, rv, known, send, done)
+
+	select {
+	case <-stop:
+	default:
+		for _, obj := range known {
+			if !send(obj, true) {
+				return
+			}
+		}
+	}
+}
+
+// list lists the resource, retrying until it succeeds or stop is closed.
+func (c *Client) list(ri dynamic.ResourceInterface, gvr schema.GroupVersionResource, stop <-chan struct{}) (*unstructured.UnstructuredList, bool) {
+	for {
+		list, err := ri.List(c.watchOptions(gvr))
+		if err == nil {
+			return list, true
+		}
+		log.Printf("Failed to List %s, %v", gvr.String(), err)
+		if !sleep(stop) {
+			return nil, false
+		}
+	}
+}
+
+// watchOptions returns the options to list and watch the resource with.
+func (c *Client) watchOptions(gvr schema.GroupVersionResource) metav1.ListOptions {
+	if gvr == crdGVR {
+		return metav1.ListOptions{LabelSelector: sourceCRDSelector}
+	}
+	return metav1.ListOptions{}
+}
+
+// follow watches the resource from the resource version rv, resuming from
+// the last seen version when the server closes the watch. Without a version,
+// or when it is gone, the resource is listed and the difference to the known
+// objects sent. known holds the objects by namespace and name. follow
+// returns when stop is closed or send returns false.
+func (c *Client) follow(ri dynamic.ResourceInterface, gvr schema.GroupVersionResource, rv string, known map[string]*unstructured.Unstructured, send func(obj *unstructured.Unstructured, deleted bool) bool, stop <-chan struct{}) {
+	for {
+		if rv == "" {
+			list, ok := c.list(ri, gvr, stop)
+			if !ok {
+				return
+			}
+			rv = list.GetResourceVersion()
+			if !relist(list, known, send) {
+				return
+			}
+		}
+
+		opts := c.watchOptions(gvr)
+		opts.ResourceVersion = rv
+		w, err := ri.Watch(opts)
+		if err != nil {
+			log.Printf("Failed to Watch %s, %v", gvr.String(), err)
+			rv = ""
+			if !sleep(stop) {
+				return
+			}
+			continue
+		}
+
+		var ok bool
+		if rv, ok = drain(w, rv, known, send, stop); !ok {
+			return
+		}
+		select {
+		case <-stop:
+			return
+		default:
+		}
+	}
+}
+
+// relist replaces the known objects with the listed ones, sending those
+// that were added, modified or deleted since, unless send is nil. It returns
+// false if send did.
+func relist(list *unstructured.UnstructuredList, known map[string]*unstructured.Unstructured, send func(obj *unstructured.Unstructured, deleted bool) bool) bool {
+	listed := make(map[string]bool, len(list.Items))
+	for i := range list.Items {
+		obj := &list.Items[i]
+		key := objectKey(obj)
+		listed[key] = true
+		prev, ok := known[key]
+		known[key] = obj
+		if send != nil && (!ok || prev.GetResourceVersion() != obj.GetResourceVersion()) {
+			if !send(obj, false) {
+				return false
+			}
+		}
+	}
+	for key, obj := range known {
+		if listed[key] {
+			continue
+		}
+		delete(known, key)
+		if send != nil && !send(obj, true) {
+			return false
+		}
+	}
+	return true
+}
+
+// drain forwards the events of w until it closes or stop is closed, and
+// returns the resource version to resume from, empty if the resource must be
+// listed again. It returns false if stopped.
+func drain(w watch.Interface, rv string, known map[string]*unstructured.Unstructured, send func(obj *unstructured.Unstructured, deleted bool) bool, stop <-chan struct{}) (string, bool) {
+	defer w.Stop()
+	for {
+		select {
+		case <-stop:
+			return rv, false
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return rv, true
+			}
+			if event.Type == watch.Error {
+				// Most likely the resource version is too old.
+				return "", true
+			}
+			obj, ok := event.Object.(*unstructured.Unstructured)
+			if !ok {
+				continue
+			}
+			rv = obj.GetResourceVersion()
+			deleted := event.Type == watch.Deleted
+			if deleted {
+				delete(known, objectKey(obj))
+			} else {
+				known[objectKey(obj)] = obj
+			}
+			if !send(obj, deleted) {
+				return rv, false
+			}
+		}
+	}
+}
+
+func objectKey(obj *unstructured.Unstructured) string {
+	return obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// sleep waits for the retry interval, returning false if stop was closed.
+func sleep(stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return false
+	case <-time.After(retryInterval):
+		return true
+	}
+}
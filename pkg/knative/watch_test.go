@@ -0,0 +1,157 @@
+package knative
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+var brokers = schema.GroupVersionResource{Group: "eventing.knative.dev", Version: "v1alpha1", Resource: "brokers"}
+
+// scripted serves the brokers of the current snapshot, and hands every
+// watch of them to the test to drive. Other resources are served from the
+// first snapshot, and their watches never send.
+type scripted struct {
+	dynamic.Interface
+	watches chan watchCall
+
+	mu      sync.Mutex
+	current dynamic.Interface
+	rv      string // resource version of lists
+}
+
+type watchCall struct {
+	rv string
+	w  *watch.FakeWatcher
+}
+
+func newScripted(t *testing.T, objs, rv string) *scripted {
+	dc := readSnapshot(t, objs)
+	return &scripted{Interface: dc, current: dc, rv: rv, watches: make(chan watchCall)}
+}
+
+// set makes later lists return the objects, at the resource version.
+func (s *scripted) set(t *testing.T, objs, rv string) {
+	dc := readSnapshot(t, objs)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current, s.rv = dc, rv
+}
+
+func (s *scripted) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	if gvr != brokers {
+		return s.Interface.Resource(gvr)
+	}
+	return &scriptedResource{NamespaceableResourceInterface: s.Interface.Resource(gvr), s: s}
+}
+
+type scriptedResource struct {
+	dynamic.NamespaceableResourceInterface
+	s         *scripted
+	namespace string
+}
+
+func (r *scriptedResource) Namespace(ns string) dynamic.ResourceInterface {
+	return &scriptedResource{NamespaceableResourceInterface: r.NamespaceableResourceInterface, s: r.s, namespace: ns}
+}
+
+func (r *scriptedResource) List(opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	r.s.mu.Lock()
+	current, rv := r.s.current, r.s.rv
+	r.s.mu.Unlock()
+	list, err := current.Resource(brokers).Namespace(r.namespace).List(opts)
+	if err != nil {
+		return nil, err
+	}
+	list.SetResourceVersion(rv)
+	return list, nil
+}
+
+func (r *scriptedResource) Watch(opts metav1.ListOptions) (watch.Interface, error) {
+	w := watch.NewFake()
+	r.s.watches <- watchCall{rv: opts.ResourceVersion, w: w}
+	return w, nil
+}
+
+func broker(name, rv string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion("eventing.knative.dev/v1alpha1")
+	u.SetKind("Broker")
+	u.SetNamespace("demo")
+	u.SetName(name)
+	u.SetResourceVersion(rv)
+	return u
+}
+
+func TestWatchRelistsAfterGap(t *testing.T) {
+	dc := newScripted(t, `
+apiVersion: eventing.knative.dev/v1alpha1
+kind: Broker
+metadata: {name: a, namespace: demo, resourceVersion: "1"}
+---
+apiVersion: eventing.knative.dev/v1alpha1
+kind: Broker
+metadata: {name: b, namespace: demo, resourceVersion: "1"}
+`, "1")
+	stop := make(chan struct{})
+	defer close(stop)
+	changes := New(dc).Watch("demo", stop)
+
+	next := func() watchCall {
+		t.Helper()
+		select {
+		case call := <-dc.watches:
+			return call
+		case <-time.After(5 * time.Second):
+			t.Fatal("brokers were not watched")
+		}
+		return watchCall{}
+	}
+	received := func() string {
+		t.Helper()
+		select {
+		case c := <-changes:
+			return c.Object.GetName() + "@" + c.Object.GetResourceVersion()
+		case <-time.After(5 * time.Second):
+			t.Fatal("no change was sent")
+		}
+		return ""
+	}
+
+	call := next()
+	if call.rv != "1" {
+		t.Errorf("watched from %q, want the listed version 1", call.rv)
+	}
+	call.w.Modify(broker("a", "2"))
+	if got := received(); got != "a@2" {
+		t.Errorf("change = %s, want a@2", got)
+	}
+
+	// While the watch is gone, b is deleted and c created. a is unchanged
+	// since it was last sent, so it is not sent again.
+	dc.set(t, `
+apiVersion: eventing.knative.dev/v1alpha1
+kind: Broker
+metadata: {name: a, namespace: demo, resourceVersion: "2"}
+---
+apiVersion: eventing.knative.dev/v1alpha1
+kind: Broker
+metadata: {name: c, namespace: demo, resourceVersion: "3"}
+`, "3")
+	call.w.Error(&metav1.Status{Status: metav1.StatusFailure, Code: 410, Reason: metav1.StatusReasonGone})
+
+	for _, want := range []string{"c@3", "b@1"} {
+		if got := received(); got != want {
+			t.Errorf("change = %s, want %s", got, want)
+		}
+	}
+	if call := next(); call.rv != "3" {
+		t.Errorf("watched again from %q, want the relisted version 3", call.rv)
+	}
+}
@@ -0,0 +1,186 @@
+package layout
+
+import (
+	"strings"
+)
+
+// Point is a position in the drawing, in points, with the origin at the top
+// left.
+type Point struct {
+	X, Y float64
+}
+
+// Rect is an axis aligned box, with its top left corner at X, Y.
+type Rect struct {
+	X, Y, Width, Height float64
+}
+
+// Graph is a parsed DOT graph. After Layout, every node, edge and cluster has
+// its position set.
+type Graph struct {
+	Name     string
+	Directed bool
+	Attrs    map[string]string
+
+	Nodes    []*Node
+	Edges    []*Edge
+	Clusters []*Cluster // top level clusters
+
+	// Bounds of the whole drawing, set by Layout.
+	Bounds Rect
+	// LabelPos is the center of the graph label, set by Layout.
+	LabelPos Point
+
+	nodes map[string]*Node
+}
+
+// Node is a vertex of the graph.
+type Node struct {
+	ID      string
+	Attrs   map[string]string
+	Cluster *Cluster // innermost cluster, or nil
+
+	// Center of the node and its size, set by Layout.
+	Pos           Point
+	Width, Height float64
+
+	rank    int
+	order   int
+	breadth float64
+	depth   float64
+	in, out []*segment
+}
+
+// Edge connects two nodes.
+type Edge struct {
+	Tail, Head *Node
+	Attrs      map[string]string
+
+	// Points is a cubic B-spline, as 1+3n control points from Tail to Head,
+	// set by Layout.
+	Points []Point
+	// LabelPos is the center of the edge label, set by Layout.
+	LabelPos Point
+
+	reversed bool
+	chain    []*Node // tail, dummies and head, in ranking direction
+}
+
+// Cluster is a subgraph whose name starts with "cluster", drawn as a box
+// around its nodes.
+type Cluster struct {
+	ID       string
+	Attrs    map[string]string
+	Parent   *Cluster
+	Clusters []*Cluster
+	Nodes    []*Node
+
+	// Bounds of the cluster box, set by Layout.
+	Bounds Rect
+
+	minRank, maxRank int
+	key              float64
+}
+
+// Attr returns the attribute value, or "" if it is not set.
+func (g *Graph) Attr(name string) string {
+	return g.Attrs[name]
+}
+
+// Attr returns the attribute value, or "" if it is not set.
+func (n *Node) Attr(name string) string {
+	return n.Attrs[name]
+}
+
+// Attr returns the attribute value, or "" if it is not set.
+func (e *Edge) Attr(name string) string {
+	return e.Attrs[name]
+}
+
+// Attr returns the attribute value, or "" if it is not set.
+func (c *Cluster) Attr(name string) string {
+	return c.Attrs[name]
+}
+
+// Label returns the lines of the node label, defaulting to the node ID.
+func (n *Node) Label() []string {
+	if l, ok := n.Attrs["label"]; ok {
+		return Lines(strings.Replace(l, `\N`, n.ID, -1))
+	}
+	return Lines(n.ID)
+}
+
+// Shape returns the node shape, defaulting to an ellipse.
+func (n *Node) Shape() string {
+	switch s := n.Attrs["shape"]; s {
+	case "":
+		return "ellipse"
+	case "oval":
+		return "ellipse"
+	case "rect", "rectangle", "square":
+		return "box"
+	default:
+		return s
+	}
+}
+
+// Label returns the lines of the edge label.
+func (e *Edge) Label() []string {
+	return Lines(e.Attrs["label"])
+}
+
+// Label returns the lines of the cluster label.
+func (c *Cluster) Label() []string {
+	return Lines(c.Attrs["label"])
+}
+
+// Label returns the lines of the graph label.
+func (g *Graph) Label() []string {
+	return Lines(g.Attrs["label"])
+}
+
+// Node returns the node with the given ID, or nil.
+func (g *Graph) Node(id string) *Node {
+	return g.nodes[id]
+}
+
+// Lines splits a DOT label on its line escapes and real newlines.
+func Lines(label string) []string {
+	if label == "" {
+		return nil
+	}
+	r := strings.NewReplacer(`\n`, "\n", `\l`, "\n", `\r`, "\n", `\\`, `\`)
+	label = strings.TrimSuffix(r.Replace(label), "\n")
+	return strings.Split(label, "\n")
+}
+
+// ancestors returns the clusters containing n, innermost first.
+func (n *Node) ancestors() []*Cluster {
+	var cs []*Cluster
+	for c := n.Cluster; c != nil; c = c.Parent {
+		cs = append(cs, c)
+	}
+	return cs
+}
+
+// contains reports whether n is inside c or one of its descendants.
+func (c *Cluster) contains(n *Node) bool {
+	for a := n.Cluster; a != nil; a = a.Parent {
+		if a == c {
+			return true
+		}
+	}
+	return false
+}
+
+// commonCluster returns the innermost cluster containing both a and b.
+func commonCluster(a, b *Cluster) *Cluster {
+	for x := a; x != nil; x = x.Parent {
+		for y := b; y != nil; y = y.Parent {
+			if x == y {
+				return x
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,940 @@
+package layout
+
+import (
+	"math"
+	"sort"
+	"unicode/utf8"
+)
+
+// Text metrics shared with the renderers, in points. The layout does not know
+// the real font, so every character is assumed to be CharWidth wide.
+const (
+	FontSize   = 12
+	CharWidth  = 7
+	LineHeight = 14
+)
+
+const (
+	nodePadX      = 12
+	nodePadY      = 8
+	minNodeWidth  = 54
+	minNodeHeight = 36
+	dummyBreadth  = 8
+	nodeSep       = 18
+	rankSep       = 48
+	clusterMargin = 10
+	graphMargin   = 8
+	sweeps        = 12
+	balancePasses = 16
+)
+
+// TextWidth returns the width of a line of text.
+func TextWidth(s string) float64 {
+	return float64(utf8.RuneCountInString(s)) * CharWidth
+}
+
+// linesWidth returns the width of the widest line.
+func linesWidth(lines []string) float64 {
+	w := 0.0
+	for _, l := range lines {
+		if lw := TextWidth(l); lw > w {
+			w = lw
+		}
+	}
+	return w
+}
+
+// Layout positions the nodes, edges and clusters of g using a layered
+// (Sugiyama style) layout: cycles are broken, nodes are assigned to ranks,
+// long edges are split with dummy nodes, ranks are ordered to reduce
+// crossings while keeping clusters contiguous, and coordinates are assigned
+// so that cluster boxes do not overlap.
+func Layout(g *Graph) {
+	l := &layouter{g: g}
+	switch g.Attrs["rankdir"] {
+	case "LR", "RL":
+		l.lr = true
+	}
+	l.flip = g.Attrs["rankdir"] == "RL" || g.Attrs["rankdir"] == "BT"
+
+	l.size()
+	l.breakCycles()
+	l.rank()
+	l.split()
+	l.clusterRanks()
+	l.fill()
+	l.order()
+	l.rankPositions()
+	l.breadthPositions()
+	l.place()
+	l.route()
+	l.bounds()
+}
+
+type layouter struct {
+	g    *Graph
+	lr   bool
+	flip bool
+
+	all     []*Node   // real and dummy nodes
+	layers  [][]*Node // nodes by rank, in order
+	rankPos []float64
+	x       map[*Node]float64 // breadth coordinate
+}
+
+type segment struct {
+	from, to *Node
+}
+
+func (l *layouter) size() {
+	for _, n := range l.g.Nodes {
+		lines := n.Label()
+		w := linesWidth(lines) + 2*nodePadX
+		h := float64(len(lines))*LineHeight + 2*nodePadY
+		switch n.Shape() {
+		case "box", "plaintext", "plain", "none", "note", "tab", "folder", "component":
+		default:
+			// Round shapes need room for the text in their corners.
+			w *= 1.3
+			h *= 1.2
+		}
+		n.Width = math.Max(w, minNodeWidth)
+		n.Height = math.Max(h, minNodeHeight)
+		if l.lr {
+			n.breadth, n.depth = n.Height, n.Width
+		} else {
+			n.breadth, n.depth = n.Width, n.Height
+		}
+		l.all = append(l.all, n)
+	}
+}
+
+// breakCycles reverses the edges that close a cycle in a depth first search.
+func (l *layouter) breakCycles() {
+	out := make(map[*Node][]*Edge)
+	for _, e := range l.g.Edges {
+		out[e.Tail] = append(out[e.Tail], e)
+	}
+	state := make(map[*Node]int)
+	var visit func(n *Node)
+	visit = func(n *Node) {
+		state[n] = 1
+		for _, e := range out[n] {
+			switch state[e.Head] {
+			case 0:
+				visit(e.Head)
+			case 1:
+				e.reversed = true
+			}
+		}
+		state[n] = 2
+	}
+	for _, n := range l.g.Nodes {
+		if state[n] == 0 {
+			visit(n)
+		}
+	}
+}
+
+// ends returns the edge's nodes in ranking direction.
+func (e *Edge) ends() (*Node, *Node) {
+	if e.reversed {
+		return e.Head, e.Tail
+	}
+	return e.Tail, e.Head
+}
+
+// rank assigns each node the length of the longest path reaching it, then
+// pulls sources forward to sit just before their first successor.
+func (l *layouter) rank() {
+	preds := make(map[*Node][]*Node)
+	succs := make(map[*Node][]*Node)
+	for _, e := range l.g.Edges {
+		if e.Tail == e.Head {
+			continue
+		}
+		a, b := e.ends()
+		preds[b] = append(preds[b], a)
+		succs[a] = append(succs[a], b)
+	}
+
+	indeg := make(map[*Node]int)
+	for n, ps := range preds {
+		indeg[n] = len(ps)
+	}
+	queue := make([]*Node, 0, len(l.g.Nodes))
+	for _, n := range l.g.Nodes {
+		if indeg[n] == 0 {
+			queue = append(queue, n)
+		}
+	}
+	for i := 0; i < len(queue); i++ {
+		n := queue[i]
+		for _, s := range succs[n] {
+			if n.rank+1 > s.rank {
+				s.rank = n.rank + 1
+			}
+			indeg[s]--
+			if indeg[s] == 0 {
+				queue = append(queue, s)
+			}
+		}
+	}
+
+	for _, n := range l.g.Nodes {
+		if len(preds[n]) > 0 || len(succs[n]) == 0 {
+			continue
+		}
+		min := math.MaxInt32
+		for _, s := range succs[n] {
+			if s.rank < min {
+				min = s.rank
+			}
+		}
+		n.rank = min - 1
+	}
+
+	min := math.MaxInt32
+	for _, n := range l.g.Nodes {
+		if n.rank < min {
+			min = n.rank
+		}
+	}
+	for _, n := range l.g.Nodes {
+		n.rank -= min
+	}
+}
+
+// split replaces edges spanning several ranks with chains of dummy nodes, one
+// per rank.
+func (l *layouter) split() {
+	for _, e := range l.g.Edges {
+		if e.Tail == e.Head {
+			continue
+		}
+		a, b := e.ends()
+		cluster := commonCluster(a.Cluster, b.Cluster)
+		e.chain = []*Node{a}
+		prev := a
+		for r := a.rank + 1; r < b.rank; r++ {
+			d := &Node{
+				rank:    r,
+				breadth: dummyBreadth,
+				Cluster: cluster,
+			}
+			l.link(prev, d)
+			l.all = append(l.all, d)
+			e.chain = append(e.chain, d)
+			prev = d
+		}
+		l.link(prev, b)
+		e.chain = append(e.chain, b)
+	}
+
+	max := 0
+	for _, n := range l.all {
+		if n.rank > max {
+			max = n.rank
+		}
+	}
+	l.layers = make([][]*Node, max+1)
+	for _, n := range l.all {
+		n.order = len(l.layers[n.rank])
+		l.layers[n.rank] = append(l.layers[n.rank], n)
+	}
+}
+
+func (l *layouter) link(a, b *Node) {
+	s := &segment{from: a, to: b}
+	a.out = append(a.out, s)
+	b.in = append(b.in, s)
+}
+
+// clusterRanks records the ranks each cluster spans.
+func (l *layouter) clusterRanks() {
+	var walk func(cs []*Cluster)
+	walk = func(cs []*Cluster) {
+		for _, c := range cs {
+			c.minRank, c.maxRank = math.MaxInt32, -1
+			walk(c.Clusters)
+		}
+	}
+	walk(l.g.Clusters)
+
+	for _, n := range l.all {
+		for _, c := range n.ancestors() {
+			if n.rank < c.minRank {
+				c.minRank = n.rank
+			}
+			if n.rank > c.maxRank {
+				c.maxRank = n.rank
+			}
+		}
+	}
+}
+
+// fill adds an invisible node to every rank a cluster spans without having
+// a node on it, so no other node is placed inside the cluster's box there.
+func (l *layouter) fill() {
+	var walk func(cs []*Cluster)
+	walk = func(cs []*Cluster) {
+		for _, c := range cs {
+			// Children first, so their fillers count for the parent.
+			walk(c.Clusters)
+			for r := c.minRank; r <= c.maxRank; r++ {
+				found := false
+				for _, n := range l.layers[r] {
+					if c.contains(n) {
+						found = true
+						break
+					}
+				}
+				if !found {
+					d := &Node{rank: r, breadth: dummyBreadth, Cluster: c}
+					d.order = len(l.layers[r])
+					l.layers[r] = append(l.layers[r], d)
+					l.all = append(l.all, d)
+				}
+			}
+		}
+	}
+	walk(l.g.Clusters)
+}
+
+// order reduces edge crossings with barycenter sweeps. Within a rank the
+// nodes of a cluster are kept together, and sibling clusters keep the same
+// relative order on every rank so their boxes can not interleave.
+func (l *layouter) order() {
+	l.updateClusterKeys()
+	for r := range l.layers {
+		l.arrange(r, l.positions(r))
+	}
+	best := l.snapshot()
+	bestCrossings := l.crossings()
+
+	for i := 0; i < sweeps && bestCrossings > 0; i++ {
+		// Every rank is arranged with the same cluster keys in a sweep, so
+		// sibling clusters end up in the same order on all of them.
+		l.updateClusterKeys()
+		if i%2 == 0 {
+			l.arrange(0, l.positions(0))
+			for r := 1; r < len(l.layers); r++ {
+				l.arrange(r, l.barycenters(r, true))
+			}
+		} else {
+			last := len(l.layers) - 1
+			l.arrange(last, l.positions(last))
+			for r := last - 1; r >= 0; r-- {
+				l.arrange(r, l.barycenters(r, false))
+			}
+		}
+		if c := l.crossings(); c < bestCrossings {
+			best, bestCrossings = l.snapshot(), c
+		}
+	}
+	l.restore(best)
+}
+
+// positions keys the nodes of rank r by their current order.
+func (l *layouter) positions(r int) map[*Node]float64 {
+	keys := make(map[*Node]float64)
+	for _, n := range l.layers[r] {
+		keys[n] = float64(n.order)
+	}
+	return keys
+}
+
+func (l *layouter) snapshot() [][]*Node {
+	s := make([][]*Node, len(l.layers))
+	for r, layer := range l.layers {
+		s[r] = append([]*Node(nil), layer...)
+	}
+	return s
+}
+
+func (l *layouter) restore(s [][]*Node) {
+	l.layers = s
+	for _, layer := range l.layers {
+		for i, n := range layer {
+			n.order = i
+		}
+	}
+}
+
+// barycenters returns the mean position of each node's neighbours in the
+// previous (down) or next rank. Nodes without neighbours keep their place.
+func (l *layouter) barycenters(r int, down bool) map[*Node]float64 {
+	keys := make(map[*Node]float64)
+	for _, n := range l.layers[r] {
+		sum, count := 0.0, 0
+		if down {
+			for _, s := range n.in {
+				sum += float64(s.from.order)
+				count++
+			}
+		} else {
+			for _, s := range n.out {
+				sum += float64(s.to.order)
+				count++
+			}
+		}
+		if count > 0 {
+			keys[n] = sum / float64(count)
+		} else {
+			keys[n] = float64(n.order)
+		}
+	}
+	return keys
+}
+
+// updateClusterKeys gives every cluster the mean relative position of its
+// nodes over all ranks, which fixes the order of sibling clusters.
+func (l *layouter) updateClusterKeys() {
+	sums := make(map[*Cluster]float64)
+	counts := make(map[*Cluster]int)
+	for _, layer := range l.layers {
+		for _, n := range layer {
+			pos := float64(n.order+1) / float64(len(layer)+1)
+			for _, c := range n.ancestors() {
+				sums[c] += pos
+				counts[c]++
+			}
+		}
+	}
+	for c, sum := range sums {
+		c.key = sum / float64(counts[c])
+	}
+}
+
+type block struct {
+	cluster *Cluster // nil for a single node
+	nodes   []*Node
+	key     float64
+}
+
+// arrange sorts rank r by the given keys, keeping clusters contiguous.
+func (l *layouter) arrange(r int, keys map[*Node]float64) {
+	layer := arrangeIn(nil, l.layers[r], keys)
+	for i, n := range layer {
+		n.order = i
+	}
+	l.layers[r] = layer
+}
+
+func arrangeIn(parent *Cluster, nodes []*Node, keys map[*Node]float64) []*Node {
+	blocks := make([]*block, 0, len(nodes))
+	byCluster := make(map[*Cluster]*block)
+	for _, n := range nodes {
+		c := childOf(parent, n)
+		if c == nil {
+			blocks = append(blocks, &block{nodes: []*Node{n}, key: keys[n]})
+			continue
+		}
+		b, ok := byCluster[c]
+		if !ok {
+			b = &block{cluster: c}
+			byCluster[c] = b
+			blocks = append(blocks, b)
+		}
+		b.nodes = append(b.nodes, n)
+	}
+
+	for _, b := range blocks {
+		if b.cluster == nil {
+			continue
+		}
+		b.nodes = arrangeIn(b.cluster, b.nodes, keys)
+		sum := 0.0
+		for _, n := range b.nodes {
+			sum += keys[n]
+		}
+		b.key = sum / float64(len(b.nodes))
+	}
+	sort.SliceStable(blocks, func(i, j int) bool {
+		return blocks[i].key < blocks[j].key
+	})
+
+	// Refill the slots taken by clusters in their global order.
+	slots := make([]int, 0)
+	clusters := make([]*block, 0)
+	for i, b := range blocks {
+		if b.cluster != nil {
+			slots = append(slots, i)
+			clusters = append(clusters, b)
+		}
+	}
+	sort.SliceStable(clusters, func(i, j int) bool {
+		return clusters[i].cluster.key < clusters[j].cluster.key
+	})
+	for i, slot := range slots {
+		blocks[slot] = clusters[i]
+	}
+
+	out := make([]*Node, 0, len(nodes))
+	for _, b := range blocks {
+		out = append(out, b.nodes...)
+	}
+	return out
+}
+
+// childOf returns the child cluster of parent that contains n, or nil if n
+// belongs directly to parent.
+func childOf(parent *Cluster, n *Node) *Cluster {
+	var child *Cluster
+	for c := n.Cluster; c != nil && c != parent; c = c.Parent {
+		child = c
+	}
+	return child
+}
+
+// crossings counts the edge crossings between all adjacent ranks.
+func (l *layouter) crossings() int {
+	count := 0
+	for r := 0; r+1 < len(l.layers); r++ {
+		var segs []*segment
+		for _, n := range l.layers[r] {
+			segs = append(segs, n.out...)
+		}
+		for i := 0; i < len(segs); i++ {
+			for j := i + 1; j < len(segs); j++ {
+				a, b := segs[i], segs[j]
+				if (a.from.order-b.from.order)*(a.to.order-b.to.order) < 0 {
+					count++
+				}
+			}
+		}
+	}
+	return count
+}
+
+// before returns the space a cluster needs before its first node along the
+// given axis: the margin, plus the label if the label sits on that side.
+func (l *layouter) before(c *Cluster, rankAxis bool) float64 {
+	pad := float64(clusterMargin)
+	if rankAxis != l.lr {
+		pad += float64(len(c.Label())) * LineHeight
+	}
+	return pad
+}
+
+// rankPositions places the ranks along the rank axis, leaving room for the
+// margins and labels of the clusters that end and start between them.
+func (l *layouter) rankPositions() {
+	depth := make([]float64, len(l.layers))
+	for r, layer := range l.layers {
+		for _, n := range layer {
+			depth[r] = math.Max(depth[r], n.depth)
+		}
+	}
+
+	opening := func(r int) float64 {
+		pad := 0.0
+		for _, n := range l.layers[r] {
+			p := 0.0
+			for _, c := range n.ancestors() {
+				if c.minRank == r {
+					p += l.before(c, true)
+				}
+			}
+			pad = math.Max(pad, p)
+		}
+		return pad
+	}
+	closing := func(r int) float64 {
+		pad := 0.0
+		for _, n := range l.layers[r] {
+			p := 0.0
+			for _, c := range n.ancestors() {
+				if c.maxRank == r {
+					p += clusterMargin
+				}
+			}
+			pad = math.Max(pad, p)
+		}
+		return pad
+	}
+
+	l.rankPos = make([]float64, len(l.layers))
+	pos := 0.0
+	for r := range l.layers {
+		if r == 0 {
+			pos = opening(0) + depth[0]/2
+		} else {
+			pos += depth[r-1]/2 + closing(r-1) + rankSep + opening(r) + depth[r]/2
+		}
+		l.rankPos[r] = pos
+	}
+}
+
+// constraint requires x[to] >= x[from] + sep.
+type constraint struct {
+	from, to *Node
+	sep      float64
+}
+
+// separation is the distance needed between the centers of a and b when a
+// is placed before b, including the margins of the clusters between them.
+func (l *layouter) separation(a, b *Node) float64 {
+	sep := a.breadth/2 + b.breadth/2 + nodeSep
+	for _, c := range a.ancestors() {
+		if !c.contains(b) {
+			sep += clusterMargin
+		}
+	}
+	for _, c := range b.ancestors() {
+		if !c.contains(a) {
+			sep += l.before(c, false)
+		}
+	}
+	return sep
+}
+
+// breadthPositions assigns coordinates within the ranks. The ordering is
+// turned into separation constraints, including constraints across ranks so
+// that a cluster box clears its neighbours on every rank it spans, then
+// nodes are moved towards their neighbours within those constraints.
+func (l *layouter) breadthPositions() {
+	members := make(map[*Cluster][]*Node)
+	for _, n := range l.all {
+		for _, c := range n.ancestors() {
+			members[c] = append(members[c], n)
+		}
+	}
+
+	var cons []constraint
+	for _, layer := range l.layers {
+		for i := 0; i+1 < len(layer); i++ {
+			a, b := layer[i], layer[i+1]
+			cons = append(cons, constraint{a, b, l.separation(a, b)})
+			for _, c := range b.ancestors() {
+				if c.contains(a) {
+					break
+				}
+				for _, m := range members[c] {
+					if m.rank != b.rank {
+						cons = append(cons, constraint{a, m, l.separation(a, m)})
+					}
+				}
+			}
+			for _, c := range a.ancestors() {
+				if c.contains(b) {
+					break
+				}
+				for _, m := range members[c] {
+					if m.rank != a.rank {
+						cons = append(cons, constraint{m, b, l.separation(m, b)})
+					}
+				}
+			}
+		}
+	}
+
+	l.x = make(map[*Node]float64)
+	order, ok := l.solve(cons)
+	if !ok {
+		// Should not happen with consistent cluster orders, but fall back to
+		// constraints within ranks only.
+		cons = cons[:0]
+		for _, layer := range l.layers {
+			for i := 0; i+1 < len(layer); i++ {
+				cons = append(cons, constraint{layer[i], layer[i+1], l.separation(layer[i], layer[i+1])})
+			}
+		}
+		order, _ = l.solve(cons)
+	}
+	l.balance(order, cons)
+}
+
+// solve sets every node to the smallest coordinate satisfying cons, and
+// returns the nodes in constraint order. It reports false on a cycle.
+func (l *layouter) solve(cons []constraint) ([]*Node, bool) {
+	out := make(map[*Node][]constraint)
+	indeg := make(map[*Node]int)
+	for _, c := range cons {
+		out[c.from] = append(out[c.from], c)
+		indeg[c.to]++
+	}
+	queue := make([]*Node, 0, len(l.all))
+	for _, n := range l.all {
+		l.x[n] = 0
+		if indeg[n] == 0 {
+			queue = append(queue, n)
+		}
+	}
+	for i := 0; i < len(queue); i++ {
+		n := queue[i]
+		for _, c := range out[n] {
+			l.x[c.to] = math.Max(l.x[c.to], l.x[n]+c.sep)
+			indeg[c.to]--
+			if indeg[c.to] == 0 {
+				queue = append(queue, c.to)
+			}
+		}
+	}
+	return queue, len(queue) == len(l.all)
+}
+
+// balance moves each node towards the median of its neighbours, as far as
+// the constraints allow, sweeping in both directions.
+func (l *layouter) balance(order []*Node, cons []constraint) {
+	in := make(map[*Node][]constraint)
+	out := make(map[*Node][]constraint)
+	for _, c := range cons {
+		in[c.to] = append(in[c.to], c)
+		out[c.from] = append(out[c.from], c)
+	}
+
+	for pass := 0; pass < balancePasses; pass++ {
+		for i := range order {
+			n := order[i]
+			if pass%2 == 1 {
+				n = order[len(order)-1-i]
+			}
+			var xs []float64
+			for _, s := range n.in {
+				xs = append(xs, l.x[s.from])
+			}
+			for _, s := range n.out {
+				xs = append(xs, l.x[s.to])
+			}
+			if len(xs) == 0 {
+				continue
+			}
+			sort.Float64s(xs)
+			want := xs[len(xs)/2]
+			if len(xs)%2 == 0 {
+				want = (xs[len(xs)/2-1] + xs[len(xs)/2]) / 2
+			}
+
+			lo, hi := math.Inf(-1), math.Inf(1)
+			for _, c := range in[n] {
+				lo = math.Max(lo, l.x[c.from]+c.sep)
+			}
+			for _, c := range out[n] {
+				hi = math.Min(hi, l.x[c.to]-c.sep)
+			}
+			if lo > hi {
+				continue
+			}
+			l.x[n] = math.Min(math.Max(want, lo), hi)
+		}
+	}
+}
+
+// place converts rank and breadth coordinates into positions on the page.
+func (l *layouter) place() {
+	for _, n := range l.all {
+		b, r := l.x[n], l.rankPos[n.rank]
+		if l.flip {
+			r = -r
+		}
+		if l.lr {
+			n.Pos = Point{X: r, Y: b}
+		} else {
+			n.Pos = Point{X: b, Y: r}
+		}
+	}
+
+	var fit func(c *Cluster) Rect
+	fit = func(c *Cluster) Rect {
+		minX, minY := math.Inf(1), math.Inf(1)
+		maxX, maxY := math.Inf(-1), math.Inf(-1)
+		for _, n := range l.all {
+			if n.Cluster != c {
+				continue
+			}
+			minX = math.Min(minX, n.Pos.X-n.Width/2)
+			maxX = math.Max(maxX, n.Pos.X+n.Width/2)
+			minY = math.Min(minY, n.Pos.Y-n.Height/2)
+			maxY = math.Max(maxY, n.Pos.Y+n.Height/2)
+		}
+		for _, child := range c.Clusters {
+			r := fit(child)
+			minX = math.Min(minX, r.X)
+			maxX = math.Max(maxX, r.X+r.Width)
+			minY = math.Min(minY, r.Y)
+			maxY = math.Max(maxY, r.Y+r.Height)
+		}
+		if math.IsInf(minX, 1) {
+			// An empty cluster is just big enough for its label.
+			minX, maxX, minY, maxY = 0, 0, 0, 0
+		}
+		label := c.Label()
+		minX -= clusterMargin
+		maxX += clusterMargin
+		minY -= clusterMargin + float64(len(label))*LineHeight
+		maxY += clusterMargin
+		if w := linesWidth(label) + 2*clusterMargin; maxX-minX < w {
+			grow := (w - (maxX - minX)) / 2
+			minX -= grow
+			maxX += grow
+		}
+		c.Bounds = Rect{X: minX, Y: minY, Width: maxX - minX, Height: maxY - minY}
+		return c.Bounds
+	}
+	for _, c := range l.g.Clusters {
+		fit(c)
+	}
+}
+
+// route draws every edge as a spline through its chain of dummy nodes,
+// leaving and entering the nodes along the rank axis.
+func (l *layouter) route() {
+	for _, e := range l.g.Edges {
+		if e.Tail == e.Head {
+			l.loop(e)
+			continue
+		}
+		pts := make([]Point, len(e.chain))
+		for i, n := range e.chain {
+			pts[i] = n.Pos
+		}
+		if e.reversed {
+			for i, j := 0, len(pts)-1; i < j; i, j = i+1, j-1 {
+				pts[i], pts[j] = pts[j], pts[i]
+			}
+		}
+		pts[0] = l.port(e.Tail, pts[1])
+		pts[len(pts)-1] = l.port(e.Head, pts[len(pts)-2])
+
+		e.Points = []Point{pts[0]}
+		for i := 0; i+1 < len(pts); i++ {
+			p, q := pts[i], pts[i+1]
+			var c1, c2 Point
+			if l.lr {
+				dx := (q.X - p.X) / 2
+				c1, c2 = Point{p.X + dx, p.Y}, Point{q.X - dx, q.Y}
+			} else {
+				dy := (q.Y - p.Y) / 2
+				c1, c2 = Point{p.X, p.Y + dy}, Point{q.X, q.Y - dy}
+			}
+			e.Points = append(e.Points, c1, c2, q)
+		}
+		mid := len(pts) / 2
+		if len(pts)%2 == 0 {
+			e.LabelPos = bezierPoint(e.Points[3*(mid-1):3*mid+1], 0.5)
+		} else {
+			e.LabelPos = pts[mid]
+		}
+	}
+}
+
+func (l *layouter) loop(e *Edge) {
+	n := e.Tail
+	hw, hh := n.Width/2, n.Height/2
+	p := n.Pos
+	e.Points = []Point{
+		{p.X + hw*0.7, p.Y - hh*0.7},
+		{p.X + hw + 30, p.Y - hh - 10},
+		{p.X + hw + 30, p.Y + hh + 10},
+		{p.X + hw*0.7, p.Y + hh*0.7},
+	}
+	e.LabelPos = Point{p.X + hw + 30, p.Y}
+}
+
+// port returns where an edge towards p attaches to n: on the side of n facing
+// p along the rank axis, shifted towards p along the rank so edges to
+// different neighbours do not all meet in one point.
+func (l *layouter) port(n *Node, p Point) Point {
+	hw, hh := n.Width/2, n.Height/2
+	// Work in rank (r) and breadth (b) coordinates.
+	dr, db, hr, hb := p.Y-n.Pos.Y, p.X-n.Pos.X, hh, hw
+	if l.lr {
+		dr, db, hr, hb = p.X-n.Pos.X, p.Y-n.Pos.Y, hw, hh
+	}
+	if dr == 0 {
+		return n.Pos
+	}
+
+	b := math.Max(-hb*0.35, math.Min(hb*0.35, db*0.2))
+	r := hr
+	switch n.Shape() {
+	case "box", "plaintext", "plain", "none", "note", "tab", "folder", "component":
+	default:
+		r = hr * math.Sqrt(1-(b*b)/(hb*hb))
+	}
+	if dr < 0 {
+		r = -r
+	}
+
+	if l.lr {
+		return Point{n.Pos.X + r, n.Pos.Y + b}
+	}
+	return Point{n.Pos.X + b, n.Pos.Y + r}
+}
+
+func bezierPoint(c []Point, t float64) Point {
+	u := 1 - t
+	a, b, cc, d := u*u*u, 3*u*u*t, 3*u*t*t, t*t*t
+	return Point{
+		X: a*c[0].X + b*c[1].X + cc*c[2].X + d*c[3].X,
+		Y: a*c[0].Y + b*c[1].Y + cc*c[2].Y + d*c[3].Y,
+	}
+}
+
+// bounds translates the drawing so it starts at the graph margin, and adds
+// room for the graph label below it.
+func (l *layouter) bounds() {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	grow := func(x0, y0, x1, y1 float64) {
+		minX, minY = math.Min(minX, x0), math.Min(minY, y0)
+		maxX, maxY = math.Max(maxX, x1), math.Max(maxY, y1)
+	}
+	for _, n := range l.g.Nodes {
+		grow(n.Pos.X-n.Width/2, n.Pos.Y-n.Height/2, n.Pos.X+n.Width/2, n.Pos.Y+n.Height/2)
+	}
+	var walk func(cs []*Cluster)
+	walk = func(cs []*Cluster) {
+		for _, c := range cs {
+			grow(c.Bounds.X, c.Bounds.Y, c.Bounds.X+c.Bounds.Width, c.Bounds.Y+c.Bounds.Height)
+			walk(c.Clusters)
+		}
+	}
+	walk(l.g.Clusters)
+	for _, e := range l.g.Edges {
+		for _, p := range e.Points {
+			grow(p.X, p.Y, p.X, p.Y)
+		}
+	}
+	if math.IsInf(minX, 1) {
+		minX, minY, maxX, maxY = 0, 0, 0, 0
+	}
+
+	label := l.g.Label()
+	labelHeight := float64(len(label)) * LineHeight
+	if w := linesWidth(label); maxX-minX < w {
+		maxX = minX + w
+	}
+
+	dx, dy := graphMargin-minX, graphMargin-minY
+	for _, n := range l.g.Nodes {
+		n.Pos.X += dx
+		n.Pos.Y += dy
+	}
+	var shift func(cs []*Cluster)
+	shift = func(cs []*Cluster) {
+		for _, c := range cs {
+			c.Bounds.X += dx
+			c.Bounds.Y += dy
+			shift(c.Clusters)
+		}
+	}
+	shift(l.g.Clusters)
+	for _, e := range l.g.Edges {
+		for i := range e.Points {
+			e.Points[i].X += dx
+			e.Points[i].Y += dy
+		}
+		e.LabelPos.X += dx
+		e.LabelPos.Y += dy
+	}
+
+	width := maxX - minX + 2*graphMargin
+	height := maxY - minY + 2*graphMargin
+	if labelHeight > 0 {
+		l.g.LabelPos = Point{X: width / 2, Y: height + labelHeight/2}
+		height += labelHeight + graphMargin
+	}
+	l.g.Bounds = Rect{Width: width, Height: height}
+}
@@ -0,0 +1,204 @@
+package layout
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func layoutOf(t *testing.T, src string) *Graph {
+	t.Helper()
+	g, err := Parse([]byte(src))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	Layout(g)
+	return g
+}
+
+// ranks returns the IDs of the nodes of each rank, in order.
+func ranks(g *Graph) [][]string {
+	var rs [][]string
+	nodes := append([]*Node(nil), g.Nodes...)
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].order < nodes[j].order })
+	for _, n := range nodes {
+		for len(rs) <= n.rank {
+			rs = append(rs, nil)
+		}
+		rs[n.rank] = append(rs[n.rank], n.ID)
+	}
+	return rs
+}
+
+// crossings counts the crossings of edges between adjacent ranks, from the
+// node positions.
+func crossings(g *Graph) int {
+	count := 0
+	for i, a := range g.Edges {
+		for _, b := range g.Edges[i+1:] {
+			if a.Tail.rank != b.Tail.rank || a.Head.rank != b.Head.rank || a.Head.rank != a.Tail.rank+1 {
+				continue
+			}
+			if (a.Tail.Pos.X-b.Tail.Pos.X)*(a.Head.Pos.X-b.Head.Pos.X) < 0 {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func TestLayering(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want [][]string
+	}{{
+		name: "chain",
+		src:  `digraph { a -> b -> c }`,
+		want: [][]string{{"a"}, {"b"}, {"c"}},
+	}, {
+		name: "shortcut is stretched",
+		src:  `digraph { a -> b -> c; a -> c }`,
+		want: [][]string{{"a"}, {"b"}, {"c"}},
+	}, {
+		name: "cycle is broken",
+		src:  `digraph { a -> b -> c -> a }`,
+		want: [][]string{{"a"}, {"b"}, {"c"}},
+	}, {
+		name: "fan out",
+		src:  `digraph { a -> b; a -> c; a -> d }`,
+		want: [][]string{{"a"}, {"b", "c", "d"}},
+	}, {
+		name: "unconnected node on the first rank",
+		src:  `digraph { a -> b; c }`,
+		want: [][]string{{"a", "c"}, {"b"}},
+	}, {
+		name: "clustered",
+		src:  `digraph { subgraph cluster_0 { a -> b } b -> c }`,
+		want: [][]string{{"a"}, {"b"}, {"c"}},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ranks(layoutOf(t, tt.src)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ranks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrossingReduction(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want [][]string
+	}{{
+		name: "swapped pair",
+		src:  `digraph { a; b; c; d; a -> d; b -> c }`,
+		want: [][]string{{"a", "b"}, {"d", "c"}},
+	}, {
+		name: "reversed permutation",
+		src:  `digraph { a; b; c; d; e; f; a -> f; b -> e; c -> d }`,
+		want: [][]string{{"a", "b", "c"}, {"f", "e", "d"}},
+	}, {
+		name: "two layers of fan in",
+		src:  `digraph { a -> x; b -> y; c -> x; x -> z; y -> z }`,
+		want: [][]string{{"a", "c", "b"}, {"x", "y"}, {"z"}},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := layoutOf(t, tt.src)
+			if n := crossings(g); n != 0 {
+				t.Errorf("crossings = %d, want 0", n)
+			}
+			if got := ranks(g); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ranks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrossingsKeepClustersContiguous(t *testing.T) {
+	g := layoutOf(t, `digraph {
+		a; b; c;
+		subgraph cluster_0 { x; z }
+		y;
+		a -> z; b -> y; c -> x;
+	}`)
+	got := ranks(g)[1]
+	var xz []int
+	for i, id := range got {
+		if id == "x" || id == "z" {
+			xz = append(xz, i)
+		}
+	}
+	if len(xz) != 2 || xz[1]-xz[0] != 1 {
+		t.Errorf("rank 1 = %v, want x and z next to each other", got)
+	}
+}
+
+func within(inner, outer Rect) bool {
+	const eps = 1e-6
+	return inner.X >= outer.X-eps && inner.Y >= outer.Y-eps &&
+		inner.X+inner.Width <= outer.X+outer.Width+eps &&
+		inner.Y+inner.Height <= outer.Y+outer.Height+eps
+}
+
+func overlap(a, b Rect) bool {
+	return a.X < b.X+b.Width && b.X < a.X+a.Width && a.Y < b.Y+b.Height && b.Y < a.Y+a.Height
+}
+
+func box(n *Node) Rect {
+	return Rect{X: n.Pos.X - n.Width/2, Y: n.Pos.Y - n.Height/2, Width: n.Width, Height: n.Height}
+}
+
+func TestClusterBounds(t *testing.T) {
+	for _, rankdir := range []string{"TB", "LR"} {
+		t.Run(rankdir, func(t *testing.T) {
+			g := layoutOf(t, `digraph {
+				rankdir=`+rankdir+`;
+				subgraph cluster_team {
+					label="Team blue";
+					subgraph cluster_broker { label="Broker default"; ingress; t1; t2 }
+					svc1;
+				}
+				subgraph cluster_other { label="Team red"; svc2; svc3 }
+				source -> ingress; ingress -> t1; ingress -> t2;
+				t1 -> svc1; t2 -> svc2; svc2 -> svc3; loose;
+			}`)
+
+			var check func(cs []*Cluster)
+			check = func(cs []*Cluster) {
+				for i, c := range cs {
+					if c.Bounds.Width <= 0 || c.Bounds.Height <= 0 {
+						t.Errorf("cluster %s has no size: %+v", c.ID, c.Bounds)
+					}
+					for _, n := range c.Nodes {
+						if !within(box(n), c.Bounds) {
+							t.Errorf("node %s %+v is outside cluster %s %+v", n.ID, box(n), c.ID, c.Bounds)
+						}
+					}
+					for _, child := range c.Clusters {
+						if !within(child.Bounds, c.Bounds) {
+							t.Errorf("cluster %s is outside its parent %s", child.ID, c.ID)
+						}
+					}
+					for _, sibling := range cs[i+1:] {
+						if overlap(c.Bounds, sibling.Bounds) {
+							t.Errorf("clusters %s and %s overlap", c.ID, sibling.ID)
+						}
+					}
+					for _, n := range g.Nodes {
+						if !c.contains(n) && overlap(box(n), c.Bounds) {
+							t.Errorf("node %s overlaps cluster %s it is not in", n.ID, c.ID)
+						}
+					}
+					if !within(c.Bounds, g.Bounds) {
+						t.Errorf("cluster %s is outside the drawing", c.ID)
+					}
+					check(c.Clusters)
+				}
+			}
+			check(g.Clusters)
+		})
+	}
+}
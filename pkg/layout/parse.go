@@ -0,0 +1,440 @@
+package layout
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Parse reads a graph in the DOT language. It understands the subset of the
+// language knap and most generators emit: node, edge and attribute
+// statements, nested and cluster subgraphs. Ports and edges to subgraphs are
+// not supported.
+func Parse(src []byte) (*Graph, error) {
+	p := &parser{lex: &lexer{src: string(src)}}
+	p.next()
+	g, err := p.parseGraph()
+	if err != nil {
+		return nil, fmt.Errorf("dot: line %d: %v", p.tok.line, err)
+	}
+	return g, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokID
+	tokPunct
+	tokEdgeOp
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	quoted bool
+	line   int
+}
+
+type lexer struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (l *lexer) skip() {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\n':
+			l.line++
+			l.pos++
+		case c == ' ' || c == '\t' || c == '\r':
+			l.pos++
+		case strings.HasPrefix(l.src[l.pos:], "//"), c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		case strings.HasPrefix(l.src[l.pos:], "/*"):
+			end := strings.Index(l.src[l.pos+2:], "*/")
+			if end < 0 {
+				l.pos = len(l.src)
+				return
+			}
+			l.line += strings.Count(l.src[l.pos:l.pos+2+end], "\n")
+			l.pos += end + 4
+		default:
+			return
+		}
+	}
+}
+
+func (l *lexer) next() (token, error) {
+	l.skip()
+	t := token{line: l.line + 1}
+	if l.pos >= len(l.src) {
+		return t, nil
+	}
+	c := l.src[l.pos]
+	switch {
+	case c == '-' && l.pos+1 < len(l.src) && (l.src[l.pos+1] == '>' || l.src[l.pos+1] == '-'):
+		t.kind, t.text = tokEdgeOp, l.src[l.pos:l.pos+2]
+		l.pos += 2
+	case strings.IndexByte("{}[];,=:", c) >= 0:
+		t.kind, t.text = tokPunct, string(c)
+		l.pos++
+	case c == '"':
+		s, err := l.quoted()
+		if err != nil {
+			return t, err
+		}
+		// Quoted strings may be concatenated with '+'.
+		for {
+			save, line := l.pos, l.line
+			l.skip()
+			if l.pos < len(l.src) && l.src[l.pos] == '+' {
+				l.pos++
+				l.skip()
+				if l.pos < len(l.src) && l.src[l.pos] == '"' {
+					more, err := l.quoted()
+					if err != nil {
+						return t, err
+					}
+					s += more
+					continue
+				}
+			}
+			l.pos, l.line = save, line
+			break
+		}
+		t.kind, t.text, t.quoted = tokID, s, true
+	case c == '<':
+		depth := 0
+		start := l.pos
+		for ; l.pos < len(l.src); l.pos++ {
+			switch l.src[l.pos] {
+			case '<':
+				depth++
+			case '>':
+				depth--
+			case '\n':
+				l.line++
+			}
+			if depth == 0 {
+				break
+			}
+		}
+		if depth != 0 {
+			return t, fmt.Errorf("unterminated HTML string")
+		}
+		l.pos++
+		t.kind, t.text, t.quoted = tokID, l.src[start+1:l.pos-1], true
+	case c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		start := l.pos
+		l.pos++
+		for l.pos < len(l.src) && (l.src[l.pos] == '.' || (l.src[l.pos] >= '0' && l.src[l.pos] <= '9')) {
+			l.pos++
+		}
+		t.kind, t.text = tokID, l.src[start:l.pos]
+	default:
+		start := l.pos
+		for l.pos < len(l.src) {
+			r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+			if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				break
+			}
+			l.pos += size
+		}
+		if start == l.pos {
+			return t, fmt.Errorf("unexpected character %q", c)
+		}
+		t.kind, t.text = tokID, l.src[start:l.pos]
+	}
+	return t, nil
+}
+
+// quoted reads a double quoted string. Escaped quotes and line continuations
+// are removed, other escapes such as \n are kept for the label to interpret.
+func (l *lexer) quoted() (string, error) {
+	var b strings.Builder
+	for l.pos++; l.pos < len(l.src); l.pos++ {
+		c := l.src[l.pos]
+		switch {
+		case c == '"':
+			l.pos++
+			return b.String(), nil
+		case c == '\\' && l.pos+1 < len(l.src) && l.src[l.pos+1] == '"':
+			b.WriteByte('"')
+			l.pos++
+		case c == '\\' && l.pos+1 < len(l.src) && l.src[l.pos+1] == '\n':
+			l.line++
+			l.pos++
+		case c == '\\' && l.pos+1 < len(l.src):
+			b.WriteByte(c)
+			b.WriteByte(l.src[l.pos+1])
+			l.pos++
+		default:
+			if c == '\n' {
+				l.line++
+			}
+			b.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("unterminated string")
+}
+
+type parser struct {
+	lex *lexer
+	tok token
+	err error
+	g   *Graph
+}
+
+type scope struct {
+	cluster   *Cluster
+	attrs     map[string]string // graph or cluster attributes
+	nodeAttrs map[string]string
+	edgeAttrs map[string]string
+}
+
+func (p *parser) next() {
+	if p.err != nil {
+		return
+	}
+	p.tok, p.err = p.lex.next()
+}
+
+func (p *parser) is(text string) bool {
+	return p.tok.kind == tokPunct && p.tok.text == text
+}
+
+func (p *parser) keyword(kw string) bool {
+	return p.tok.kind == tokID && !p.tok.quoted && strings.EqualFold(p.tok.text, kw)
+}
+
+func (p *parser) expect(text string) error {
+	if p.err != nil {
+		return p.err
+	}
+	if !p.is(text) {
+		return fmt.Errorf("expected %q, found %q", text, p.tok.text)
+	}
+	p.next()
+	return p.err
+}
+
+func (p *parser) parseGraph() (*Graph, error) {
+	g := &Graph{
+		Attrs: make(map[string]string),
+		nodes: make(map[string]*Node),
+	}
+	p.g = g
+
+	if p.keyword("strict") {
+		p.next()
+	}
+	switch {
+	case p.keyword("digraph"):
+		g.Directed = true
+	case p.keyword("graph"):
+	default:
+		return nil, fmt.Errorf("expected graph or digraph, found %q", p.tok.text)
+	}
+	p.next()
+	if p.tok.kind == tokID {
+		g.Name = p.tok.text
+		p.next()
+	}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	s := &scope{
+		attrs:     g.Attrs,
+		nodeAttrs: map[string]string{},
+		edgeAttrs: map[string]string{},
+	}
+	if err := p.parseStmts(s); err != nil {
+		return nil, err
+	}
+	if err := p.expect("}"); err != nil {
+		return nil, err
+	}
+	return g, p.err
+}
+
+func (p *parser) parseStmts(s *scope) error {
+	for p.err == nil && p.tok.kind != tokEOF && !p.is("}") {
+		if err := p.parseStmt(s); err != nil {
+			return err
+		}
+		if p.is(";") || p.is(",") {
+			p.next()
+		}
+	}
+	return p.err
+}
+
+func (p *parser) parseStmt(s *scope) error {
+	switch {
+	case p.keyword("graph"):
+		p.next()
+		return p.parseAttrList(s.attrs)
+	case p.keyword("node"):
+		p.next()
+		return p.parseAttrList(s.nodeAttrs)
+	case p.keyword("edge"):
+		p.next()
+		return p.parseAttrList(s.edgeAttrs)
+	case p.keyword("subgraph"), p.is("{"):
+		return p.parseSubgraph(s)
+	case p.tok.kind == tokID:
+		id := p.tok.text
+		p.next()
+		if p.is("=") {
+			p.next()
+			if p.tok.kind != tokID {
+				return fmt.Errorf("expected value for %q", id)
+			}
+			s.attrs[id] = p.tok.text
+			p.next()
+			return p.err
+		}
+		p.skipPort()
+		return p.parseNodeOrEdge(s, id)
+	default:
+		return fmt.Errorf("unexpected %q", p.tok.text)
+	}
+}
+
+func (p *parser) skipPort() {
+	for p.is(":") {
+		p.next()
+		if p.tok.kind == tokID {
+			p.next()
+		}
+	}
+}
+
+func (p *parser) parseSubgraph(s *scope) error {
+	name := ""
+	if p.keyword("subgraph") {
+		p.next()
+		if p.tok.kind == tokID {
+			name = p.tok.text
+			p.next()
+		}
+	}
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+
+	inner := &scope{
+		cluster:   s.cluster,
+		attrs:     map[string]string{},
+		nodeAttrs: copyAttrs(s.nodeAttrs),
+		edgeAttrs: copyAttrs(s.edgeAttrs),
+	}
+	if strings.HasPrefix(name, "cluster") {
+		c := &Cluster{ID: name, Attrs: inner.attrs, Parent: s.cluster}
+		if s.cluster != nil {
+			s.cluster.Clusters = append(s.cluster.Clusters, c)
+		} else {
+			p.g.Clusters = append(p.g.Clusters, c)
+		}
+		inner.cluster = c
+	}
+	if err := p.parseStmts(inner); err != nil {
+		return err
+	}
+	return p.expect("}")
+}
+
+func (p *parser) parseNodeOrEdge(s *scope, id string) error {
+	ids := []string{id}
+	for p.tok.kind == tokEdgeOp {
+		p.next()
+		if p.tok.kind != tokID {
+			return fmt.Errorf("expected node after edge operator, found %q", p.tok.text)
+		}
+		ids = append(ids, p.tok.text)
+		p.next()
+		p.skipPort()
+	}
+
+	attrs := map[string]string{}
+	if p.is("[") {
+		if err := p.parseAttrList(attrs); err != nil {
+			return err
+		}
+	}
+
+	if len(ids) == 1 {
+		n := p.node(s, id)
+		for k, v := range attrs {
+			n.Attrs[k] = v
+		}
+		return nil
+	}
+
+	for i := 0; i+1 < len(ids); i++ {
+		e := &Edge{
+			Tail:  p.node(s, ids[i]),
+			Head:  p.node(s, ids[i+1]),
+			Attrs: copyAttrs(s.edgeAttrs),
+		}
+		for k, v := range attrs {
+			e.Attrs[k] = v
+		}
+		p.g.Edges = append(p.g.Edges, e)
+	}
+	return nil
+}
+
+// node returns the node with the given id, creating it in the scope's
+// cluster if it does not exist yet.
+func (p *parser) node(s *scope, id string) *Node {
+	if n, ok := p.g.nodes[id]; ok {
+		return n
+	}
+	n := &Node{ID: id, Attrs: copyAttrs(s.nodeAttrs), Cluster: s.cluster}
+	if s.cluster != nil {
+		s.cluster.Nodes = append(s.cluster.Nodes, n)
+	}
+	p.g.nodes[id] = n
+	p.g.Nodes = append(p.g.Nodes, n)
+	return n
+}
+
+func (p *parser) parseAttrList(attrs map[string]string) error {
+	for p.is("[") {
+		p.next()
+		for p.err == nil && p.tok.kind == tokID {
+			key := p.tok.text
+			p.next()
+			if err := p.expect("="); err != nil {
+				return err
+			}
+			if p.tok.kind != tokID {
+				return fmt.Errorf("expected value for %q", key)
+			}
+			attrs[key] = p.tok.text
+			p.next()
+			if p.is(",") || p.is(";") {
+				p.next()
+			}
+		}
+		if err := p.expect("]"); err != nil {
+			return err
+		}
+	}
+	return p.err
+}
+
+func copyAttrs(attrs map[string]string) map[string]string {
+	c := make(map[string]string, len(attrs))
+	for k, v := range attrs {
+		c[k] = v
+	}
+	return c
+}
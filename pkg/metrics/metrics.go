@@ -0,0 +1,237 @@
+// Package metrics keeps counters and histograms and serves them in the
+// Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets are the default histogram buckets, in seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Registry holds metrics and serves them over HTTP.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+type metric interface {
+	write(w io.Writer)
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+// ServeHTTP writes every metric in the registry.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// desc is what every metric has: a name, help and label names.
+type desc struct {
+	name   string
+	help   string
+	labels []string
+}
+
+func (d *desc) header(w io.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, strings.Replace(d.help, "\n", " ", -1))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, kind)
+}
+
+// key joins label values so they can index a map.
+func (d *desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metric %s has %d labels, got %d values", d.name, len(d.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// pairs formats the labels for the values, plus extra name, value pairs.
+func (d *desc) pairs(key string, extra ...string) string {
+	var names, values []string
+	if len(d.labels) > 0 {
+		names = append(names, d.labels...)
+		values = strings.Split(key, "\xff")
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		names = append(names, extra[i])
+		values = append(values, extra[i+1])
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	parts := make([]string, len(names))
+	for i := range names {
+		parts[i] = names[i] + `="` + labelEscaper.Replace(values[i]) + `"`
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// sortedKeys returns the keys of the series in a stable order.
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// Counter counts events, by label values.
+type Counter struct {
+	desc
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewCounter registers a counter with the label names.
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{desc: desc{name: name, help: help, labels: labels}, values: make(map[string]float64)}
+	r.register(c)
+	return c
+}
+
+// Inc adds one to the series with the label values.
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds v to the series with the label values.
+func (c *Counter) Add(v float64, values ...string) {
+	key := c.key(values)
+	c.mu.Lock()
+	c.values[key] += v
+	c.mu.Unlock()
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.header(w, "counter")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	keys := make(map[string]bool, len(c.values))
+	for k := range c.values {
+		keys[k] = true
+	}
+	for _, k := range sortedKeys(keys) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.pairs(k), formatFloat(c.values[k]))
+	}
+}
+
+// Histogram counts observations into buckets, by label values.
+type Histogram struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram registers a histogram with the upper bounds of its buckets,
+// in increasing order, and the label names.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{
+		desc:    desc{name: name, help: help, labels: labels},
+		buckets: buckets,
+		series:  make(map[string]*histogramSeries),
+	}
+	r.register(h)
+	return h
+}
+
+// Observe records v in the series with the label values.
+func (h *Histogram) Observe(v float64, values ...string) {
+	key := h.key(values)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	for i, upper := range h.buckets {
+		if v <= upper {
+			s.counts[i]++
+			break
+		}
+	}
+	s.count++
+	s.sum += v
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.header(w, "histogram")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	keys := make(map[string]bool, len(h.series))
+	for k := range h.series {
+		keys[k] = true
+	}
+	for _, k := range sortedKeys(keys) {
+		s := h.series[k]
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.pairs(k, "le", formatFloat(upper)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.pairs(k, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.pairs(k), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.pairs(k), s.count)
+	}
+}
+
+// GaugeFunc reports the value of a function when scraped.
+type GaugeFunc struct {
+	desc
+	fn func() float64
+}
+
+// NewGaugeFunc registers a gauge whose value is fn's result.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{desc: desc{name: name, help: help}, fn: fn}
+	r.register(g)
+	return g
+}
+
+func (g *GaugeFunc) write(w io.Writer) {
+	g.header(w, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(g.fn()))
+}
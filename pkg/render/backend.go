@@ -0,0 +1,106 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Renderers lists the renderers Image accepts.
+var Renderers = []string{"auto", "graphviz", "builtin"}
+
+// Image draws the DOT graph in the given format with the chosen renderer:
+// graphviz, builtin, or auto to use graphviz when the dot program is
+// installed and the builtin renderer otherwise.
+func Image(ctx context.Context, renderer, format string, b []byte) ([]byte, error) {
+	if err := Check(renderer, format); err != nil {
+		return nil, err
+	}
+	if renderer != "builtin" {
+		if _, err := lookPathDot(); err == nil {
+			return Graphviz(ctx, format, b)
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	if err := Render(buf, format, b); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Check reports why the renderer cannot draw the format, or returns nil if
+// it can.
+func Check(renderer, format string) error {
+	_, dotErr := lookPathDot()
+	switch renderer {
+	case "builtin":
+		if !Supports(format) {
+			return fmt.Errorf("the builtin renderer cannot draw %s, only %s; install Graphviz and use the graphviz renderer", format, strings.Join(Formats, " and "))
+		}
+	case "graphviz":
+		if dotErr != nil {
+			return dotErr
+		}
+	case "auto", "":
+		if dotErr != nil && !Supports(format) {
+			return fmt.Errorf("no renderer can draw %s: %v; the builtin renderer only draws %s", format, dotErr, strings.Join(Formats, " and "))
+		}
+	default:
+		return fmt.Errorf("unknown renderer %q, use one of %s", renderer, strings.Join(Renderers, ", "))
+	}
+	return nil
+}
+
+// GraphvizError is returned when the dot program fails.
+type GraphvizError struct {
+	Err    error
+	Stderr string
+}
+
+func (e *GraphvizError) Error() string {
+	return fmt.Sprintf("dot: %v: %s", e.Err, e.Stderr)
+}
+
+var (
+	dotOnce sync.Once
+	dot     string
+	dotErr  error
+)
+
+// lookPathDot finds the dot program once, it is called from concurrent
+// requests.
+func lookPathDot() (string, error) {
+	dotOnce.Do(func() {
+		var err error
+		if dot, err = exec.LookPath("dot"); err != nil {
+			dotErr = fmt.Errorf("unable to find program 'dot', please install it or check your PATH")
+		}
+	})
+	return dot, dotErr
+}
+
+// Graphviz pipes the graph through dot. The process is killed when ctx is
+// done.
+func Graphviz(ctx context.Context, format string, b []byte) ([]byte, error) {
+	bin, err := lookPathDot()
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, bin, fmt.Sprintf("-T%s", format))
+	cmd.Stdin = bytes.NewReader(b)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, &GraphvizError{Err: err, Stderr: strings.TrimSpace(stderr.String())}
+	}
+	return stdout.Bytes(), nil
+}
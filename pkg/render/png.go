@@ -0,0 +1,257 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/n3wscott/knap/pkg/layout"
+)
+
+// pngScale is the number of pixels per point, so the 5x8 font is drawn at a
+// readable size.
+const pngScale = 2
+
+// PNG rasterizes a laid out graph as a PNG image.
+func PNG(w io.Writer, g *layout.Graph) error {
+	width := int(math.Ceil(g.Bounds.Width * pngScale))
+	height := int(math.Ceil(g.Bounds.Height * pngScale))
+	c := &canvas{img: image.NewRGBA(image.Rect(0, 0, width, height))}
+
+	bg := parseColor(g.Attr("bgcolor"), color.RGBA{255, 255, 255, 255})
+	c.fillPolygon([]layout.Point{{X: 0, Y: 0}, {X: g.Bounds.Width}, {X: g.Bounds.Width, Y: g.Bounds.Height}, {Y: g.Bounds.Height}}, bg)
+	c.text(g.LabelPos, g.Label(), black)
+
+	walkClusters(g.Clusters, func(cl *layout.Cluster) {
+		st := styles(cl.Attr("style"))
+		if st["invis"] {
+			return
+		}
+		b := cl.Bounds
+		box := []layout.Point{{X: b.X, Y: b.Y}, {X: b.X + b.Width, Y: b.Y}, {X: b.X + b.Width, Y: b.Y + b.Height}, {X: b.X, Y: b.Y + b.Height}}
+		if st["filled"] {
+			c.fillPolygon(box, parseColor(first(cl.Attr("fillcolor"), cl.Attr("color"), cl.Attr("bgcolor")), lightgrey))
+		} else if cl.Attr("bgcolor") != "" {
+			c.fillPolygon(box, parseColor(cl.Attr("bgcolor"), lightgrey))
+		}
+		c.outline(box, parseColor(first(cl.Attr("pencolor"), cl.Attr("color")), black), 1, st)
+
+		label := cl.Label()
+		c.text(layout.Point{X: b.X + b.Width/2, Y: b.Y + 4 + float64(len(label))*layout.LineHeight/2}, label, parseColor(cl.Attr("fontcolor"), black))
+	})
+
+	for _, e := range g.Edges {
+		st := styles(e.Attr("style"))
+		if st["invis"] {
+			continue
+		}
+		col := parseColor(e.Attr("color"), black)
+		pts, arrows := edgeGeometry(g, e)
+		c.polyline(flatten(pts), col, penWidth(e.Attr("penwidth")), st)
+		for _, a := range arrows {
+			c.fillPolygon(a[:], col)
+		}
+		c.text(e.LabelPos, e.Label(), parseColor(e.Attr("fontcolor"), black))
+	}
+
+	for _, n := range g.Nodes {
+		st := styles(n.Attr("style"))
+		if st["invis"] {
+			continue
+		}
+		shape := n.Shape()
+		var outline []layout.Point
+		switch {
+		case shape == "plaintext" || shape == "plain" || shape == "none":
+		case polygon(n) != nil:
+			outline = polygon(n)
+		default:
+			outline = ellipse(n)
+		}
+		if outline != nil {
+			if st["filled"] {
+				c.fillPolygon(outline, parseColor(first(n.Attr("fillcolor"), n.Attr("color")), lightgrey))
+			}
+			c.outline(outline, parseColor(n.Attr("color"), black), penWidth(n.Attr("penwidth")), st)
+		}
+		c.text(n.Pos, n.Label(), parseColor(n.Attr("fontcolor"), black))
+	}
+
+	return png.Encode(w, c.img)
+}
+
+var (
+	black     = color.RGBA{0, 0, 0, 255}
+	lightgrey = color.RGBA{211, 211, 211, 255}
+)
+
+type canvas struct {
+	img *image.RGBA
+}
+
+// plot blends col into the pixel at x, y.
+func (c *canvas) plot(x, y int, col color.RGBA) {
+	if !(image.Point{X: x, Y: y}.In(c.img.Rect)) || col.A == 0 {
+		return
+	}
+	if col.A == 255 {
+		c.img.SetRGBA(x, y, col)
+		return
+	}
+	dst := c.img.RGBAAt(x, y)
+	a := uint32(col.A)
+	mix := func(s, d uint8) uint8 {
+		return uint8((uint32(s)*a + uint32(d)*(255-a)) / 255)
+	}
+	c.img.SetRGBA(x, y, color.RGBA{mix(col.R, dst.R), mix(col.G, dst.G), mix(col.B, dst.B), 255})
+}
+
+// line draws a line of the given width in points, skipping the gaps of
+// dashed and dotted styles.
+func (c *canvas) line(a, b layout.Point, col color.RGBA, width float64, st map[string]bool, phase *float64) {
+	on, off := 0.0, 0.0
+	switch {
+	case st["dashed"]:
+		on, off = 5, 2
+	case st["dotted"]:
+		on, off = 1, 5
+	}
+
+	r := math.Max(width*pngScale/2, 0.5)
+	length := math.Hypot(b.X-a.X, b.Y-a.Y)
+	steps := int(length*pngScale*2) + 1
+	for i := 0; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		if on > 0 {
+			d := math.Mod(*phase+t*length, on+off)
+			if d > on {
+				continue
+			}
+		}
+		c.dot((a.X+(b.X-a.X)*t)*pngScale, (a.Y+(b.Y-a.Y)*t)*pngScale, r, col)
+	}
+	*phase += length
+}
+
+// dot fills a disc of radius r pixels centered on x, y.
+func (c *canvas) dot(x, y, r float64, col color.RGBA) {
+	for py := int(math.Floor(y - r)); py <= int(math.Ceil(y+r)); py++ {
+		for px := int(math.Floor(x - r)); px <= int(math.Ceil(x+r)); px++ {
+			dx, dy := float64(px)+0.5-x, float64(py)+0.5-y
+			if dx*dx+dy*dy <= r*r {
+				c.plot(px, py, col)
+			}
+		}
+	}
+}
+
+func (c *canvas) polyline(pts []layout.Point, col color.RGBA, width float64, st map[string]bool) {
+	phase := 0.0
+	for i := 0; i+1 < len(pts); i++ {
+		c.line(pts[i], pts[i+1], col, width, st, &phase)
+	}
+}
+
+func (c *canvas) outline(pts []layout.Point, col color.RGBA, width float64, st map[string]bool) {
+	if len(pts) == 0 {
+		return
+	}
+	c.polyline(append(pts, pts[0]), col, width, st)
+}
+
+// fillPolygon fills the polygon with the even-odd rule, one scanline per
+// pixel row.
+func (c *canvas) fillPolygon(pts []layout.Point, col color.RGBA) {
+	if len(pts) < 3 {
+		return
+	}
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, p := range pts {
+		minY, maxY = math.Min(minY, p.Y), math.Max(maxY, p.Y)
+	}
+	for py := int(minY * pngScale); py <= int(maxY*pngScale); py++ {
+		y := (float64(py) + 0.5) / pngScale
+		var xs []float64
+		for i := range pts {
+			a, b := pts[i], pts[(i+1)%len(pts)]
+			if (a.Y <= y) != (b.Y <= y) {
+				xs = append(xs, a.X+(y-a.Y)/(b.Y-a.Y)*(b.X-a.X))
+			}
+		}
+		sort.Float64s(xs)
+		for i := 0; i+1 < len(xs); i += 2 {
+			for px := int(math.Ceil(xs[i]*pngScale - 0.5)); float64(px)+0.5 <= xs[i+1]*pngScale; px++ {
+				c.plot(px, py, col)
+			}
+		}
+	}
+}
+
+// text draws the lines centered on p with the bitmap font.
+func (c *canvas) text(p layout.Point, lines []string, col color.RGBA) {
+	const (
+		cell   = layout.CharWidth * pngScale
+		pixel  = pngScale
+		height = 8 * pixel
+	)
+	top := p.Y*pngScale - float64(len(lines))*layout.LineHeight*pngScale/2
+	for i, line := range lines {
+		runes := []rune(line)
+		x0 := int(p.X*pngScale - float64(len(runes)*cell)/2)
+		y0 := int(top + float64(i)*layout.LineHeight*pngScale + (layout.LineHeight*pngScale-height)/2)
+		for j, r := range runes {
+			g := glyph(r)
+			for gx := 0; gx < 5; gx++ {
+				bits := g[gx]
+				for row := 0; row < 8; row++ {
+					if bits&(1<<uint(row)) == 0 {
+						continue
+					}
+					for dy := 0; dy < pixel; dy++ {
+						for dx := 0; dx < pixel; dx++ {
+							c.plot(x0+j*cell+pixel+gx*pixel+dx, y0+row*pixel+dy, col)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// ellipse approximates the node's ellipse with a polygon.
+func ellipse(n *layout.Node) []layout.Point {
+	const segments = 72
+	pts := make([]layout.Point, segments)
+	for i := range pts {
+		a := 2 * math.Pi * float64(i) / segments
+		pts[i] = layout.Point{
+			X: n.Pos.X + math.Cos(a)*n.Width/2,
+			Y: n.Pos.Y + math.Sin(a)*n.Height/2,
+		}
+	}
+	return pts
+}
+
+// flatten approximates a cubic B-spline with line segments.
+func flatten(pts []layout.Point) []layout.Point {
+	if len(pts) < 4 {
+		return pts
+	}
+	const steps = 16
+	out := []layout.Point{pts[0]}
+	for i := 0; i+3 < len(pts); i += 3 {
+		for s := 1; s <= steps; s++ {
+			t := float64(s) / steps
+			u := 1 - t
+			a, b, c, d := u*u*u, 3*u*u*t, 3*u*t*t, t*t*t
+			out = append(out, layout.Point{
+				X: a*pts[i].X + b*pts[i+1].X + c*pts[i+2].X + d*pts[i+3].X,
+				Y: a*pts[i].Y + b*pts[i+1].Y + c*pts[i+2].Y + d*pts[i+3].Y,
+			})
+		}
+	}
+	return out
+}
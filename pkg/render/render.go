@@ -0,0 +1,181 @@
+// Package render draws DOT graphs, with Graphviz or without it using the
+// layered layout from pkg/layout.
+package render
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/n3wscott/knap/pkg/layout"
+)
+
+// Formats lists the output formats of the builtin renderer.
+var Formats = []string{"svg", "png"}
+
+// Supports reports whether the builtin renderer can produce format.
+func Supports(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// Render lays out the DOT graph and writes it to w in the given format.
+func Render(w io.Writer, format string, dot []byte) error {
+	if !Supports(format) {
+		return fmt.Errorf("builtin renderer does not support format %q", format)
+	}
+	g, err := layout.Parse(dot)
+	if err != nil {
+		return err
+	}
+	layout.Layout(g)
+
+	switch format {
+	case "png":
+		return PNG(w, g)
+	default:
+		return SVG(w, g)
+	}
+}
+
+const (
+	arrowLength = 10
+	arrowWidth  = 3.5
+)
+
+// arrow is an arrowhead, as its tip and the two corners of its base.
+type arrow [3]layout.Point
+
+// edgeGeometry returns the spline of the edge, shortened to make room for its
+// arrowheads, and the arrowheads themselves.
+func edgeGeometry(g *layout.Graph, e *layout.Edge) ([]layout.Point, []arrow) {
+	pts := append([]layout.Point(nil), e.Points...)
+	if len(pts) < 4 {
+		return pts, nil
+	}
+
+	dir := e.Attr("dir")
+	if dir == "" {
+		dir = "none"
+		if g.Directed {
+			dir = "forward"
+		}
+	}
+	head := (dir == "forward" || dir == "both") && e.Attr("arrowhead") != "none"
+	tail := (dir == "back" || dir == "both") && e.Attr("arrowtail") != "none"
+
+	var arrows []arrow
+	if head {
+		n := len(pts)
+		a, tip := shorten(pts[n-2], pts[n-1])
+		pts[n-2], pts[n-1] = a.move(pts[n-2]), a.move(pts[n-1])
+		arrows = append(arrows, arrowAt(tip, a))
+	}
+	if tail {
+		a, tip := shorten(pts[1], pts[0])
+		pts[0], pts[1] = a.move(pts[0]), a.move(pts[1])
+		arrows = append(arrows, arrowAt(tip, a))
+	}
+	return pts, arrows
+}
+
+// offset is the vector an end of a spline is pulled back by.
+type offset layout.Point
+
+func (o offset) move(p layout.Point) layout.Point {
+	return layout.Point{X: p.X + o.X, Y: p.Y + o.Y}
+}
+
+// shorten returns the offset pulling end back towards control by the arrow
+// length, and the original end as the tip of the arrow.
+func shorten(control, end layout.Point) (offset, layout.Point) {
+	dx, dy := end.X-control.X, end.Y-control.Y
+	d := math.Hypot(dx, dy)
+	if d == 0 {
+		return offset{}, end
+	}
+	return offset{X: -dx / d * arrowLength, Y: -dy / d * arrowLength}, end
+}
+
+func arrowAt(tip layout.Point, back offset) arrow {
+	base := back.move(tip)
+	d := math.Hypot(back.X, back.Y)
+	if d == 0 {
+		return arrow{tip, tip, tip}
+	}
+	nx, ny := -back.Y/d*arrowWidth, back.X/d*arrowWidth
+	return arrow{
+		tip,
+		{X: base.X + nx, Y: base.Y + ny},
+		{X: base.X - nx, Y: base.Y - ny},
+	}
+}
+
+// polygon returns the corners of a regular polygon shape fitted to the node,
+// or nil if the shape is not a polygon.
+func polygon(n *layout.Node) []layout.Point {
+	sides := 0
+	rotate := 0.0
+	switch n.Shape() {
+	case "box", "plaintext", "plain", "none", "note", "tab", "folder", "component":
+		x, y, w, h := n.Pos.X-n.Width/2, n.Pos.Y-n.Height/2, n.Width, n.Height
+		return []layout.Point{{X: x, Y: y}, {X: x + w, Y: y}, {X: x + w, Y: y + h}, {X: x, Y: y + h}}
+	case "triangle":
+		sides = 3
+	case "diamond":
+		sides = 4
+	case "pentagon":
+		sides = 5
+	case "hexagon":
+		sides, rotate = 6, math.Pi/6
+	case "septagon":
+		sides = 7
+	case "octagon":
+		sides, rotate = 8, math.Pi/8
+	default:
+		return nil
+	}
+	pts := make([]layout.Point, sides)
+	for i := range pts {
+		a := -math.Pi/2 + rotate + 2*math.Pi*float64(i)/float64(sides)
+		pts[i] = layout.Point{
+			X: n.Pos.X + math.Cos(a)*n.Width/2,
+			Y: n.Pos.Y + math.Sin(a)*n.Height/2,
+		}
+	}
+	return pts
+}
+
+// styles returns the comma separated entries of the style attribute.
+func styles(style string) map[string]bool {
+	s := make(map[string]bool)
+	for _, name := range strings.Split(style, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			s[name] = true
+		}
+	}
+	return s
+}
+
+// penWidth returns the penwidth attribute, defaulting to 1.
+func penWidth(v string) float64 {
+	w, err := strconv.ParseFloat(v, 64)
+	if err != nil || w < 0 {
+		return 1
+	}
+	return w
+}
+
+// walkClusters calls fn for every cluster, parents before their children.
+func walkClusters(cs []*layout.Cluster, fn func(c *layout.Cluster)) {
+	for _, c := range cs {
+		fn(c)
+		walkClusters(c.Clusters, fn)
+	}
+}
@@ -0,0 +1,78 @@
+package render
+
+import (
+	"bytes"
+	"flag"
+	"image/png"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+func TestSVGGolden(t *testing.T) {
+	src, err := ioutil.ReadFile(filepath.Join("testdata", "broker.dot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got bytes.Buffer
+	if err := Render(&got, "svg", src); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	golden := filepath.Join("testdata", "broker.svg")
+	if *update {
+		if err := ioutil.WriteFile(golden, got.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v, run the test with -update to create it", err)
+	}
+	if !bytes.Equal(got.Bytes(), want) {
+		t.Errorf("SVG differs from %s, run the test with -update and review the diff:\n%s", golden, got.String())
+	}
+}
+
+func TestSVGIsDeterministic(t *testing.T) {
+	src, err := ioutil.ReadFile(filepath.Join("testdata", "broker.dot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var a, b bytes.Buffer
+	if err := Render(&a, "svg", src); err != nil {
+		t.Fatal(err)
+	}
+	if err := Render(&b, "svg", src); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Error("rendering the same graph twice gave different SVGs")
+	}
+}
+
+func TestPNG(t *testing.T) {
+	src, err := ioutil.ReadFile(filepath.Join("testdata", "broker.dot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := Render(&buf, "png", src); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("not a PNG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() == 0 || b.Dy() == 0 {
+		t.Errorf("empty image %v", b)
+	}
+}
+
+func TestRenderUnsupportedFormat(t *testing.T) {
+	if err := Render(ioutil.Discard, "pdf", []byte("digraph { a }")); err == nil {
+		t.Error("Render(pdf) returned no error")
+	}
+}
@@ -0,0 +1,254 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/n3wscott/knap/pkg/layout"
+)
+
+const fontFamily = "Helvetica,Arial,sans-serif"
+
+// SVG writes a laid out graph as SVG. The document follows the structure of
+// Graphviz's SVG output: one group per cluster, node and edge, with a title
+// naming it.
+func SVG(w io.Writer, g *layout.Graph) error {
+	s := &svgWriter{w: bufio.NewWriter(w)}
+
+	width, height := g.Bounds.Width, g.Bounds.Height
+	s.printf("<?xml version=\"1.0\" encoding=\"UTF-8\" standalone=\"no\"?>\n")
+	s.printf("<svg width=\"%.0fpt\" height=\"%.0fpt\" viewBox=\"0.00 0.00 %.2f %.2f\" xmlns=\"http://www.w3.org/2000/svg\" xmlns:xlink=\"http://www.w3.org/1999/xlink\">\n",
+		width, height, width, height)
+	s.printf("<g id=\"graph0\" class=\"graph\">\n")
+	s.printf("<title>%s</title>\n", esc(g.Name))
+	s.printf("<rect x=\"0\" y=\"0\" width=\"%.2f\" height=\"%.2f\" fill=\"%s\" stroke=\"none\"/>\n",
+		width, height, svgColor(g.Attr("bgcolor"), "white"))
+	s.text(g.LabelPos, g.Label(), "black")
+
+	i := 0
+	walkClusters(g.Clusters, func(c *layout.Cluster) {
+		i++
+		s.cluster(i, c)
+	})
+	for i, n := range g.Nodes {
+		s.node(i+1, n)
+	}
+	for i, e := range g.Edges {
+		s.edge(i+1, g, e)
+	}
+
+	s.printf("</g>\n</svg>\n")
+	if s.err != nil {
+		return s.err
+	}
+	return s.w.Flush()
+}
+
+type svgWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (s *svgWriter) printf(format string, args ...interface{}) {
+	if s.err != nil {
+		return
+	}
+	_, s.err = fmt.Fprintf(s.w, format, args...)
+}
+
+func (s *svgWriter) open(id, class, title string) {
+	s.printf("<g id=\"%s\" class=\"%s\">\n<title>%s</title>\n", esc(id), class, esc(title))
+}
+
+func (s *svgWriter) cluster(i int, c *layout.Cluster) {
+	st := styles(c.Attr("style"))
+	if st["invis"] {
+		return
+	}
+	id := c.Attr("id")
+	if id == "" {
+		id = fmt.Sprintf("clust%d", i)
+	}
+	s.open(id, "cluster", c.ID)
+
+	fill := "none"
+	if st["filled"] {
+		fill = svgColor(first(c.Attr("fillcolor"), c.Attr("color"), c.Attr("bgcolor")), "lightgrey")
+	} else if c.Attr("bgcolor") != "" {
+		fill = svgColor(c.Attr("bgcolor"), "none")
+	}
+	r := ""
+	if st["rounded"] {
+		r = ` rx="8" ry="8"`
+	}
+	b := c.Bounds
+	s.printf("<rect x=\"%.2f\" y=\"%.2f\" width=\"%.2f\" height=\"%.2f\"%s fill=\"%s\" stroke=\"%s\"%s/>\n",
+		b.X, b.Y, b.Width, b.Height, r, fill, svgColor(first(c.Attr("pencolor"), c.Attr("color")), "black"), dash(st))
+
+	label := c.Label()
+	center := layout.Point{
+		X: b.X + b.Width/2,
+		Y: b.Y + 4 + float64(len(label))*layout.LineHeight/2,
+	}
+	s.text(center, label, svgColor(c.Attr("fontcolor"), "black"))
+	s.printf("</g>\n")
+}
+
+func (s *svgWriter) node(i int, n *layout.Node) {
+	st := styles(n.Attr("style"))
+	if st["invis"] {
+		return
+	}
+	id := n.Attr("id")
+	if id == "" {
+		id = fmt.Sprintf("node%d", i)
+	}
+	s.open(id, "node", n.ID)
+	linked := s.link(id, n.Attrs)
+
+	fill := "none"
+	if st["filled"] {
+		fill = svgColor(first(n.Attr("fillcolor"), n.Attr("color")), "lightgrey")
+	}
+	stroke := svgColor(n.Attr("color"), "black")
+	pen := fmt.Sprintf(" fill=\"%s\" stroke=\"%s\" stroke-width=\"%g\"%s", fill, stroke, penWidth(n.Attr("penwidth")), dash(st))
+
+	switch shape := n.Shape(); {
+	case shape == "plaintext" || shape == "plain" || shape == "none":
+	case shape == "box" && st["rounded"]:
+		s.printf("<rect x=\"%.2f\" y=\"%.2f\" width=\"%.2f\" height=\"%.2f\" rx=\"8\" ry=\"8\"%s/>\n",
+			n.Pos.X-n.Width/2, n.Pos.Y-n.Height/2, n.Width, n.Height, pen)
+	case polygon(n) != nil:
+		s.printf("<polygon points=\"%s\"%s/>\n", points(polygon(n)), pen)
+	case shape == "circle" || shape == "doublecircle":
+		r := n.Height / 2
+		if n.Width > n.Height {
+			r = n.Width / 2
+		}
+		s.printf("<ellipse cx=\"%.2f\" cy=\"%.2f\" rx=\"%.2f\" ry=\"%.2f\"%s/>\n", n.Pos.X, n.Pos.Y, r, r, pen)
+	default:
+		s.printf("<ellipse cx=\"%.2f\" cy=\"%.2f\" rx=\"%.2f\" ry=\"%.2f\"%s/>\n",
+			n.Pos.X, n.Pos.Y, n.Width/2, n.Height/2, pen)
+	}
+	s.text(n.Pos, n.Label(), svgColor(n.Attr("fontcolor"), "black"))
+	if linked {
+		s.printf("</a>\n</g>\n")
+	}
+	s.printf("</g>\n")
+}
+
+// link opens an anchor for the URL and tooltip attributes, as Graphviz does,
+// and reports whether it did.
+func (s *svgWriter) link(id string, attrs map[string]string) bool {
+	url := first(attrs["URL"], attrs["href"])
+	tooltip := attrs["tooltip"]
+	if url == "" && tooltip == "" {
+		return false
+	}
+	s.printf("<g id=\"a_%s\"><a", esc(id))
+	if url != "" {
+		s.printf(" xlink:href=\"%s\"", esc(url))
+	}
+	if tooltip != "" {
+		lines := layout.Lines(tooltip)
+		for i := range lines {
+			lines[i] = esc(lines[i])
+		}
+		s.printf(" xlink:title=\"%s\"", strings.Join(lines, "&#10;"))
+	}
+	if target := attrs["target"]; target != "" {
+		s.printf(" target=\"%s\"", esc(target))
+	}
+	s.printf(">\n")
+	return true
+}
+
+func (s *svgWriter) edge(i int, g *layout.Graph, e *layout.Edge) {
+	st := styles(e.Attr("style"))
+	if st["invis"] {
+		return
+	}
+	id := e.Attr("id")
+	if id == "" {
+		id = fmt.Sprintf("edge%d", i)
+	}
+	op := "--"
+	if g.Directed {
+		op = "->"
+	}
+	s.open(id, "edge", e.Tail.ID+op+e.Head.ID)
+
+	pts, arrows := edgeGeometry(g, e)
+	color := svgColor(e.Attr("color"), "black")
+	if len(pts) > 0 {
+		var d strings.Builder
+		fmt.Fprintf(&d, "M%.2f,%.2f", pts[0].X, pts[0].Y)
+		for i := 1; i+2 < len(pts); i += 3 {
+			fmt.Fprintf(&d, " C%.2f,%.2f %.2f,%.2f %.2f,%.2f",
+				pts[i].X, pts[i].Y, pts[i+1].X, pts[i+1].Y, pts[i+2].X, pts[i+2].Y)
+		}
+		s.printf("<path fill=\"none\" stroke=\"%s\" stroke-width=\"%g\"%s d=\"%s\"/>\n",
+			color, penWidth(e.Attr("penwidth")), dash(st), d.String())
+	}
+	for _, a := range arrows {
+		s.printf("<polygon fill=\"%s\" stroke=\"%s\" points=\"%s\"/>\n", color, color, points(a[:]))
+	}
+	s.text(e.LabelPos, e.Label(), svgColor(e.Attr("fontcolor"), "black"))
+	s.printf("</g>\n")
+}
+
+// text writes the lines centered on c.
+func (s *svgWriter) text(c layout.Point, lines []string, color string) {
+	y := c.Y - float64(len(lines)-1)*layout.LineHeight/2 + layout.FontSize/3
+	for _, line := range lines {
+		s.printf("<text text-anchor=\"middle\" x=\"%.2f\" y=\"%.2f\" font-family=\"%s\" font-size=\"%d\" fill=\"%s\">%s</text>\n",
+			c.X, y, fontFamily, layout.FontSize, color, esc(line))
+		y += layout.LineHeight
+	}
+}
+
+func points(pts []layout.Point) string {
+	s := make([]string, len(pts))
+	for i, p := range pts {
+		s[i] = fmt.Sprintf("%.2f,%.2f", p.X, p.Y)
+	}
+	return strings.Join(s, " ")
+}
+
+func dash(st map[string]bool) string {
+	switch {
+	case st["dashed"]:
+		return ` stroke-dasharray="5,2"`
+	case st["dotted"]:
+		return ` stroke-dasharray="1,5"`
+	}
+	return ""
+}
+
+// svgColor converts a Graphviz color to SVG, which understands the same
+// names and hex notation.
+func svgColor(c, def string) string {
+	switch c {
+	case "":
+		return def
+	case "transparent", "invis":
+		return "none"
+	}
+	return esc(c)
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func esc(s string) string {
+	return html.EscapeString(s)
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/n3wscott/knap/pkg/graph"
+)
+
+// apiPrefix is the root of the versioned API. Under it:
+//
+//	GET {ns}/nodes[?kind=Trigger]       the nodes, optionally of one kind
+//	GET {ns}/nodes/{key}                one node with its status and object
+//	GET {ns}/edges?node={key}           the edges into and out of a node
+//	GET {ns}/path?from={key}&to={key}   the shortest path events take
+//
+// Node keys are group/version/kind/name, for example
+// serving.knative.dev/v1alpha1/service/display.
+const apiPrefix = "/api/v1/namespaces/"
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+type edgesResponse struct {
+	In  []graph.Edge `json:"in"`
+	Out []graph.Edge `json:"out"`
+}
+
+type pathResponse struct {
+	Path []graph.Edge `json:"path"`
+}
+
+func (s *Server) api(w http.ResponseWriter, r *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, apiPrefix), "/", 3)
+	if len(parts) < 2 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	ns, resource := parts[0], parts[1]
+
+	if !s.allowed(ns) {
+		writeError(w, http.StatusForbidden, "namespace "+ns+" is not allowed")
+		return
+	}
+	authz, ok := s.authorize(w, r, ns)
+	if !ok {
+		return
+	}
+
+	opts := []graph.Option{
+		graph.WithAuthorizer(s.self),
+		graph.WithAuthorizer(authz),
+		graph.WithSourceInformers(s.sourceInformers(ns)),
+	}
+	if internals := getQueryParam(r, "internals"); internals == "expand" || internals == "true" {
+		opts = append(opts, graph.ExpandInternals())
+	}
+	g := graph.LoadTopology(s.client, ns, opts...)
+
+	switch {
+	case resource == "nodes" && len(parts) == 2:
+		kind := getQueryParam(r, "kind")
+		nodes := make([]*graph.Node, 0)
+		for _, n := range g.Nodes() {
+			if kind == "" || strings.EqualFold(n.Kind, kind) {
+				nodes = append(nodes, n.Summary())
+			}
+		}
+		writeJSON(w, nodes)
+
+	case resource == "nodes":
+		n, ok := g.Node(parts[2])
+		if !ok {
+			writeError(w, http.StatusNotFound, "node "+parts[2]+" not found")
+			return
+		}
+		writeJSON(w, n)
+
+	case resource == "edges" && len(parts) == 2:
+		n, ok := g.Node(getQueryParam(r, "node"))
+		if !ok {
+			writeError(w, http.StatusNotFound, "node "+getQueryParam(r, "node")+" not found")
+			return
+		}
+		in, out := g.Edges(n.Key)
+		writeJSON(w, edgesResponse{In: nonNil(in), Out: nonNil(out)})
+
+	case resource == "path" && len(parts) == 2:
+		from, ok := g.Node(getQueryParam(r, "from"))
+		if !ok {
+			writeError(w, http.StatusNotFound, "node "+getQueryParam(r, "from")+" not found")
+			return
+		}
+		to, ok := g.Node(getQueryParam(r, "to"))
+		if !ok {
+			writeError(w, http.StatusNotFound, "node "+getQueryParam(r, "to")+" not found")
+			return
+		}
+		path := g.Path(from.Key, to.Key)
+		if path == nil {
+			writeError(w, http.StatusNotFound, "no path from "+from.Key+" to "+to.Key)
+			return
+		}
+		writeJSON(w, pathResponse{Path: path})
+
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func nonNil(edges []graph.Edge) []graph.Edge {
+	if edges == nil {
+		return []graph.Edge{}
+	}
+	return edges
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("unable to write json.")
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(apiError{Error: msg})
+}
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/n3wscott/knap/pkg/knative"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+)
+
+var errUnauthenticated = errors.New("unauthenticated")
+
+// tokenTTL is how long the review of a token is remembered, so that pages
+// and event stream reconnects do not each send a TokenReview.
+const tokenTTL = 30 * time.Second
+
+// tokenCache remembers the reviews of bearer tokens by their hash.
+type tokenCache struct {
+	mu      sync.Mutex
+	reviews map[[sha256.Size]byte]tokenReview
+}
+
+type tokenReview struct {
+	id *identity // nil if the token was rejected
+	at time.Time
+}
+
+func newTokenCache() *tokenCache {
+	return &tokenCache{reviews: make(map[[sha256.Size]byte]tokenReview)}
+}
+
+// review returns the identity of the token, asking review only if the token
+// was not reviewed within the TTL. Rejected tokens are remembered too,
+// failed reviews are not.
+func (c *tokenCache) review(token string, review func(string) (*identity, error)) (*identity, error) {
+	key := sha256.Sum256([]byte(token))
+	now := time.Now()
+
+	c.mu.Lock()
+	r, ok := c.reviews[key]
+	c.mu.Unlock()
+	if ok && now.Sub(r.at) < tokenTTL {
+		if r.id == nil {
+			return nil, errUnauthenticated
+		}
+		return r.id, nil
+	}
+
+	id, err := review(token)
+	if err != nil && err != errUnauthenticated {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, r := range c.reviews {
+		if now.Sub(r.at) >= tokenTTL {
+			delete(c.reviews, k)
+		}
+	}
+	c.reviews[key] = tokenReview{id: id, at: now}
+	return id, err
+}
+
+// identity is the caller the graph is drawn for.
+type identity struct {
+	user   string
+	uid    string
+	groups []string
+	extra  map[string]authorizationv1.ExtraValue
+}
+
+// authenticate identifies the caller according to the AUTH setting. With
+// "none" everyone sees what knap's service account sees. With "token" the
+// bearer token is checked with a TokenReview. With "header" the user and
+// groups are read from headers, which must be set by a trusted authenticating
+// proxy in front of knap. A nil identity means no authorization checks are
+// made.
+func (s *Server) authenticate(r *http.Request) (*identity, error) {
+	switch s.env.Auth {
+	case "", "none":
+		return nil, nil
+
+	case "token":
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") {
+			return nil, errUnauthenticated
+		}
+		return s.tokens.review(strings.TrimPrefix(auth, "Bearer "), s.reviewToken)
+
+	case "header":
+		user := r.Header.Get(s.env.UserHeader)
+		if user == "" {
+			return nil, errUnauthenticated
+		}
+		id := &identity{user: user}
+		for _, v := range r.Header[http.CanonicalHeaderKey(s.env.GroupsHeader)] {
+			for _, group := range strings.Split(v, ",") {
+				if group = strings.TrimSpace(group); group != "" {
+					id.groups = append(id.groups, group)
+				}
+			}
+		}
+		return id, nil
+	}
+	return nil, fmt.Errorf("unknown auth mode %q", s.env.Auth)
+}
+
+// reviewToken identifies the caller the token belongs to with a TokenReview.
+func (s *Server) reviewToken(token string) (*identity, error) {
+	tr, err := s.kube.AuthenticationV1().TokenReviews().Create(&authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !tr.Status.Authenticated {
+		return nil, errUnauthenticated
+	}
+	id := &identity{
+		user:   tr.Status.User.Username,
+		uid:    tr.Status.User.UID,
+		groups: tr.Status.User.Groups,
+		extra:  make(map[string]authorizationv1.ExtraValue),
+	}
+	for k, v := range tr.Status.User.Extra {
+		id.extra[k] = authorizationv1.ExtraValue(v)
+	}
+	return id, nil
+}
+
+// authorizer checks with SubjectAccessReviews whether the caller may list a
+// resource, remembering the answers for the rest of the request.
+func (id *identity) authorizer(kube kubernetes.Interface) knative.Authorizer {
+	if id == nil {
+		return nil
+	}
+	answers := make(map[string]bool)
+	return func(namespace string, gvr schema.GroupVersionResource) bool {
+		key := namespace + "/" + gvr.String()
+		if allowed, ok := answers[key]; ok {
+			return allowed
+		}
+		sar, err := kube.AuthorizationV1().SubjectAccessReviews().Create(&authorizationv1.SubjectAccessReview{
+			Spec: authorizationv1.SubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace: namespace,
+					Verb:      "list",
+					Group:     gvr.Group,
+					Version:   gvr.Version,
+					Resource:  gvr.Resource,
+				},
+				User:   id.user,
+				UID:    id.uid,
+				Groups: id.groups,
+				Extra:  id.extra,
+			},
+		})
+		if err != nil {
+			log.Printf("SubjectAccessReview for %s failed, %v", id.user, err)
+			return false
+		}
+		answers[key] = sar.Status.Allowed
+		return sar.Status.Allowed
+	}
+}
+
+// identify authenticates the caller, writing an error and returning false if
+// that fails.
+func (s *Server) identify(w http.ResponseWriter, r *http.Request) (*identity, bool) {
+	id, err := s.authenticate(r)
+	if err == errUnauthenticated {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="knap"`)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return nil, false
+	} else if err != nil {
+		log.Printf("authenticate error %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	return id, true
+}
+
+// authorize identifies the caller and makes sure they may see something in
+// the namespace, writing an error and returning false if not.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request, ns string) (knative.Authorizer, bool) {
+	id, ok := s.identify(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	authz := id.authorizer(s.kube)
+	if !knative.New(s.client, knative.WithAuthorizer(authz)).CanList(ns) {
+		http.Error(w, "not allowed to list anything in namespace "+ns, http.StatusForbidden)
+		return nil, false
+	}
+	return authz, true
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTokenCache(t *testing.T) {
+	c := newTokenCache()
+	calls := make(map[string]int)
+	review := func(token string) (*identity, error) {
+		calls[token]++
+		switch token {
+		case "good":
+			return &identity{user: "jane"}, nil
+		case "bad":
+			return nil, errUnauthenticated
+		}
+		return nil, errors.New("API server unreachable")
+	}
+
+	for i := 0; i < 3; i++ {
+		if id, err := c.review("good", review); err != nil || id.user != "jane" {
+			t.Errorf("review(good) = %v, %v", id, err)
+		}
+		if _, err := c.review("bad", review); err != errUnauthenticated {
+			t.Errorf("review(bad) error = %v, want %v", err, errUnauthenticated)
+		}
+		if _, err := c.review("down", review); err == nil {
+			t.Error("review(down) did not fail")
+		}
+	}
+
+	want := map[string]int{"good": 1, "bad": 1, "down": 3}
+	for token, n := range want {
+		if calls[token] != n {
+			t.Errorf("%s reviewed %d times, want %d", token, calls[token], n)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxCacheEntries bounds the number of rendered images kept in memory.
+const maxCacheEntries = 64
+
+// ageBucket is how long a render is reused for an unchanged topology. The
+// tooltips of an image tell the age of each resource, which a render cached
+// for as long as the topology does not change would get wrong.
+const ageBucket = time.Minute
+
+// renderVersion is the version a render of the topology at resourceVersion
+// is cached for at now. It changes every ageBucket.
+func renderVersion(resourceVersion string, now time.Time) string {
+	return resourceVersion + "@" + strconv.FormatInt(now.Truncate(ageBucket).Unix(), 10)
+}
+
+// renderCache holds the latest image for each request key. Identical
+// concurrent requests share a single render.
+type renderCache struct {
+	mu      sync.Mutex
+	timeout time.Duration
+	entries map[string]*renderCall
+	order   []string // keys, oldest first
+}
+
+// renderCall is an image being rendered, or already rendered, for one
+// version of the topology.
+type renderCall struct {
+	version string
+	done    chan struct{}
+	img     []byte
+	err     error
+
+	waiters  int
+	cancel   context.CancelFunc
+	canceled bool
+}
+
+func newRenderCache(timeout time.Duration) *renderCache {
+	return &renderCache{
+		timeout: timeout,
+		entries: make(map[string]*renderCall),
+	}
+}
+
+// get returns the image for key at the given topology version, rendering it
+// with fn unless it is cached or already being rendered. The render is
+// canceled once every waiting request has gone away, or after the timeout.
+func (c *renderCache) get(ctx context.Context, key, version string, fn func(ctx context.Context) ([]byte, error)) ([]byte, bool, error) {
+	c.mu.Lock()
+	call, ok := c.entries[key]
+	if ok && call.version == version && !call.canceled {
+		select {
+		case <-call.done:
+			c.mu.Unlock()
+			return call.img, true, call.err
+		default:
+		}
+	} else {
+		call = c.start(key, version, fn)
+	}
+	call.waiters++
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+		c.mu.Lock()
+		call.waiters--
+		c.mu.Unlock()
+		return call.img, false, call.err
+	case <-ctx.Done():
+		c.mu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			call.canceled = true
+			call.cancel()
+		}
+		c.mu.Unlock()
+		return nil, false, ctx.Err()
+	}
+}
+
+// start begins rendering key in the background. c.mu must be held.
+func (c *renderCache) start(key, version string, fn func(ctx context.Context) ([]byte, error)) *renderCall {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	call := &renderCall{
+		version: version,
+		done:    make(chan struct{}),
+		cancel:  cancel,
+	}
+	c.put(key, call)
+
+	go func() {
+		img, err := fn(ctx)
+		cancel()
+
+		c.mu.Lock()
+		call.img, call.err = img, err
+		if err != nil && c.entries[key] == call {
+			// Failures are not cached, the next request tries again.
+			c.remove(key)
+		}
+		c.mu.Unlock()
+		close(call.done)
+	}()
+	return call
+}
+
+// put stores call under key, evicting the oldest entries beyond the limit.
+// c.mu must be held.
+func (c *renderCache) put(key string, call *renderCall) {
+	if _, ok := c.entries[key]; ok {
+		c.remove(key)
+	}
+	c.entries[key] = call
+	c.order = append(c.order, key)
+	for len(c.order) > maxCacheEntries {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+}
+
+// remove drops key from the cache. c.mu must be held.
+func (c *renderCache) remove(key string) {
+	delete(c.entries, key)
+	for i, k := range c.order {
+		if k == key {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+}
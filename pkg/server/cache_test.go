@@ -0,0 +1,35 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCachedRendersExpire(t *testing.T) {
+	c := newRenderCache(time.Minute)
+	renders := 0
+	render := func(ctx context.Context) ([]byte, error) {
+		renders++
+		return []byte("image"), nil
+	}
+
+	start := time.Date(2019, 3, 1, 12, 0, 10, 0, time.UTC)
+	for _, at := range []time.Time{
+		start,
+		start.Add(30 * time.Second),
+		start.Add(ageBucket),
+	} {
+		if _, _, err := c.get(context.Background(), "demo/triggers/svg", renderVersion("v1", at), render); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The second request is within the first bucket, the third in the
+	// next, when the ages drawn may have changed.
+	if renders != 2 {
+		t.Errorf("rendered %d times, want 2", renders)
+	}
+	if n := len(c.entries); n != 1 {
+		t.Errorf("%d entries, want the latest render only", n)
+	}
+}
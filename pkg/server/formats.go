@@ -0,0 +1,126 @@
+package server
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// outputFormat is a way the graph can be served.
+type outputFormat struct {
+	name      string
+	ext       string
+	mediaType string
+
+	// image formats are drawn from the dot graph by a renderer.
+	image bool
+}
+
+var outputFormats = []outputFormat{
+	{name: "svg", ext: "svg", mediaType: "image/svg+xml", image: true},
+	{name: "png", ext: "png", mediaType: "image/png", image: true},
+	{name: "pdf", ext: "pdf", mediaType: "application/pdf", image: true},
+	{name: "dot", ext: "dot", mediaType: "text/vnd.graphviz"},
+	{name: "json", ext: "json", mediaType: "application/json"},
+	{name: "mermaid", ext: "mmd", mediaType: "text/vnd.mermaid"},
+}
+
+func formatByName(name string) (outputFormat, bool) {
+	for _, f := range outputFormats {
+		if f.name == name || f.ext == name {
+			return f, true
+		}
+	}
+	return outputFormat{}, false
+}
+
+func formatByMediaType(mediaType string) (outputFormat, bool) {
+	for _, f := range outputFormats {
+		if f.mediaType == mediaType {
+			return f, true
+		}
+	}
+	return outputFormat{}, false
+}
+
+// contentType is the Content-Type header for the format.
+func (f outputFormat) contentType() string {
+	if strings.HasPrefix(f.mediaType, "text/") {
+		return f.mediaType + "; charset=utf-8"
+	}
+	return f.mediaType
+}
+
+// filename names a download of the graph of the namespace.
+func (f outputFormat) filename(ns, focus string, at time.Time) string {
+	return fmt.Sprintf("knap-%s-%s-%s.%s", ns, focus, at.UTC().Format("20060102-150405"), f.ext)
+}
+
+// offers are the media types the graph handler can negotiate, the html page
+// first so that */* gets it.
+func offers() []string {
+	o := []string{"text/html"}
+	for _, f := range outputFormats {
+		o = append(o, f.mediaType)
+	}
+	return o
+}
+
+// negotiate returns the offered media type the Accept header prefers, or ""
+// if none is acceptable. An empty header accepts the first offer.
+func negotiate(accept string, offers []string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+
+	type mediaRange struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		// The most specific matching range sets the quality of an offer.
+		q, specificity := 0.0, -1
+		for _, r := range ranges {
+			s := -1
+			switch {
+			case r.mediaType == offer:
+				s = 2
+			case strings.HasSuffix(r.mediaType, "/*") && strings.HasPrefix(offer, strings.TrimSuffix(r.mediaType, "*")):
+				s = 1
+			case r.mediaType == "*/*":
+				s = 0
+			}
+			if s > specificity {
+				q, specificity = r.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// writeDownload sets the headers that save the response as a file.
+func writeDownload(w http.ResponseWriter, filename string) {
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+}
@@ -0,0 +1,425 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/n3wscott/knap/pkg/graph"
+	"github.com/n3wscott/knap/pkg/graphql"
+	"github.com/n3wscott/knap/pkg/knative"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// To use:
+//   curl -d '{"query": "{ triggers { name broker { name } subscriber { key } } }"}' localhost:8080/graphql
+//
+// Every root field takes an optional namespace, which defaults to the pod's.
+// Source fields also take a kind, and node takes a key as in the REST API.
+
+// gqlNode is a node served by the GraphQL endpoint, with the graph it is in
+// so relationships can be followed.
+type gqlNode struct {
+	*graph.Node
+	g *graph.Graph
+}
+
+type gqlEdge struct {
+	graph.Edge
+	g *graph.Graph
+}
+
+// topology loads each namespace a query asks for once, as the caller.
+type topology struct {
+	s         *Server
+	authz     knative.Authorizer
+	internals bool
+	graphs    map[string]*graph.Graph
+}
+
+type topologyKey struct{}
+
+func (t *topology) load(ns string) (*graph.Graph, error) {
+	if ns == "" {
+		ns = t.s.env.Namespace
+	}
+	if g, ok := t.graphs[ns]; ok {
+		return g, nil
+	}
+	if !t.s.allowed(ns) {
+		return nil, fmt.Errorf("namespace %s is not allowed", ns)
+	}
+	if !knative.New(t.s.client, knative.WithAuthorizer(t.authz)).CanList(ns) {
+		return nil, fmt.Errorf("not allowed to list anything in namespace %s", ns)
+	}
+
+	opts := []graph.Option{
+		graph.WithAuthorizer(t.s.self),
+		graph.WithAuthorizer(t.authz),
+		graph.WithSourceInformers(t.s.sourceInformers(ns)),
+	}
+	if t.internals {
+		opts = append(opts, graph.ExpandInternals())
+	}
+	g := graph.LoadTopology(t.s.client, ns, opts...)
+	t.graphs[ns] = g
+	return g, nil
+}
+
+var (
+	nodeInterface = &graphql.Interface{Name: "Node"}
+
+	brokerType       = &graphql.Object{Name: "Broker", Interfaces: []*graphql.Interface{nodeInterface}}
+	triggerType      = &graphql.Object{Name: "Trigger", Interfaces: []*graphql.Interface{nodeInterface}}
+	sourceType       = &graphql.Object{Name: "Source", Interfaces: []*graphql.Interface{nodeInterface}}
+	channelType      = &graphql.Object{Name: "Channel", Interfaces: []*graphql.Interface{nodeInterface}}
+	subscriptionType = &graphql.Object{Name: "Subscription", Interfaces: []*graphql.Interface{nodeInterface}}
+	eventTypeType    = &graphql.Object{Name: "EventType", Interfaces: []*graphql.Interface{nodeInterface}}
+	serviceType      = &graphql.Object{Name: "Service", Interfaces: []*graphql.Interface{nodeInterface}}
+
+	// referenceType is anything else a resource points at: an address, an
+	// object that was not loaded or a hidden placeholder.
+	referenceType = &graphql.Object{Name: "Reference", Interfaces: []*graphql.Interface{nodeInterface}}
+
+	edgeType = &graphql.Object{Name: "Edge"}
+
+	gqlSchema = newSchema()
+)
+
+func newSchema() *graphql.Schema {
+	nodeInterface.Fields = nodeFields(nil)
+	nodeInterface.ResolveType = func(v interface{}) *graphql.Object {
+		if n, ok := v.(gqlNode); ok {
+			return typeOf(n.Node)
+		}
+		return nil
+	}
+
+	brokerType.Fields = nodeFields(graphql.Fields{
+		"address":    {Type: graphql.String, Resolve: objectField("status", "address", "hostname")},
+		"triggers":   {Type: graphql.ListOf(triggerType), Resolve: related(false, "trigger", triggerType)},
+		"eventTypes": {Type: graphql.ListOf(eventTypeType), Resolve: brokerEventTypes},
+	})
+	triggerType.Fields = nodeFields(graphql.Fields{
+		"broker":        {Type: brokerType, Resolve: first(related(true, "trigger", brokerType))},
+		"subscriber":    {Type: nodeInterface, Resolve: first(related(false, "subscriber", nil))},
+		"subscriberURI": {Type: graphql.String, Resolve: objectField("status", "subscriberURI")},
+		"filter":        {Type: graphql.JSON, Resolve: objectField("spec", "filter")},
+	})
+	sourceType.Fields = nodeFields(graphql.Fields{
+		"sink":    {Type: nodeInterface, Resolve: first(related(false, "sink", nil))},
+		"sinkURI": {Type: graphql.String, Resolve: objectField("status", "sinkUri")},
+	})
+	channelType.Fields = nodeFields(graphql.Fields{
+		"address":       {Type: graphql.String, Resolve: objectField("status", "address", "hostname")},
+		"subscriptions": {Type: graphql.ListOf(subscriptionType), Resolve: related(false, "subscription", subscriptionType)},
+	})
+	subscriptionType.Fields = nodeFields(graphql.Fields{
+		"channel":    {Type: channelType, Resolve: first(related(true, "subscription", channelType))},
+		"subscriber": {Type: nodeInterface, Resolve: first(related(false, "subscriber", nil))},
+		"reply":      {Type: nodeInterface, Resolve: first(related(false, "reply", nil))},
+	})
+	eventTypeType.Fields = nodeFields(graphql.Fields{
+		"type":        {Type: graphql.String, Resolve: objectField("spec", "type")},
+		"source":      {Type: graphql.String, Resolve: objectField("spec", "source")},
+		"schema":      {Type: graphql.String, Resolve: objectField("spec", "schema")},
+		"description": {Type: graphql.String, Resolve: objectField("spec", "description")},
+		"broker":      {Type: brokerType, Resolve: eventTypeBroker},
+	})
+	serviceType.Fields = nodeFields(graphql.Fields{
+		"domain":        {Type: graphql.String, Resolve: objectField("status", "domain")},
+		"address":       {Type: graphql.String, Resolve: objectField("status", "address", "hostname")},
+		"sinks":         {Type: graphql.ListOf(nodeInterface), Resolve: related(false, "sink", nil)},
+		"triggers":      {Type: graphql.ListOf(triggerType), Resolve: related(true, "subscriber", triggerType)},
+		"subscriptions": {Type: graphql.ListOf(subscriptionType), Resolve: related(true, "subscriber", subscriptionType)},
+	})
+	referenceType.Fields = nodeFields(nil)
+
+	edgeType.Fields = graphql.Fields{
+		"relation": {Type: graphql.String, Resolve: func(p graphql.Params) (interface{}, error) {
+			return p.Source.(gqlEdge).Relation, nil
+		}},
+		"from": {Type: nodeInterface, Resolve: func(p graphql.Params) (interface{}, error) {
+			e := p.Source.(gqlEdge)
+			return lookup(e.g, e.From), nil
+		}},
+		"to": {Type: nodeInterface, Resolve: func(p graphql.Params) (interface{}, error) {
+			e := p.Source.(gqlEdge)
+			return lookup(e.g, e.To), nil
+		}},
+	}
+
+	query := &graphql.Object{Name: "Query", Fields: graphql.Fields{
+		"node": {Type: nodeInterface, Args: []string{"namespace", "key"}, Resolve: func(p graphql.Params) (interface{}, error) {
+			g, err := load(p)
+			if err != nil {
+				return nil, err
+			}
+			return lookup(g, p.String("key")), nil
+		}},
+		"nodes": {Type: graphql.ListOf(nodeInterface), Args: []string{"namespace", "kind"}, Resolve: func(p graphql.Params) (interface{}, error) {
+			g, err := load(p)
+			if err != nil {
+				return nil, err
+			}
+			var nodes []gqlNode
+			for _, n := range g.Nodes() {
+				if kind := p.String("kind"); kind == "" || strings.EqualFold(n.Kind, kind) {
+					nodes = append(nodes, gqlNode{Node: n, g: g})
+				}
+			}
+			return nodes, nil
+		}},
+	}}
+	for _, t := range []struct {
+		one, all string
+		obj      *graphql.Object
+	}{
+		{"broker", "brokers", brokerType},
+		{"trigger", "triggers", triggerType},
+		{"source", "sources", sourceType},
+		{"channel", "channels", channelType},
+		{"subscription", "subscriptions", subscriptionType},
+		{"eventType", "eventTypes", eventTypeType},
+		{"service", "services", serviceType},
+	} {
+		query.Fields[t.all] = &graphql.Field{Type: graphql.ListOf(t.obj), Args: []string{"namespace", "kind"}, Resolve: resources(t.obj)}
+		query.Fields[t.one] = &graphql.Field{Type: t.obj, Args: []string{"namespace", "kind", "name"}, Resolve: first(resources(t.obj))}
+	}
+
+	return &graphql.Schema{Query: query, Types: []*graphql.Object{referenceType}}
+}
+
+// nodeFields returns the fields every node has, plus the extra ones.
+func nodeFields(extra graphql.Fields) graphql.Fields {
+	attr := func(f func(n gqlNode) interface{}) func(graphql.Params) (interface{}, error) {
+		return func(p graphql.Params) (interface{}, error) {
+			return f(p.Source.(gqlNode)), nil
+		}
+	}
+	edges := func(in bool) func(graphql.Params) (interface{}, error) {
+		return func(p graphql.Params) (interface{}, error) {
+			n := p.Source.(gqlNode)
+			edgesIn, edgesOut := n.g.Edges(n.Key)
+			if in {
+				edgesOut = edgesIn
+			}
+			var edges []gqlEdge
+			for _, e := range edgesOut {
+				edges = append(edges, gqlEdge{Edge: e, g: n.g})
+			}
+			return edges, nil
+		}
+	}
+
+	fields := graphql.Fields{
+		"key":        {Type: graphql.ID, Resolve: attr(func(n gqlNode) interface{} { return n.Key })},
+		"apiVersion": {Type: graphql.String, Resolve: attr(func(n gqlNode) interface{} { return n.APIVersion })},
+		"kind":       {Type: graphql.String, Resolve: attr(func(n gqlNode) interface{} { return n.Kind })},
+		"namespace":  {Type: graphql.String, Resolve: attr(func(n gqlNode) interface{} { return n.Namespace })},
+		"name":       {Type: graphql.String, Resolve: attr(func(n gqlNode) interface{} { return n.Name })},
+		"uri":        {Type: graphql.String, Resolve: attr(func(n gqlNode) interface{} { return n.URI })},
+		"ready":      {Type: graphql.String, Resolve: attr(func(n gqlNode) interface{} { return n.Ready })},
+		"status":     {Type: graphql.JSON, Resolve: attr(func(n gqlNode) interface{} { return n.Status })},
+		"object":     {Type: graphql.JSON, Resolve: attr(func(n gqlNode) interface{} { return n.Object })},
+		"inbound":    {Type: graphql.ListOf(edgeType), Resolve: edges(true)},
+		"outbound":   {Type: graphql.ListOf(edgeType), Resolve: edges(false)},
+	}
+	for name, f := range extra {
+		fields[name] = f
+	}
+	return fields
+}
+
+// typeOf returns the GraphQL type of a node.
+func typeOf(n *graph.Node) *graphql.Object {
+	gv, _ := schema.ParseGroupVersion(n.APIVersion)
+	switch {
+	case gv.Group == "eventing.knative.dev" && n.Kind == "Broker":
+		return brokerType
+	case gv.Group == "eventing.knative.dev" && n.Kind == "Trigger":
+		return triggerType
+	case gv.Group == "eventing.knative.dev" && n.Kind == "Channel":
+		return channelType
+	case gv.Group == "eventing.knative.dev" && n.Kind == "Subscription":
+		return subscriptionType
+	case gv.Group == "eventing.knative.dev" && n.Kind == "EventType":
+		return eventTypeType
+	case gv.Group == "serving.knative.dev" && n.Kind == "Service":
+		return serviceType
+	case n.Object != nil:
+		// Everything else that is loaded is a source.
+		return sourceType
+	}
+	return referenceType
+}
+
+func load(p graphql.Params) (*graph.Graph, error) {
+	return p.Context.Value(topologyKey{}).(*topology).load(p.String("namespace"))
+}
+
+// lookup returns the node with the key, or nil.
+func lookup(g *graph.Graph, key string) interface{} {
+	if n, ok := g.Node(key); ok {
+		return gqlNode{Node: n, g: g}
+	}
+	return nil
+}
+
+// resources lists the loaded resources of the type, filtered by the kind and
+// name arguments.
+func resources(obj *graphql.Object) func(graphql.Params) (interface{}, error) {
+	return func(p graphql.Params) (interface{}, error) {
+		g, err := load(p)
+		if err != nil {
+			return nil, err
+		}
+		var nodes []gqlNode
+		for _, n := range g.Nodes() {
+			if n.Object == nil || typeOf(n) != obj {
+				continue
+			}
+			if kind := p.String("kind"); kind != "" && !strings.EqualFold(n.Kind, kind) {
+				continue
+			}
+			if name := p.String("name"); name != "" && n.Name != name {
+				continue
+			}
+			nodes = append(nodes, gqlNode{Node: n, g: g})
+		}
+		return nodes, nil
+	}
+}
+
+// related follows the edges with the relation into or out of the node,
+// keeping only nodes of the type when obj is set.
+func related(in bool, relation string, obj *graphql.Object) func(graphql.Params) (interface{}, error) {
+	return func(p graphql.Params) (interface{}, error) {
+		n := p.Source.(gqlNode)
+		edgesIn, edgesOut := n.g.Edges(n.Key)
+		var nodes []gqlNode
+		for _, e := range edgesOut {
+			if !in && e.Relation == relation {
+				nodes = appendNode(nodes, n.g, e.To, obj)
+			}
+		}
+		for _, e := range edgesIn {
+			if in && e.Relation == relation {
+				nodes = appendNode(nodes, n.g, e.From, obj)
+			}
+		}
+		return nodes, nil
+	}
+}
+
+func appendNode(nodes []gqlNode, g *graph.Graph, key string, obj *graphql.Object) []gqlNode {
+	n, ok := g.Node(key)
+	if !ok || (obj != nil && typeOf(n) != obj) {
+		return nodes
+	}
+	return append(nodes, gqlNode{Node: n, g: g})
+}
+
+// first turns a resolver of a list into one of its first item.
+func first(list func(graphql.Params) (interface{}, error)) func(graphql.Params) (interface{}, error) {
+	return func(p graphql.Params) (interface{}, error) {
+		v, err := list(p)
+		if nodes, ok := v.([]gqlNode); ok && len(nodes) > 0 {
+			return nodes[0], err
+		}
+		return nil, err
+	}
+}
+
+// objectField resolves a field of the raw object.
+func objectField(path ...string) func(graphql.Params) (interface{}, error) {
+	return func(p graphql.Params) (interface{}, error) {
+		var v interface{} = p.Source.(gqlNode).Object
+		for _, name := range path {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, nil
+			}
+			v = m[name]
+		}
+		return v, nil
+	}
+}
+
+func brokerEventTypes(p graphql.Params) (interface{}, error) {
+	b := p.Source.(gqlNode)
+	var nodes []gqlNode
+	for _, n := range b.g.Nodes() {
+		if typeOf(n) != eventTypeType {
+			continue
+		}
+		if spec, ok := n.Object["spec"].(map[string]interface{}); ok && spec["broker"] == b.Name {
+			nodes = append(nodes, gqlNode{Node: n, g: b.g})
+		}
+	}
+	return nodes, nil
+}
+
+func eventTypeBroker(p graphql.Params) (interface{}, error) {
+	et := p.Source.(gqlNode)
+	broker, err := objectField("spec", "broker")(p)
+	if name, ok := broker.(string); ok && err == nil {
+		return lookup(et.g, graph.Key("eventing.knative.dev/v1alpha1", "Broker", name)), nil
+	}
+	return nil, err
+}
+
+// maxQueryBytes limits the size of a posted query, with its variables.
+const maxQueryBytes = 1 << 20
+
+// gql serves GraphQL queries, posted as JSON or passed in the query
+// parameter.
+func (s *Server) gql(w http.ResponseWriter, r *http.Request) {
+	var req graphql.Request
+	switch r.Method {
+	case http.MethodGet:
+		req.Query = getQueryParam(r, "query")
+		req.OperationName = getQueryParam(r, "operationName")
+		if vars := getQueryParam(r, "variables"); vars != "" {
+			if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid variables: "+err.Error())
+				return
+			}
+		}
+	case http.MethodPost:
+		r.Body = http.MaxBytesReader(w, r.Body, maxQueryBytes)
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request: "+err.Error())
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	id, ok := s.identify(w, r)
+	if !ok {
+		return
+	}
+	t := &topology{
+		s:      s,
+		authz:  id.authorizer(s.kube),
+		graphs: make(map[string]*graph.Graph),
+	}
+	if internals := getQueryParam(r, "internals"); internals == "expand" || internals == "true" {
+		t.internals = true
+	}
+
+	ctx := context.WithValue(r.Context(), topologyKey{}, t)
+	res := gqlSchema.Execute(ctx, req)
+	if res.Data == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(res)
+		return
+	}
+	writeJSON(w, res)
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/n3wscott/knap/pkg/knative"
+)
+
+// topologyYAML is a broker with a trigger to a service, which sends back to
+// the broker, and two sources: one sending to the broker, one to an address
+// nothing serves.
+const topologyYAML = `
+apiVersion: v1
+kind: List
+items:
+- apiVersion: apiextensions.k8s.io/v1beta1
+  kind: CustomResourceDefinition
+  metadata:
+    name: cronjobsources.sources.eventing.knative.dev
+    labels:
+      eventing.knative.dev/source: "true"
+  spec:
+    group: sources.eventing.knative.dev
+    names: {kind: CronJobSource, plural: cronjobsources}
+    versions:
+    - {name: v1alpha1, served: true, storage: true}
+- apiVersion: sources.eventing.knative.dev/v1alpha1
+  kind: CronJobSource
+  metadata: {name: cron, namespace: demo}
+  status:
+    sinkUri: http://default-broker.demo.svc.cluster.local/
+- apiVersion: sources.eventing.knative.dev/v1alpha1
+  kind: CronJobSource
+  metadata: {name: lost, namespace: demo}
+  status:
+    sinkUri: http://nowhere.demo.svc.cluster.local/
+- apiVersion: eventing.knative.dev/v1alpha1
+  kind: Broker
+  metadata: {name: default, namespace: demo}
+  status:
+    address: {hostname: default-broker.demo.svc.cluster.local}
+- apiVersion: eventing.knative.dev/v1alpha1
+  kind: Trigger
+  metadata: {name: display, namespace: demo}
+  spec:
+    broker: default
+    subscriber: {ref: {apiVersion: serving.knative.dev/v1alpha1, kind: Service, name: display}}
+- apiVersion: serving.knative.dev/v1alpha1
+  kind: Service
+  metadata: {name: display, namespace: demo}
+  spec:
+    runLatest:
+      configuration:
+        revisionTemplate:
+          spec:
+            container:
+              env:
+              - {name: TARGET, value: "http://default-broker.demo.svc.cluster.local/"}
+  status:
+    address: {hostname: display.demo.svc.cluster.local}
+`
+
+func serveTopology(t *testing.T) *Server {
+	t.Helper()
+	objs, err := knative.ReadSnapshot(strings.NewReader(topologyYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newServer(Config{Namespace: "demo", Auth: "none"}, knative.NewSnapshot(objs), nil, nil)
+}
+
+func postQuery(s *Server, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+	return w
+}
+
+func TestGraphQLRelationships(t *testing.T) {
+	s := serveTopology(t)
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{{
+		name:  "trigger.broker",
+		query: `{ trigger(name: "display") { broker { __typename name address } } }`,
+		want:  `{"trigger":{"broker":{"__typename":"Broker","name":"default","address":"default-broker.demo.svc.cluster.local"}}}`,
+	}, {
+		name:  "trigger.subscriber",
+		query: `{ trigger(name: "display") { subscriber { __typename key ... on Service { address } } } }`,
+		want:  `{"trigger":{"subscriber":{"__typename":"Service","key":"serving.knative.dev/v1alpha1/service/display","address":"display.demo.svc.cluster.local"}}}`,
+	}, {
+		name:  "source.sink to a broker",
+		query: `{ source(name: "cron") { sinkURI sink { __typename key } } }`,
+		want:  `{"source":{"sinkURI":"http://default-broker.demo.svc.cluster.local/","sink":{"__typename":"Broker","key":"eventing.knative.dev/v1alpha1/broker/default"}}}`,
+	}, {
+		name:  "source.sink to an unknown address",
+		query: `{ source(name: "lost") { sink { __typename uri } } }`,
+		want:  `{"source":{"sink":{"__typename":"Reference","uri":"http://nowhere.demo.svc.cluster.local/"}}}`,
+	}, {
+		name:  "service.sinks",
+		query: `{ service(name: "display") { sinks { __typename name } } }`,
+		want:  `{"service":{"sinks":[{"__typename":"Broker","name":"default"}]}}`,
+	}, {
+		name:  "service.triggers and back",
+		query: `{ service(name: "display") { triggers { name broker { triggers { name } } } } }`,
+		want:  `{"service":{"triggers":[{"name":"display","broker":{"triggers":[{"name":"display"}]}}]}}`,
+	}, {
+		name:  "sources by kind",
+		query: `{ sources(kind: "CronJobSource") { name } }`,
+		want:  `{"sources":[{"name":"cron"},{"name":"lost"}]}`,
+	}, {
+		name:  "node by key",
+		query: `query N($key: String) { node(key: $key) { __typename name } }`,
+		want:  `{"node":{"__typename":"Trigger","name":"display"}}`,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]interface{}{
+				"query":     tt.query,
+				"variables": map[string]interface{}{"key": "eventing.knative.dev/v1alpha1/trigger/display"},
+			})
+			w := postQuery(s, string(body))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body)
+			}
+			var res struct {
+				Data   json.RawMessage
+				Errors []struct{ Message string }
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatal(err)
+			}
+			if len(res.Errors) > 0 {
+				t.Fatalf("errors: %v", res.Errors)
+			}
+			if got := string(res.Data); got != tt.want {
+				t.Errorf("data =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraphQLRejects(t *testing.T) {
+	s := serveTopology(t)
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{{
+		name: "object without selection",
+		body: `{"query": "{ triggers { broker } }"}`,
+		want: `must have a selection of subfields`,
+	}, {
+		name: "too deep",
+		body: `{"query": "{ triggers ` + strings.Repeat(`{ broker { triggers `, 20) + `{ name }` + strings.Repeat(` } }`, 20) + ` }"}`,
+		want: `levels deep`,
+	}, {
+		name: "too large",
+		body: `{"query": "{ ` + strings.Repeat(" ", maxQueryBytes) + `triggers { name } }"}`,
+		want: `too large`,
+	}, {
+		name: "not JSON",
+		body: `{ triggers { name } }`,
+		want: `invalid request`,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postQuery(s, tt.body)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+			}
+			if !bytes.Contains(w.Body.Bytes(), []byte(tt.want)) {
+				t.Errorf("body = %s, want it to contain %q", w.Body, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// healthInterval is how often the API server is checked.
+	healthInterval = 10 * time.Second
+
+	// unreachableLimit is how long the API server may be unreachable before
+	// the liveness check fails and the pod is restarted.
+	unreachableLimit = 5 * time.Minute
+)
+
+// health tracks whether the API server can be reached.
+type health struct {
+	mu       sync.Mutex
+	started  time.Time
+	lastOK   time.Time
+	err      error
+	draining bool
+
+	kube kubernetes.Interface
+}
+
+func newHealth(kube kubernetes.Interface) *health {
+	return &health{started: time.Now(), kube: kube}
+}
+
+// run checks the API server every interval until stop is closed.
+func (h *health) run(stop <-chan struct{}) {
+	ticker := time.NewTicker(healthInterval)
+	defer ticker.Stop()
+	for {
+		h.check()
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *health) check() {
+	_, err := h.kube.Discovery().ServerVersion()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err != nil && h.err == nil {
+		log.Printf("[WARN] API server unreachable: %s", err)
+	} else if err == nil && h.err != nil {
+		log.Printf("API server reachable again")
+	}
+	h.err = err
+	if err == nil {
+		h.lastOK = time.Now()
+	}
+}
+
+// drain fails the readiness check while the server shuts down.
+func (h *health) drain() {
+	h.mu.Lock()
+	h.draining = true
+	h.mu.Unlock()
+}
+
+// healthz is the liveness check. It only fails when the API server has been
+// unreachable for a long time, since restarting does not fix an outage.
+func (h *health) healthz(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	since := h.lastOK
+	if since.IsZero() {
+		since = h.started
+	}
+	err := h.err
+	h.mu.Unlock()
+
+	if err != nil && time.Since(since) > unreachableLimit {
+		http.Error(w, fmt.Sprintf("API server unreachable since %s: %s", since.Format(time.RFC3339), err), http.StatusServiceUnavailable)
+		return
+	}
+	_, _ = fmt.Fprintln(w, "ok")
+}
+
+// readyz is the readiness check, it fails while the API server is
+// unreachable or the server is shutting down.
+func (h *health) readyz(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	err, ok, draining := h.err, !h.lastOK.IsZero(), h.draining
+	h.mu.Unlock()
+
+	switch {
+	case draining:
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+	case err != nil:
+		http.Error(w, "API server unreachable: "+err.Error(), http.StatusServiceUnavailable)
+	case !ok:
+		http.Error(w, "API server not checked yet", http.StatusServiceUnavailable)
+	default:
+		_, _ = fmt.Fprintln(w, "ok")
+	}
+}
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/n3wscott/knap/pkg/graph"
+	"github.com/n3wscott/knap/pkg/knative"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// debounce collects a burst of changes into a single update.
+	debounce = 500 * time.Millisecond
+
+	// keepAlive is how often an idle event stream is pinged, so proxies do
+	// not close it.
+	keepAlive = 30 * time.Second
+)
+
+// update tells the browser to reload the graph, naming the nodes that
+// changed.
+type update struct {
+	Changed []string `json:"changed"`
+}
+
+// change is a node that changed, and the resource it was changed through.
+type change struct {
+	key string
+	gvr schema.GroupVersionResource
+}
+
+// hub shares one watch of a namespace between all the browsers showing it.
+type hub struct {
+	client dynamic.Interface
+	self   knative.Authorizer
+
+	mu       sync.Mutex
+	watchers map[string]*watcher
+}
+
+type watcher struct {
+	stop chan struct{}
+	subs map[chan []change]struct{}
+}
+
+func newHub(client dynamic.Interface, self knative.Authorizer) *hub {
+	return &hub{client: client, self: self, watchers: make(map[string]*watcher)}
+}
+
+// subscribe returns a channel of updates for the namespace, starting to
+// watch it if nobody else is.
+func (h *hub) subscribe(ns string) chan []change {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	w, ok := h.watchers[ns]
+	if !ok {
+		w = &watcher{
+			stop: make(chan struct{}),
+			subs: make(map[chan []change]struct{}),
+		}
+		h.watchers[ns] = w
+		go h.run(ns, w)
+	}
+	ch := make(chan []change, 1)
+	w.subs[ch] = struct{}{}
+	return ch
+}
+
+// unsubscribe stops sending updates to ch, and stops watching the namespace
+// once nobody is left.
+func (h *hub) unsubscribe(ns string, ch chan []change) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	w, ok := h.watchers[ns]
+	if !ok {
+		return
+	}
+	delete(w.subs, ch)
+	if len(w.subs) == 0 {
+		close(w.stop)
+		delete(h.watchers, ns)
+	}
+}
+
+// run collects the changes in the namespace and broadcasts them once they
+// settle.
+func (h *hub) run(ns string, w *watcher) {
+	changes := knative.New(h.client, knative.WithAuthorizer(h.self)).Watch(ns, w.stop)
+
+	changed := make(map[string]schema.GroupVersionResource)
+	var flush <-chan time.Time
+	for {
+		select {
+		case <-w.stop:
+			return
+		case c := <-changes:
+			for _, key := range keysFor(c.Object) {
+				changed[key] = c.GVR
+			}
+			if flush == nil {
+				flush = time.After(debounce)
+			}
+		case <-flush:
+			var u []change
+			for key, gvr := range changed {
+				u = append(u, change{key: key, gvr: gvr})
+			}
+			changed = make(map[string]schema.GroupVersionResource)
+			flush = nil
+			h.broadcast(w, u)
+		}
+	}
+}
+
+func (h *hub) broadcast(w *watcher, u []change) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range w.subs {
+		select {
+		case ch <- u:
+		default:
+			// The browser has not caught up with the previous update, it
+			// will reload once for both.
+			select {
+			case prev := <-ch:
+				u = append(prev, u...)
+			default:
+			}
+			ch <- u
+		}
+	}
+}
+
+// keysFor returns the keys of the nodes that show obj: its own, and its
+// controller's for collapsed internals. Anyone who can see obj can see its
+// owner references, so both are reported under obj's resource.
+func keysFor(obj *unstructured.Unstructured) []string {
+	keys := []string{graph.Key(obj.GetAPIVersion(), obj.GetKind(), obj.GetName())}
+	if owner := metav1.GetControllerOf(obj); owner != nil {
+		keys = append(keys, graph.Key(owner.APIVersion, owner.Kind, owner.Name))
+	}
+	return keys
+}
+
+// events streams updates of the namespace to the browser as server-sent
+// events.
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	ns, ok := s.namespaceParam(w, r)
+	if !ok {
+		return
+	}
+	authz, ok := s.authorize(w, r, ns)
+	if !ok {
+		return
+	}
+	ch := s.live.subscribe(ns)
+	defer s.live.unsubscribe(ns, ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ping := time.NewTicker(keepAlive)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.shutdown:
+			return
+		case <-ping.C:
+			_, _ = fmt.Fprint(w, ": ping\n\n")
+		case changes := <-ch:
+			u := update{Changed: []string{}}
+			for _, c := range changes {
+				if authz == nil || authz(ns, c.gvr) {
+					u.Changed = append(u.Changed, c.key)
+				}
+			}
+			data, err := json.Marshal(u)
+			if err != nil {
+				log.Printf("unable to marshal update: %s", err)
+				continue
+			}
+			_, _ = fmt.Fprintf(w, "event: update\ndata: %s\n\n", data)
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/n3wscott/knap/pkg/metrics"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+var (
+	registry = metrics.NewRegistry()
+
+	requestsTotal = registry.NewCounter("knap_http_requests_total",
+		"HTTP requests served, by handler and status code.", "handler", "code")
+	renderSeconds = registry.NewHistogram("knap_render_duration_seconds",
+		"Time taken to render a graph, by renderer and format.", metrics.DefBuckets, "renderer", "format")
+	dotFailures = registry.NewCounter("knap_dot_failures_total",
+		"Renders that the dot program failed.")
+	listSeconds = registry.NewHistogram("knap_list_duration_seconds",
+		"Time taken to list a resource from the API server, by group, version, resource and result.",
+		metrics.DefBuckets, "group", "version", "resource", "result")
+	cacheRequests = registry.NewCounter("knap_render_cache_requests_total",
+		"Render cache lookups, by result: hit or miss.", "result")
+)
+
+// instrument counts the requests each handler of mux serves.
+func instrument(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		if pattern == "" {
+			pattern = "none"
+		}
+		sw := &statusWriter{ResponseWriter: w, code: http.StatusOK}
+		mux.ServeHTTP(sw, r)
+		requestsTotal.Inc(pattern, strconv.Itoa(sw.code))
+	})
+}
+
+// statusWriter remembers the status code written, and still streams.
+type statusWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.code = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// timedClient records how long each List takes, per resource.
+type timedClient struct {
+	dynamic.Interface
+}
+
+func (c timedClient) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return timedResource{NamespaceableResourceInterface: c.Interface.Resource(gvr), gvr: gvr}
+}
+
+type timedResource struct {
+	dynamic.NamespaceableResourceInterface
+	gvr schema.GroupVersionResource
+}
+
+func (r timedResource) Namespace(ns string) dynamic.ResourceInterface {
+	return timedNamespacedResource{ResourceInterface: r.NamespaceableResourceInterface.Namespace(ns), gvr: r.gvr}
+}
+
+func (r timedResource) List(opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	return timeList(r.gvr, func() (*unstructured.UnstructuredList, error) {
+		return r.NamespaceableResourceInterface.List(opts)
+	})
+}
+
+type timedNamespacedResource struct {
+	dynamic.ResourceInterface
+	gvr schema.GroupVersionResource
+}
+
+func (r timedNamespacedResource) List(opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	return timeList(r.gvr, func() (*unstructured.UnstructuredList, error) {
+		return r.ResourceInterface.List(opts)
+	})
+}
+
+func timeList(gvr schema.GroupVersionResource, list func() (*unstructured.UnstructuredList, error)) (*unstructured.UnstructuredList, error) {
+	start := time.Now()
+	l, err := list()
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	listSeconds.Observe(time.Since(start).Seconds(), gvr.Group, gvr.Version, gvr.Resource, result)
+	return l, err
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/n3wscott/knap/pkg/knative"
+)
+
+// allowed reports whether the namespace may be shown. Without an allow-list
+// only the namespace knap runs in is served, "*" allows every namespace.
+func (s *Server) allowed(ns string) bool {
+	if ns == s.env.Namespace {
+		return true
+	}
+	for _, a := range s.env.Namespaces {
+		if a == "*" || a == ns {
+			return true
+		}
+	}
+	return false
+}
+
+// listed returns the namespaces the index lists, or nil if every namespace
+// is allowed.
+func (s *Server) listed() []string {
+	namespaces := []string{s.env.Namespace}
+	for _, ns := range s.env.Namespaces {
+		if ns == "*" {
+			return nil
+		}
+		if ns != s.env.Namespace {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
+
+// namespaceParam returns the namespace requested by r, writing an error and
+// returning false if it is not allowed.
+func (s *Server) namespaceParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	ns := getQueryParam(r, "namespace")
+	if ns == "" {
+		ns = s.env.Namespace
+	}
+	if !s.allowed(ns) {
+		http.Error(w, "namespace "+ns+" is not allowed", http.StatusForbidden)
+		return "", false
+	}
+	return ns, true
+}
+
+var IndexTemplate = `<!DOCTYPE html>
+<html lang="en"><head><title>knap</title></head>
+<body>
+<h1>Namespaces</h1>
+{{if .Namespaces}}
+<table>
+<tr><th>Namespace</th>{{range .Kinds}}<th>{{.}}</th>{{end}}</tr>
+{{range .Namespaces}}<tr><td><a href="./?namespace={{.Name}}">{{.Name}}</a></td>{{range .Counts}}<td>{{.}}</td>{{end}}</tr>
+{{end}}
+</table>
+{{else}}
+<p>No namespaces contain eventing or serving resources.</p>
+{{end}}
+</body></html>`
+
+var indexTmpl = template.Must(template.New("index").Parse(IndexTemplate))
+
+type namespaceRow struct {
+	Name   string
+	Counts []int
+}
+
+// index lists the allowed namespaces that contain eventing or serving
+// resources, with the number of resources of each kind the caller may see.
+func (s *Server) index(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.identify(w, r)
+	if !ok {
+		return
+	}
+	// Only count what knap itself may read too, so every namespace listed
+	// can be shown.
+	c := knative.New(s.client, knative.WithAuthorizer(s.self), knative.WithAuthorizer(id.authorizer(s.kube)))
+	counts := c.Counts(s.listed()...)
+
+	kindSet := make(map[string]bool)
+	var names []string
+	for ns, byKind := range counts {
+		if !s.allowed(ns) {
+			continue
+		}
+		names = append(names, ns)
+		for kind := range byKind {
+			kindSet[kind] = true
+		}
+	}
+	sort.Strings(names)
+
+	var kinds []string
+	for kind := range kindSet {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	rows := make([]namespaceRow, len(names))
+	for i, ns := range names {
+		rows[i].Name = ns
+		for _, kind := range kinds {
+			rows[i].Counts = append(rows[i].Counts, counts[ns][kind])
+		}
+	}
+
+	data := map[string]interface{}{
+		"Kinds":      kinds,
+		"Namespaces": rows,
+	}
+	if err := indexTmpl.Execute(w, data); err != nil {
+		log.Println("unable to execute index template.")
+	}
+}
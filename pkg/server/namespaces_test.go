@@ -0,0 +1,77 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/n3wscott/knap/pkg/knative"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestIndex(t *testing.T) {
+	objs, err := knative.ReadSnapshot(strings.NewReader(topologyYAML + `
+- apiVersion: eventing.knative.dev/v1alpha1
+  kind: Broker
+  metadata: {name: default, namespace: team-a, uid: team-a-broker}
+- apiVersion: eventing.knative.dev/v1alpha1
+  kind: Broker
+  metadata: {name: default, namespace: team-b, uid: team-b-broker}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// knap may not read team-b's brokers.
+	self := func(ns string, gvr schema.GroupVersionResource) bool {
+		return ns != "team-b"
+	}
+
+	tests := []struct {
+		name       string
+		namespaces []string
+		want       []string
+		notWant    []string
+		row        string
+	}{{
+		name:    "own namespace",
+		want:    []string{"demo"},
+		notWant: []string{"team-a", "team-b"},
+		// The snapshot's objects have no UIDs, they are still counted.
+		row: `demo</a></td><td>1</td><td>2</td><td>1</td><td>1</td>`,
+	}, {
+		name:       "allow-list",
+		namespaces: []string{"team-a", "team-b"},
+		want:       []string{"demo", "team-a"},
+		notWant:    []string{"team-b"},
+	}, {
+		name:       "every namespace",
+		namespaces: []string{"*"},
+		want:       []string{"demo", "team-a"},
+		notWant:    []string{"team-b"},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(Config{Namespace: "demo", Namespaces: tt.namespaces, Auth: "none"}, knative.NewSnapshot(objs), nil, self)
+			w := httptest.NewRecorder()
+			s.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/namespaces", nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body)
+			}
+			body := w.Body.String()
+			if !strings.Contains(body, tt.row) {
+				t.Errorf("counts are not %s:\n%s", tt.row, body)
+			}
+			for _, ns := range tt.want {
+				if !strings.Contains(body, "namespace="+ns+`"`) {
+					t.Errorf("%s is not listed:\n%s", ns, body)
+				}
+			}
+			for _, ns := range tt.notWant {
+				if strings.Contains(body, "namespace="+ns+`"`) {
+					t.Errorf("%s is listed:\n%s", ns, body)
+				}
+			}
+		})
+	}
+}
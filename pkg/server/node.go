@@ -0,0 +1,124 @@
+package server
+
+import (
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/ghodss/yaml"
+	"github.com/n3wscott/knap/pkg/graph"
+)
+
+// nodeLinks link every node of the graph pages to its detail view.
+var nodeLinks = func() graph.Links {
+	links, err := graph.ParseLinks("*=node?namespace={{urlquery .Namespace}}&key={{urlquery .Key}}")
+	if err != nil {
+		log.Fatalf("Error parsing node links: %s", err)
+	}
+	return links
+}()
+
+type nodeEdge struct {
+	graph.Edge
+	Other *graph.Node
+}
+
+type nodeView struct {
+	Namespace  string
+	Node       *graph.Node
+	Conditions []map[string]interface{}
+	In, Out    []nodeEdge
+	Object     string
+}
+
+var nodeTemplate = template.Must(template.New("node").Parse(`<!DOCTYPE html>
+<html lang="en"><head><title>{{.Node.Kind}} {{.Node.Name}}</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { text-align: left; padding: 2px 12px 2px 0; }
+pre { background: #f4f4f4; padding: 8px; }
+</style></head>
+<body>
+<p><a href="./?namespace={{.Namespace}}">&larr; graph of {{.Namespace}}</a></p>
+<h1>{{.Node.Kind}} {{.Node.Name}}{{.Node.URI}}</h1>
+<table>
+<tr><th>Key</th><td>{{.Node.Key}}</td></tr>
+{{with .Node.APIVersion}}<tr><th>API version</th><td>{{.}}</td></tr>{{end}}
+{{with .Node.Namespace}}<tr><th>Namespace</th><td>{{.}}</td></tr>{{end}}
+{{with .Node.Ready}}<tr><th>Ready</th><td>{{.}}</td></tr>{{end}}
+</table>
+{{if .Conditions}}<h2>Conditions</h2>
+<table><tr><th>Type</th><th>Status</th><th>Reason</th><th>Message</th></tr>
+{{range .Conditions}}<tr><td>{{.type}}</td><td>{{.status}}</td><td>{{.reason}}</td><td>{{.message}}</td></tr>
+{{end}}</table>{{end}}
+{{if .In}}<h2>Events from</h2>
+<table>{{range .In}}<tr><td>{{.Relation}}</td><td><a href="?namespace={{$.Namespace}}&key={{.From}}">{{.Other.Kind}} {{.Other.Name}}{{.Other.URI}}</a></td></tr>
+{{end}}</table>{{end}}
+{{if .Out}}<h2>Events to</h2>
+<table>{{range .Out}}<tr><td>{{.Relation}}</td><td><a href="?namespace={{$.Namespace}}&key={{.To}}">{{.Other.Kind}} {{.Other.Name}}{{.Other.URI}}</a></td></tr>
+{{end}}</table>{{end}}
+{{with .Object}}<h2>Object</h2>
+<pre>{{.}}</pre>{{end}}
+</body></html>
+`))
+
+// node shows one node of the graph: its status, where its events come from
+// and go to, and the object itself.
+func (s *Server) node(w http.ResponseWriter, r *http.Request) {
+	ns, ok := s.namespaceParam(w, r)
+	if !ok {
+		return
+	}
+	authz, ok := s.authorize(w, r, ns)
+	if !ok {
+		return
+	}
+
+	g := graph.LoadTopology(s.client, ns,
+		graph.WithAuthorizer(s.self),
+		graph.WithAuthorizer(authz),
+		graph.WithSourceInformers(s.sourceInformers(ns)))
+	n, ok := g.Node(getQueryParam(r, "key"))
+	if !ok {
+		http.Error(w, "node "+getQueryParam(r, "key")+" not found", http.StatusNotFound)
+		return
+	}
+
+	v := nodeView{Namespace: ns, Node: n}
+	if conditions, ok := n.Status["conditions"].([]interface{}); ok {
+		for _, c := range conditions {
+			if cond, ok := c.(map[string]interface{}); ok {
+				v.Conditions = append(v.Conditions, cond)
+			}
+		}
+		sort.Slice(v.Conditions, func(i, j int) bool {
+			return fmt.Sprint(v.Conditions[i]["type"]) < fmt.Sprint(v.Conditions[j]["type"])
+		})
+	}
+	in, out := g.Edges(n.Key)
+	for _, e := range in {
+		if other, ok := g.Node(e.From); ok {
+			v.In = append(v.In, nodeEdge{Edge: e, Other: other})
+		}
+	}
+	for _, e := range out {
+		if other, ok := g.Node(e.To); ok {
+			v.Out = append(v.Out, nodeEdge{Edge: e, Other: other})
+		}
+	}
+	if n.Object != nil {
+		b, err := yaml.Marshal(n.Object)
+		if err != nil {
+			log.Printf("unable to marshal %s: %s", n.Key, err)
+		}
+		v.Object = string(b)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := nodeTemplate.Execute(w, v); err != nil {
+		log.Printf("unable to execute node template: %s", err)
+	}
+}
@@ -0,0 +1,500 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/n3wscott/knap/pkg/graph"
+	"github.com/n3wscott/knap/pkg/knative"
+	"github.com/n3wscott/knap/pkg/render"
+	"html/template"
+	"image"
+	"image/jpeg"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Config configures the server, usually from the environment.
+type Config struct {
+	// Namespace is shown by default, usually the one this pod exists in.
+	Namespace string `envconfig:"POD_NAMESPACE"`
+
+	// Namespaces lists the other namespaces that may be shown, "*" for all.
+	Namespaces []string `envconfig:"NAMESPACES"`
+
+	// Auth selects how callers are identified: none, token or header. See
+	// authenticate.
+	Auth string `envconfig:"AUTH" default:"none"`
+
+	// UserHeader and GroupsHeader carry the caller's identity in header mode.
+	UserHeader   string `envconfig:"AUTH_USER_HEADER" default:"X-Forwarded-User"`
+	GroupsHeader string `envconfig:"AUTH_GROUPS_HEADER" default:"X-Forwarded-Groups"`
+
+	// Renderer draws the images: graphviz, builtin, or auto to use graphviz
+	// when the dot program is installed and the builtin renderer otherwise.
+	Renderer string `envconfig:"RENDERER" default:"auto"`
+
+	// RenderTimeout limits how long a single render may take.
+	RenderTimeout time.Duration `envconfig:"RENDER_TIMEOUT" default:"30s"`
+
+	// Port is set by Knative. ListenAddress, host:port, overrides it.
+	Port          string `envconfig:"PORT" default:"8080"`
+	ListenAddress string `envconfig:"LISTEN_ADDRESS"`
+
+	// ShutdownTimeout is how long requests in flight get to finish after
+	// SIGTERM.
+	ShutdownTimeout time.Duration `envconfig:"SHUTDOWN_TIMEOUT" default:"10s"`
+}
+
+// Server serves the graphs of one cluster. It keeps its own clients, caches
+// and watches, so several can run in one process.
+type Server struct {
+	env    Config
+	client dynamic.Interface
+	kube   kubernetes.Interface
+	self   knative.Authorizer
+	tokens *tokenCache
+	cache  *renderCache
+	live   *hub
+	health *health
+
+	mu      sync.Mutex
+	sources map[string]*knative.SourceInformers // by namespace
+
+	// shutdown is closed when the server starts shutting down, so streams end.
+	shutdown     chan struct{}
+	shutdownOnce sync.Once
+}
+
+// New returns a server of the cluster cfg connects to, logging the resources
+// knap's own credentials may not list.
+func New(cfg *rest.Config, c Config) (*Server, error) {
+	if c.Namespace == "" {
+		return nil, errors.New("a namespace is required")
+	}
+	dc, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	kube, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client := timedClient{dc}
+	s := newServer(c, client, kube, knative.SelfAuthorizer(client, time.Minute))
+	s.preflight()
+	return s, nil
+}
+
+func newServer(c Config, client dynamic.Interface, kube kubernetes.Interface, self knative.Authorizer) *Server {
+	return &Server{
+		env:      c,
+		client:   client,
+		kube:     kube,
+		self:     self,
+		tokens:   newTokenCache(),
+		cache:    newRenderCache(c.RenderTimeout),
+		live:     newHub(client, self),
+		health:   newHealth(kube),
+		sources:  make(map[string]*knative.SourceInformers),
+		shutdown: make(chan struct{}),
+	}
+}
+
+// Run serves the graphs of the cluster cfg connects to until SIGTERM or an
+// interrupt.
+func Run(cfg *rest.Config, c Config) error {
+	s, err := New(cfg, c)
+	if err != nil {
+		return err
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(sigs)
+	go func() {
+		select {
+		case sig := <-sigs:
+			log.Printf("Received %s, shutting down", sig)
+			close(stop)
+		case <-done:
+		}
+	}()
+	return s.Serve(stop)
+}
+
+// Handler returns the handler of every page and API of the server.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/favicon.ico", favicon)
+	mux.HandleFunc("/healthz", s.health.healthz)
+	mux.HandleFunc("/readyz", s.health.readyz)
+	mux.Handle("/metrics", registry)
+	mux.HandleFunc("/events", s.events)
+	mux.HandleFunc("/namespaces", s.index)
+	mux.HandleFunc("/node", s.node)
+	mux.HandleFunc(apiPrefix, s.api)
+	mux.HandleFunc("/graphql", s.gql)
+	mux.HandleFunc(downloadPrefix, s.download)
+	mux.HandleFunc("/", s.handler)
+	return instrument(mux)
+}
+
+// Serve listens on the configured address until stop is closed, then gives
+// the requests in flight ShutdownTimeout to finish.
+func (s *Server) Serve(stop <-chan struct{}) error {
+	addr := s.env.ListenAddress
+	if addr == "" {
+		addr = ":" + s.env.Port
+	}
+	srv := &http.Server{Addr: addr, Handler: s.Handler()}
+	srv.RegisterOnShutdown(s.Shutdown)
+
+	checks := make(chan struct{})
+	defer close(checks)
+	go s.health.run(checks)
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.ListenAndServe()
+	}()
+	log.Printf("Listening on %s", addr)
+
+	select {
+	case err := <-errs:
+		return err
+	case <-stop:
+	}
+	s.health.drain()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.env.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("[ERROR] Failed to shut down: %s", err)
+	}
+	if err := <-errs; err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Shutdown ends the event streams of the server. It may be called more than
+// once.
+func (s *Server) Shutdown() {
+	s.shutdownOnce.Do(func() {
+		close(s.shutdown)
+	})
+}
+
+// sourceInformers returns the informers caching the sources of the
+// namespace, which run until the server shuts down.
+func (s *Server) sourceInformers(ns string) *knative.SourceInformers {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	si, ok := s.sources[ns]
+	if !ok {
+		si = knative.New(s.client).SourceInformers(ns, 0, s.shutdown)
+		s.sources[ns] = si
+	}
+	return si
+}
+
+// preflight logs the resources knap's own credentials may not list, which are
+// shown as not permitted on the graphs.
+func (s *Server) preflight() {
+	namespaces := []string{s.env.Namespace}
+	for _, ns := range s.env.Namespaces {
+		if ns != "*" && ns != s.env.Namespace {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	c := knative.New(s.client)
+	for _, ns := range namespaces {
+		for _, r := range c.Preflight(ns) {
+			gr := r.GroupResource()
+			log.Printf("[WARN] not permitted to list %s in namespace %s", gr.String(), ns)
+		}
+	}
+}
+
+func favicon(w http.ResponseWriter, r *http.Request) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+
+	buffer := new(bytes.Buffer)
+	if err := jpeg.Encode(buffer, img, nil); err != nil {
+		log.Println("unable to encode image.")
+	}
+
+	writeBytes(w, buffer.Bytes(), "image/jpeg")
+}
+
+func getQueryParam(r *http.Request, key string) string {
+	keys, ok := r.URL.Query()[key]
+	if !ok || len(keys[0]) < 1 {
+		return ""
+	}
+	return keys[0]
+}
+
+var defaultPage = "html"      // or img
+var defaultFormat = "svg"     // or png
+var defaultFocus = "triggers" // or subscriptions
+
+// downloadPrefix serves the graph as a file, for example /download/pdf.
+const downloadPrefix = "/download/"
+
+// handler serves the graph in the format asked for with the page and format
+// query parameters or, without them, in the format the Accept header
+// prefers.
+func (s *Server) handler(w http.ResponseWriter, r *http.Request) {
+	page := getQueryParam(r, "page")
+	format := getQueryParam(r, "format")
+	if page == "" && format == "" {
+		w.Header().Add("Vary", "Accept")
+		switch mediaType := negotiate(r.Header.Get("Accept"), offers()); mediaType {
+		case "":
+			http.Error(w, "unable to serve any of the accepted media types, try one of "+strings.Join(offers(), ", "), http.StatusNotAcceptable)
+			return
+		case "text/html":
+		default:
+			f, _ := formatByMediaType(mediaType)
+			page, format = "img", f.name
+		}
+	}
+	if page == "" {
+		page = defaultPage
+	}
+	if format == "" {
+		format = defaultFormat
+	}
+	s.serveGraph(w, r, page, format, false)
+}
+
+// download serves the graph as an attachment named after the namespace and
+// the time.
+func (s *Server) download(w http.ResponseWriter, r *http.Request) {
+	s.serveGraph(w, r, "img", strings.TrimPrefix(r.URL.Path, downloadPrefix), true)
+}
+
+func (s *Server) serveGraph(w http.ResponseWriter, r *http.Request, page, format string, attach bool) {
+	of, ok := formatByName(format)
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown format %q", format), http.StatusBadRequest)
+		return
+	}
+
+	ns, ok := s.namespaceParam(w, r)
+	if !ok {
+		return
+	}
+	authz, ok := s.authorize(w, r, ns)
+	if !ok {
+		return
+	}
+
+	focus := getQueryParam(r, "focus")
+	if focus == "" {
+		focus = defaultFocus
+	}
+
+	groupings, err := graph.ParseGroupings(getQueryParam(r, "group"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts := []graph.Option{
+		graph.WithGrouping(groupings...),
+		graph.WithAuthorizer(s.self),
+		graph.WithAuthorizer(authz),
+		graph.WithSourceInformers(s.sourceInformers(ns)),
+		graph.WithLinks(nodeLinks),
+	}
+	if internals := getQueryParam(r, "internals"); internals == "expand" || internals == "true" {
+		opts = append(opts, graph.ExpandInternals())
+	}
+
+	var g *graph.Graph
+
+	switch focus {
+	case "sub", "subs", "subscription", "subscriptions":
+		focus = "subscriptions"
+		g = graph.LoadSubscriptions(s.client, ns, opts...)
+	case "broker", "trigger", "triggers":
+		fallthrough
+	default:
+		focus = "triggers"
+		g = graph.LoadTriggers(s.client, ns, opts...)
+	}
+	dotGraph := []byte(g.String())
+
+	var body []byte
+	switch of.name {
+	case "dot":
+		body = dotGraph
+	case "mermaid":
+		body = []byte(g.Mermaid())
+	case "json":
+		if body, err = json.MarshalIndent(g.Document(), "", "  "); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	default:
+		renderer := getQueryParam(r, "renderer")
+		if renderer == "" {
+			renderer = s.env.Renderer
+		}
+
+		key := strings.Join([]string{ns, focus, of.name, renderer,
+			getQueryParam(r, "group"), getQueryParam(r, "internals"), strings.Join(g.Hidden(), ",")}, "/")
+		var cached bool
+		body, cached, err = s.cache.get(r.Context(), key, renderVersion(g.ResourceVersion(), time.Now()), func(ctx context.Context) ([]byte, error) {
+			start := time.Now()
+			img, err := renderImage(ctx, renderer, of.name, dotGraph)
+			if err == nil {
+				renderSeconds.Observe(time.Since(start).Seconds(), renderer, of.name)
+			}
+			return img, err
+		})
+		if cached {
+			cacheRequests.Inc("hit")
+		} else {
+			cacheRequests.Inc("miss")
+		}
+		if err != nil {
+			if r.Context().Err() != nil {
+				// The client has gone away.
+				return
+			}
+			log.Printf("renderImage error %s", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if page == "html" && (of.name == "svg" || of.name == "png") {
+		writeBytesWithTemplate(w, body, of, downloadLinks(r))
+		return
+	}
+	if attach {
+		writeDownload(w, of.filename(ns, focus, time.Now()))
+	}
+	writeBytes(w, body, of.contentType())
+}
+
+// downloadLinks links to every download of the graph the page shows.
+func downloadLinks(r *http.Request) string {
+	q := r.URL.Query()
+	q.Del("page")
+	q.Del("format")
+	var links []string
+	for _, f := range outputFormats {
+		u := downloadPrefix + f.name
+		if len(q) > 0 {
+			u += "?" + q.Encode()
+		}
+		links = append(links, fmt.Sprintf(`<a href="%s" download>%s</a>`, template.HTMLEscapeString(u), f.name))
+	}
+	return `<nav class="downloads">Download: ` + strings.Join(links, " ") + `</nav>`
+}
+
+// renderImage draws the dot graph with the chosen renderer.
+func renderImage(ctx context.Context, renderer, format string, b []byte) ([]byte, error) {
+	img, err := render.Image(ctx, renderer, format, b)
+	if _, ok := err.(*render.GraphvizError); ok {
+		dotFailures.Inc()
+	}
+	return img, err
+}
+
+// liveStyle highlights the nodes changed by the last update.
+var liveStyle = `<style>
+.changed polygon, .changed ellipse, .changed path { stroke: #ff8c00; stroke-width: 4px; }
+.downloads { font-family: sans-serif; font-size: small; }
+</style>`
+
+// liveScript reloads the graph whenever the server reports a change, and
+// briefly highlights the changed nodes.
+var liveScript = `<script>
+(function() {
+  var params = new URLSearchParams(location.search);
+  var source = new EventSource("events?" + params.toString());
+  source.addEventListener("update", function(e) {
+    var changed = JSON.parse(e.data).changed || [];
+    params.set("page", "img");
+    fetch("?" + params.toString()).then(function(resp) {
+      if (!resp.ok) {
+        throw new Error(resp.statusText);
+      }
+      return resp.headers.get("Content-Type").indexOf("svg") >= 0 ? resp.text() : resp.blob();
+    }).then(function(body) {
+      var graph = document.getElementById("graph");
+      if (typeof body === "string") {
+        graph.innerHTML = body;
+      } else {
+        graph.querySelector("img").src = URL.createObjectURL(body);
+      }
+      changed.forEach(function(id) {
+        var node = document.getElementById(id);
+        if (node) {
+          node.classList.add("changed");
+          setTimeout(function() { node.classList.remove("changed"); }, 5000);
+        }
+      });
+    }).catch(function(err) {
+      console.log("unable to reload graph", err);
+    });
+  });
+})();
+</script>`
+
+var Template = `<!DOCTYPE html>
+<html lang="en"><head>` + liveStyle + `</head>
+<body>{{.Links}}<div id="graph"><img src="data:{{.Format}},{{.Image}}"></div>` + liveScript + `</body></html>`
+
+func writeBytesWithTemplate(w http.ResponseWriter, b []byte, format outputFormat, links string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if format.name == "svg" {
+		_, _ = w.Write([]byte(`<!DOCTYPE html><html lang="en"><head>` + liveStyle + `</head><body>` + links + `<div id="graph">`))
+		_, _ = w.Write(b)
+		_, _ = w.Write([]byte(`</div>` + liveScript + `</body></html>`))
+		return
+	}
+
+	data := map[string]interface{}{
+		"Links":  template.HTML(links),
+		"Image":  base64.StdEncoding.EncodeToString(b),
+		"Format": template.URL(format.mediaType + ";base64"),
+	}
+	if tmpl, err := template.New("image").Parse(Template); err != nil {
+		log.Println("unable to parse image template.")
+	} else {
+		if err = tmpl.Execute(w, data); err != nil {
+			log.Println("unable to execute template.")
+		}
+	}
+}
+
+// writeBytes writes b with the content type.
+func writeBytes(w http.ResponseWriter, b []byte, contentType string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+	if _, err := w.Write(b); err != nil {
+		log.Println("unable to write image.")
+	}
+}
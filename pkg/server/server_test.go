@@ -0,0 +1,80 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/n3wscott/knap/pkg/knative"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestServeTwice(t *testing.T) {
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"major": "1", "minor": "12"}`)
+	}))
+	defer apiServer.Close()
+	kube, err := kubernetes.NewForConfig(&rest.Config{Host: apiServer.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		s := newServer(Config{
+			Namespace:       "demo",
+			ListenAddress:   "127.0.0.1:0",
+			ShutdownTimeout: time.Second,
+		}, knative.NewSnapshot(nil), kube, nil)
+
+		stop := make(chan struct{})
+		errs := make(chan error, 1)
+		go func() {
+			errs <- s.Serve(stop)
+		}()
+		close(stop)
+
+		select {
+		case err := <-errs:
+			if err != nil {
+				t.Fatalf("run %d: %s", i, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("run %d did not stop", i)
+		}
+		// Shutting down again must not panic.
+		s.Shutdown()
+	}
+}
+
+func TestShutdownEndsStreams(t *testing.T) {
+	s := serveTopology(t)
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/events?namespace=demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+
+	ended := make(chan struct{})
+	go func() {
+		defer close(ended)
+		_, _ = ioutil.ReadAll(resp.Body)
+	}()
+	s.Shutdown()
+	s.Shutdown()
+
+	select {
+	case <-ended:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the event stream did not end on shutdown")
+	}
+}